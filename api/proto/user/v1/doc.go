@@ -0,0 +1,5 @@
+// Package userv1 holds the protobuf definition of the user API and the code
+// generated from it. Clients import this package to call the gRPC server.
+package userv1
+
+//go:generate protoc -I ../.. --go_out=../.. --go_opt=paths=source_relative --go-grpc_out=../.. --go-grpc_opt=paths=source_relative user/v1/user.proto
@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -11,13 +12,34 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/kaa-dan/clean-architecture-go/internal/config"
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/events"
+	domainrepos "github.com/kaa-dan/clean-architecture-go/internal/domain/repositories"
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/services"
+	"github.com/kaa-dan/clean-architecture-go/internal/infrastructure/activity"
+	"github.com/kaa-dan/clean-architecture-go/internal/infrastructure/blobstore"
+	infracache "github.com/kaa-dan/clean-architecture-go/internal/infrastructure/cache"
 	"github.com/kaa-dan/clean-architecture-go/internal/infrastructure/database"
+	"github.com/kaa-dan/clean-architecture-go/internal/infrastructure/migrations"
+	"github.com/kaa-dan/clean-architecture-go/internal/infrastructure/notification"
+	"github.com/kaa-dan/clean-architecture-go/internal/infrastructure/outbox"
+	"github.com/kaa-dan/clean-architecture-go/internal/infrastructure/publisher"
 	"github.com/kaa-dan/clean-architecture-go/internal/infrastructure/repositories"
+	"github.com/kaa-dan/clean-architecture-go/internal/infrastructure/search"
 	"github.com/kaa-dan/clean-architecture-go/internal/infrastructure/security"
+	"github.com/kaa-dan/clean-architecture-go/internal/infrastructure/webhook"
+	"github.com/kaa-dan/clean-architecture-go/internal/interfaces/encoding"
+	"github.com/kaa-dan/clean-architecture-go/internal/interfaces/graphql"
+	grpcserver "github.com/kaa-dan/clean-architecture-go/internal/interfaces/grpc"
 	"github.com/kaa-dan/clean-architecture-go/internal/interfaces/handlers"
+	"github.com/kaa-dan/clean-architecture-go/internal/interfaces/idempotency"
 	"github.com/kaa-dan/clean-architecture-go/internal/interfaces/routes"
+	"github.com/kaa-dan/clean-architecture-go/internal/interfaces/versioning"
+	apiv2 "github.com/kaa-dan/clean-architecture-go/internal/interfaces/versioning/v2"
 	"github.com/kaa-dan/clean-architecture-go/internal/usecases"
+	"github.com/kaa-dan/clean-architecture-go/pkg/cache"
 	"github.com/kaa-dan/clean-architecture-go/pkg/logger"
+	"github.com/kaa-dan/clean-architecture-go/pkg/response"
+	"github.com/kaa-dan/clean-architecture-go/pkg/worker"
 )
 
 func main() {
@@ -28,6 +50,11 @@ func main() {
 	// Initialize logger
 	logger.Init(cfg.LogLevel)
 
+	canonicalizer, err := cfg.Canonicalizer()
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	//Connect to MongoDb
 
 	db, err := database.NewMongoDB(cfg.DatabaseURL)
@@ -37,20 +64,185 @@ func main() {
 
 	defer db.Disconnect(context.Background())
 
+	// Apply or verify schema migrations
+	migrationRunner := migrations.NewRunner(db, cfg.DatabaseName, migrations.All(canonicalizer))
+	if cfg.MigrateOnStart {
+		if _, err := migrationRunner.Up(context.Background()); err != nil {
+			log.Fatal("Failed to apply migrations:", err)
+		}
+	}
+	if cfg.FailOnPendingMigrations {
+		if err := migrationRunner.EnsureApplied(context.Background()); err != nil {
+			log.Fatal("Refusing to start:", err)
+		}
+	}
+
 	// Initialize repositories
 
-	userRepo := repositories.NewUserRepository(db, cfg.DatabaseName)
+	var userRepo domainrepos.UserRepository = repositories.NewUserRepository(db, cfg.DatabaseName, canonicalizer)
+
+	txManager := database.NewTxManager(db)
+
+	// Wrap the user repository in a read-through cache
+	var userCacheStats handlers.CacheStatsProvider
+	if cfg.UserCacheEnabled {
+		var invalidator infracache.Invalidator = infracache.NewLocalInvalidator()
+		if cfg.CacheInvalidation == "mongo" {
+			mongoInvalidator, err := infracache.NewMongoInvalidator(db, cfg.DatabaseName)
+			if err != nil {
+				log.Fatal("Failed to set up cache invalidation:", err)
+			}
+			defer mongoInvalidator.Close()
+			invalidator = mongoInvalidator
+		}
+
+		backend := cache.NewLRU(cfg.UserCacheSize, time.Duration(cfg.UserCacheTTLSeconds)*time.Second)
+		cachedUserRepo := repositories.NewCachedUserRepository(userRepo, backend, invalidator, txManager)
+		userRepo = cachedUserRepo
+		userCacheStats = cachedUserRepo
+	}
+
+	attributeSchemaRepo := repositories.NewAttributeSchemaRepository(db, cfg.DatabaseName)
+	outboxRepo := repositories.NewOutboxRepository(db, cfg.DatabaseName)
+
+	// Initialize search index
+	var searchIndex domainrepos.UserSearchIndex
+	switch cfg.SearchBackend {
+	case "memory":
+		memoryIndex := search.NewMemoryIndex()
+		if err := memoryIndex.Load(context.Background(), userRepo); err != nil {
+			log.Fatal("Failed to build search index:", err)
+		}
+		searchIndex = memoryIndex
+	default:
+		searchIndex = search.NewMongoIndex(db, cfg.DatabaseName)
+	}
+
+	// Live activity feed for admins
+	activityBroker := activity.NewBroker(1000)
 
 	// Initialize use cases
 	jwtManager := security.NewJWTManager(cfg.JWTSecret, cfg.JWTExpiryHours)
 	passwordManager := security.NewPasswordManger()
-	userUseCases := usecases.NewUserUseCase(userRepo, jwtManager, passwordManager)
+	userUseCases := usecases.NewUserUseCase(userRepo, attributeSchemaRepo, searchIndex, txManager, outboxRepo, activityBroker, jwtManager, passwordManager)
+	attributeUseCases := usecases.NewAttributeUseCase(attributeSchemaRepo)
+	importUseCases := usecases.NewUserImportUseCase(userRepo, searchIndex, txManager, outboxRepo, canonicalizer, passwordManager, notification.NewLogInvitationSender())
+
+	// Outgoing webhooks, fed by the outbox relay
+	webhookDeliveryRepo := repositories.NewWebhookDeliveryRepository(db, cfg.DatabaseName)
+	webhookUseCases := usecases.NewWebhookUseCase(
+		repositories.NewWebhookRepository(db, cfg.DatabaseName),
+		webhookDeliveryRepo,
+		txManager,
+		webhook.NewHTTPSender(),
+	)
+
+	// Blob storage for uploaded images
+	var (
+		blobStore services.BlobStore
+		mediaDir  string
+	)
+	switch cfg.BlobStore {
+	case "s3":
+		s3Store, err := blobstore.NewS3Store(blobstore.S3Config{
+			Endpoint:        cfg.S3Endpoint,
+			Region:          cfg.S3Region,
+			Bucket:          cfg.S3Bucket,
+			AccessKeyID:     cfg.S3AccessKeyID,
+			SecretAccessKey: cfg.S3SecretAccessKey,
+			PublicURL:       cfg.BlobPublicURL,
+		})
+		if err != nil {
+			log.Fatal("Failed to set up S3 blob storage:", err)
+		}
+		blobStore = s3Store
+	default:
+		publicURL := cfg.BlobPublicURL
+		if publicURL == "" {
+			publicURL = blobstore.LocalMediaRoute
+		}
+		localStore, err := blobstore.NewLocalStore(cfg.BlobLocalDir, publicURL)
+		if err != nil {
+			log.Fatal("Failed to set up local blob storage:", err)
+		}
+		blobStore = localStore
+		mediaDir = cfg.BlobLocalDir
+	}
+	avatarUseCases := usecases.NewAvatarUseCase(userRepo, attributeSchemaRepo, searchIndex, txManager, outboxRepo, activityBroker, blobStore, cfg.AvatarMaxBytes, cfg.AvatarMaxPixels)
+
+	// Stores that reference users, covered by data export and erasure
+	idempotencyRepo := repositories.NewIdempotencyRepository(db, cfg.DatabaseName)
+	personalDataProviders := []domainrepos.PersonalDataProvider{outboxRepo, webhookDeliveryRepo, avatarUseCases, idempotencyRepo}
+	privacyUseCases := usecases.NewPrivacyUseCase(
+		userRepo,
+		repositories.NewErasureRepository(db, cfg.DatabaseName),
+		searchIndex,
+		txManager,
+		outboxRepo,
+		activityBroker,
+		passwordManager,
+		personalDataProviders,
+		time.Duration(cfg.ErasureGracePeriodHours)*time.Hour,
+	)
+
+	// Publish domain events from the outbox
+	var eventPublisher events.Publisher
+	switch cfg.EventPublisher {
+	case "memory":
+		eventPublisher = publisher.NewMemoryPublisher(1000)
+	case "http":
+		if cfg.EventPublisherURL == "" {
+			log.Fatal("EVENT_PUBLISHER_URL is required for the http event publisher")
+		}
+		eventPublisher = publisher.NewHTTPPublisher(cfg.EventPublisherURL)
+	default:
+		eventPublisher = publisher.NewLogPublisher()
+	}
+	outboxRelay := outbox.NewRelay(outboxRepo, publisher.NewMultiPublisher(eventPublisher, webhookUseCases), 100, cfg.OutboxMaxAttempts)
+
+	// Start background workers
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	go worker.Run(workerCtx, "outbox relay", time.Duration(cfg.OutboxRelayIntervalMS)*time.Millisecond, outboxRelay.RunOnce)
+	go worker.Run(workerCtx, "webhook delivery", time.Duration(cfg.WebhookWorkerIntervalSeconds)*time.Second, func(ctx context.Context) error {
+		_, err := webhookUseCases.ProcessDueDeliveries(ctx)
+		return err
+	})
+	go worker.Run(workerCtx, "erasure worker", time.Duration(cfg.ErasureWorkerIntervalSeconds)*time.Second, func(ctx context.Context) error {
+		_, err := privacyUseCases.ProcessDueErasures(ctx)
+		return err
+	})
 
 	// Initialize handlers
 	userHandler := handlers.NewUserHandler(userUseCases)
+	cacheHandler := handlers.NewCacheHandler(userCacheStats)
+	importHandler := handlers.NewImportHandler(importUseCases)
+	privacyHandler := handlers.NewPrivacyHandler(privacyUseCases)
+	webhookHandler := handlers.NewWebhookHandler(webhookUseCases)
+	activityHandler := handlers.NewActivityHandler(activityBroker)
+	avatarHandler := handlers.NewAvatarHandler(avatarUseCases, cfg.AvatarMaxBytes)
+	attributeHandler := handlers.NewAttributeHandler(attributeUseCases)
+	graphqlHandler, err := graphql.NewHandler(userUseCases, graphql.Limits{
+		MaxDepth:      cfg.GraphQLMaxDepth,
+		MaxComplexity: cfg.GraphQLMaxComplexity,
+	})
+	if err != nil {
+		log.Fatal("Failed to build GraphQL schema:", err)
+	}
 
 	// Initialize middleware
 	authMiddleware := security.NewAuthMiddleware(jwtManager)
+	idempotencyMiddleware := idempotency.NewMiddleware(
+		idempotencyRepo,
+		cfg.IdempotencySecret,
+		cfg.IdempotencyMaxBodyBytes,
+		time.Duration(cfg.IdempotencyWindowHours)*time.Hour,
+		time.Duration(cfg.IdempotencyLockSeconds)*time.Second,
+	)
+	var compressor *encoding.Compressor
+	if cfg.CompressionEnabled {
+		compressor = encoding.NewCompressor(cfg.CompressionMinBytes)
+	}
 
 	// Set Gin mode
 	if cfg.Environment == "production" {
@@ -60,8 +252,32 @@ func main() {
 	// Initialize router
 	router := gin.New()
 
+	// Batches run their requests through the router
+	batchHandler := handlers.NewBatchHandler(router, txManager, cfg.BatchMaxRequests, cfg.BatchMaxConcurrency)
+
+	// Responses are only checked against the OpenAPI document in development
+	openAPI := routes.OpenAPIOptions{
+		Document:          routes.OpenAPI(),
+		ValidateRequests:  cfg.OpenAPIValidateRequests,
+		ValidateResponses: cfg.OpenAPIValidateResponses && cfg.Environment == "development",
+	}
+
+	// REST API versions, served side by side
+	apiVersions, err := versioning.NewRegistry(cfg.APIDefaultVersion,
+		versioning.Version{
+			Number:     1,
+			Renderer:   response.Envelope{},
+			Deprecated: cfg.APIV1DeprecatedAt,
+			Sunset:     cfg.APIV1SunsetAt,
+		},
+		versioning.Version{Number: 2, Renderer: apiv2.Renderer{}, Requests: apiv2.RequestMapper{}},
+	)
+	if err != nil {
+		log.Fatal("Invalid API version configuration:", err)
+	}
+
 	// Setup routes
-	routes.SetupRoutes(router, userHandler, authMiddleware)
+	routes.SetupRoutes(router, userHandler, cacheHandler, importHandler, privacyHandler, webhookHandler, activityHandler, avatarHandler, attributeHandler, batchHandler, graphqlHandler, openAPI, apiVersions, mediaDir, authMiddleware, idempotencyMiddleware, compressor)
 
 	// Create server
 	srv := &http.Server{
@@ -81,16 +297,37 @@ func main() {
 
 	log.Printf("Server started on port %s", cfg.Port)
 
+	// Serve the user API over gRPC as well
+	var grpcSrv *grpcserver.Server
+	if cfg.GRPCEnabled {
+		lis, err := net.Listen("tcp", ":"+cfg.GRPCPort)
+		if err != nil {
+			log.Fatalf("Failed to listen for gRPC: %v", err)
+		}
+		grpcSrv = grpcserver.NewServer(userUseCases, jwtManager)
+		go func() {
+			if err := grpcSrv.Serve(lis); err != nil {
+				log.Fatalf("Failed to start gRPC server: %v", err)
+			}
+		}()
+		log.Printf("gRPC server started on port %s", cfg.GRPCPort)
+	}
+
 	// Wait for interrupt signal to gracefully shutdown
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 	log.Println("Shutting down server...")
+	stopWorkers()
 
 	// Graceful shutdown
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
+	if grpcSrv != nil {
+		grpcSrv.Shutdown(ctx)
+	}
+
 	if err := srv.Shutdown(ctx); err != nil {
 		log.Fatal("Server forced to shutdown:", err)
 	}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kaa-dan/clean-architecture-go/internal/config"
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/entities"
+	"github.com/kaa-dan/clean-architecture-go/internal/infrastructure/database"
+	"github.com/kaa-dan/clean-architecture-go/internal/infrastructure/importer"
+	"github.com/kaa-dan/clean-architecture-go/internal/infrastructure/notification"
+	"github.com/kaa-dan/clean-architecture-go/internal/infrastructure/repositories"
+	"github.com/kaa-dan/clean-architecture-go/internal/infrastructure/search"
+	"github.com/kaa-dan/clean-architecture-go/internal/infrastructure/security"
+	"github.com/kaa-dan/clean-architecture-go/internal/usecases"
+	"github.com/kaa-dan/clean-architecture-go/pkg/logger"
+)
+
+func main() {
+	file := flag.String("file", "", "path to a CSV or NDJSON file, or - for stdin")
+	format := flag.String("format", "", "csv or ndjson (defaults to the file extension)")
+	dryRun := flag.Bool("dry-run", false, "validate rows without writing anything")
+	onConflict := flag.String("on-conflict", "skip", "skip or upsert users whose email already exists")
+	invite := flag.Bool("invite", false, "send invitations to users imported without a password")
+	jsonOutput := flag.Bool("json", false, "print the full report as JSON")
+	flag.Parse()
+
+	if *file == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	// Load configuration
+	cfg := config.Load()
+	logger.Init(cfg.LogLevel)
+
+	var input io.Reader = os.Stdin
+	if *file != "-" {
+		f, err := os.Open(*file)
+		if err != nil {
+			log.Fatal("Failed to open file: ", err)
+		}
+		defer f.Close()
+		input = f
+
+		if *format == "" {
+			*format = strings.TrimPrefix(strings.ToLower(filepath.Ext(*file)), ".")
+			if *format == "jsonl" {
+				*format = importer.FormatNDJSON
+			}
+		}
+	}
+
+	source, err := importer.NewSource(*format, input)
+	if err != nil {
+		log.Fatal("Failed to read file: ", err)
+	}
+
+	canonicalizer, err := cfg.Canonicalizer()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	db, err := database.NewMongoDB(cfg.DatabaseURL)
+	if err != nil {
+		log.Fatal("Failed to connect to database:", err)
+	}
+	defer db.Disconnect(context.Background())
+
+	importUseCase := usecases.NewUserImportUseCase(
+		repositories.NewUserRepository(db, cfg.DatabaseName, canonicalizer),
+		search.NewMongoIndex(db, cfg.DatabaseName),
+		database.NewTxManager(db),
+		repositories.NewOutboxRepository(db, cfg.DatabaseName),
+		canonicalizer,
+		security.NewPasswordManger(),
+		notification.NewLogInvitationSender(),
+	)
+
+	report, err := importUseCase.ImportUsers(context.Background(), source, entities.ImportOptions{
+		DryRun:          *dryRun,
+		OnConflict:      entities.ImportConflictMode(*onConflict),
+		SendInvitations: *invite,
+	})
+	if err != nil {
+		log.Fatal("Import failed: ", err)
+	}
+
+	if *jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(report)
+	} else {
+		for _, row := range report.Rows {
+			if row.Status == entities.ImportRowFailed {
+				fmt.Printf("row %d (%s): %s\n", row.Row, row.Email, strings.Join(row.Errors, "; "))
+			}
+		}
+		fmt.Printf("total=%d created=%d updated=%d skipped=%d valid=%d failed=%d dry_run=%t\n",
+			report.Total, report.Created, report.Updated, report.Skipped, report.Valid, report.Failed, report.DryRun)
+	}
+
+	if report.Failed > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/kaa-dan/clean-architecture-go/internal/config"
+	"github.com/kaa-dan/clean-architecture-go/internal/infrastructure/database"
+	"github.com/kaa-dan/clean-architecture-go/internal/infrastructure/migrations"
+	"github.com/kaa-dan/clean-architecture-go/pkg/logger"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	// Load configuration
+	cfg := config.Load()
+	logger.Init(cfg.LogLevel)
+
+	canonicalizer, err := cfg.Canonicalizer()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	db, err := database.NewMongoDB(cfg.DatabaseURL)
+	if err != nil {
+		log.Fatal("Failed to connect to database:", err)
+	}
+	defer db.Disconnect(context.Background())
+
+	runner := migrations.NewRunner(db, cfg.DatabaseName, migrations.All(canonicalizer))
+	ctx := context.Background()
+
+	switch os.Args[1] {
+	case "up":
+		applied, err := runner.Up(ctx)
+		for _, migration := range applied {
+			fmt.Printf("applied %d: %s\n", migration.Version, migration.Description)
+		}
+		if err != nil {
+			log.Fatal("Migration failed: ", err)
+		}
+		if len(applied) == 0 {
+			fmt.Println("no pending migrations")
+		}
+
+	case "status":
+		statuses, err := runner.Status(ctx)
+		if err != nil {
+			log.Fatal("Failed to read migration status: ", err)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tSTATUS\tAPPLIED AT\tDESCRIPTION")
+		for _, status := range statuses {
+			state, appliedAt := "pending", "-"
+			if status.Applied {
+				state, appliedAt = "applied", status.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", status.Version, state, appliedAt, status.Description)
+		}
+		w.Flush()
+
+	case "collisions":
+		collisions, err := migrations.FindCollisions(ctx, db.Database(cfg.DatabaseName), canonicalizer)
+		if err != nil {
+			log.Fatal("Failed to check for collisions: ", err)
+		}
+		if len(collisions) == 0 {
+			fmt.Println("no collisions")
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "FIELD\tCANONICAL KEY\tUSER ID\tSTORED VALUE\tCREATED AT")
+		for _, collision := range collisions {
+			for _, user := range collision.Users {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", collision.Field, collision.Key, user.ID, user.Value, user.CreatedAt.Format(time.RFC3339))
+			}
+		}
+		w.Flush()
+
+		fmt.Fprintf(os.Stderr, "%d collision(s) must be resolved before the canonical indexes can be created\n", len(collisions))
+		os.Exit(1)
+
+	case "canonicalize":
+		if err := migrations.RecomputeCanonicalKeys(ctx, db.Database(cfg.DatabaseName), canonicalizer); err != nil {
+			log.Fatal("Failed to recompute canonical keys: ", err)
+		}
+		fmt.Println("canonical keys recomputed")
+
+	default:
+		usage()
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: migrate <up|status|collisions|canonicalize>")
+	os.Exit(2)
+}
@@ -1,10 +1,13 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
+	"github.com/kaa-dan/clean-architecture-go/pkg/identity"
 )
 
 type Config struct {
@@ -17,6 +20,63 @@ type Config struct {
 	LogLevel       string
 	RateLimitRPM   int
 	BCryptCost     int
+	SearchBackend  string
+
+	EmailCanonicalRules string
+
+	MigrateOnStart          bool
+	FailOnPendingMigrations bool
+
+	UserCacheEnabled    bool
+	UserCacheSize       int
+	UserCacheTTLSeconds int
+	CacheInvalidation   string
+
+	ErasureGracePeriodHours      int
+	ErasureWorkerIntervalSeconds int
+
+	EventPublisher        string
+	EventPublisherURL     string
+	OutboxRelayIntervalMS int
+	OutboxMaxAttempts     int
+
+	WebhookWorkerIntervalSeconds int
+
+	BlobStore         string
+	BlobLocalDir      string
+	BlobPublicURL     string
+	S3Endpoint        string
+	S3Region          string
+	S3Bucket          string
+	S3AccessKeyID     string
+	S3SecretAccessKey string
+
+	AvatarMaxBytes  int64
+	AvatarMaxPixels int
+
+	GRPCEnabled bool
+	GRPCPort    string
+
+	GraphQLMaxDepth      int
+	GraphQLMaxComplexity int
+
+	OpenAPIValidateRequests  bool
+	OpenAPIValidateResponses bool
+
+	APIDefaultVersion int
+	APIV1DeprecatedAt time.Time
+	APIV1SunsetAt     time.Time
+
+	IdempotencyWindowHours  int
+	IdempotencyLockSeconds  int
+	IdempotencySecret       string
+	IdempotencyMaxBodyBytes int64
+
+	CompressionEnabled  bool
+	CompressionMinBytes int
+
+	BatchMaxRequests    int
+	BatchMaxConcurrency int
 }
 
 func Load() *Config {
@@ -24,27 +84,134 @@ func Load() *Config {
 	// Load .env file if exists
 	godotenv.Load()
 
+	jwtSecret := getEnv("JWT_SECRET", "your-secret-key-change-this")
 	jwtExpiryHours, _ := strconv.Atoi(getEnv("JWT_EXPIRY_HOURS", "24"))
 	rateLimitRPM, _ := strconv.Atoi(getEnv("RATE_LIMIT_RPM", "60"))
 	bcryptCost, _ := strconv.Atoi(getEnv("BCRYPT_COST", "12"))
+	migrateOnStart, _ := strconv.ParseBool(getEnv("MIGRATE_ON_START", "true"))
+	failOnPendingMigrations, _ := strconv.ParseBool(getEnv("FAIL_ON_PENDING_MIGRATIONS", "true"))
+	userCacheEnabled, _ := strconv.ParseBool(getEnv("USER_CACHE_ENABLED", "false"))
+	userCacheSize, _ := strconv.Atoi(getEnv("USER_CACHE_SIZE", "10000"))
+	userCacheTTLSeconds, _ := strconv.Atoi(getEnv("USER_CACHE_TTL_SECONDS", "60"))
+	erasureGracePeriodHours, _ := strconv.Atoi(getEnv("ERASURE_GRACE_PERIOD_HOURS", "72"))
+	erasureWorkerIntervalSeconds, _ := strconv.Atoi(getEnv("ERASURE_WORKER_INTERVAL_SECONDS", "60"))
+	outboxRelayIntervalMS, _ := strconv.Atoi(getEnv("OUTBOX_RELAY_INTERVAL_MS", "1000"))
+	outboxMaxAttempts, _ := strconv.Atoi(getEnv("OUTBOX_MAX_ATTEMPTS", "10"))
+	webhookWorkerIntervalSeconds, _ := strconv.Atoi(getEnv("WEBHOOK_WORKER_INTERVAL_SECONDS", "5"))
+	avatarMaxBytes, _ := strconv.ParseInt(getEnv("AVATAR_MAX_BYTES", "5242880"), 10, 64)
+	avatarMaxPixels, _ := strconv.Atoi(getEnv("AVATAR_MAX_PIXELS", "40000000"))
+	grpcEnabled, _ := strconv.ParseBool(getEnv("GRPC_ENABLED", "true"))
+	graphQLMaxDepth, _ := strconv.Atoi(getEnv("GRAPHQL_MAX_DEPTH", "10"))
+	graphQLMaxComplexity, _ := strconv.Atoi(getEnv("GRAPHQL_MAX_COMPLEXITY", "2000"))
+	openAPIValidateRequests, _ := strconv.ParseBool(getEnv("OPENAPI_VALIDATE_REQUESTS", "false"))
+	openAPIValidateResponses, _ := strconv.ParseBool(getEnv("OPENAPI_VALIDATE_RESPONSES", "true"))
+	apiDefaultVersion, _ := strconv.Atoi(getEnv("API_DEFAULT_VERSION", "1"))
+	idempotencyWindowHours, _ := strconv.Atoi(getEnv("IDEMPOTENCY_WINDOW_HOURS", "24"))
+	idempotencyLockSeconds, _ := strconv.Atoi(getEnv("IDEMPOTENCY_LOCK_SECONDS", "60"))
+	// Avatar uploads must fit, with room for the multipart encoding
+	idempotencyMaxBodyBytes, _ := strconv.ParseInt(getEnv("IDEMPOTENCY_MAX_BODY_BYTES", strconv.FormatInt(avatarMaxBytes+1<<20, 10)), 10, 64)
+	compressionEnabled, _ := strconv.ParseBool(getEnv("COMPRESSION_ENABLED", "false"))
+	compressionMinBytes, _ := strconv.Atoi(getEnv("COMPRESSION_MIN_BYTES", "1024"))
+	batchMaxRequests, _ := strconv.Atoi(getEnv("BATCH_MAX_REQUESTS", "100"))
+	batchMaxConcurrency, _ := strconv.Atoi(getEnv("BATCH_MAX_CONCURRENCY", "10"))
 
 	return &Config{
 		Environment:    getEnv("ENVIRONMENT", "development"),
 		Port:           getEnv("PORT", "8080"),
 		DatabaseURL:    getEnv("DATABSE_URL", "mongodb://localhost:27017"),
 		DatabaseName:   getEnv("DATABASE_NAME", "userapi"),
-		JWTSecret:      getEnv("JWT_SECRET", "your-secret-key-change-this"),
+		JWTSecret:      jwtSecret,
 		JWTExpiryHours: jwtExpiryHours,
 		LogLevel:       getEnv("LOG_LEVEL", "info"),
 		RateLimitRPM:   rateLimitRPM,
 		BCryptCost:     bcryptCost,
+		SearchBackend:  getEnv("SEARCH_BACKEND", "mongo"),
+
+		EmailCanonicalRules: getEnv("EMAIL_CANONICAL_RULES", ""),
+
+		MigrateOnStart:          migrateOnStart,
+		FailOnPendingMigrations: failOnPendingMigrations,
+
+		UserCacheEnabled:    userCacheEnabled,
+		UserCacheSize:       userCacheSize,
+		UserCacheTTLSeconds: userCacheTTLSeconds,
+		CacheInvalidation:   getEnv("CACHE_INVALIDATION", "local"),
+
+		ErasureGracePeriodHours:      erasureGracePeriodHours,
+		ErasureWorkerIntervalSeconds: erasureWorkerIntervalSeconds,
+
+		EventPublisher:        getEnv("EVENT_PUBLISHER", "log"),
+		EventPublisherURL:     getEnv("EVENT_PUBLISHER_URL", ""),
+		OutboxRelayIntervalMS: outboxRelayIntervalMS,
+		OutboxMaxAttempts:     outboxMaxAttempts,
+
+		WebhookWorkerIntervalSeconds: webhookWorkerIntervalSeconds,
+
+		BlobStore:         getEnv("BLOB_STORE", "local"),
+		BlobLocalDir:      getEnv("BLOB_LOCAL_DIR", "./data/blobs"),
+		BlobPublicURL:     getEnv("BLOB_PUBLIC_URL", ""),
+		S3Endpoint:        getEnv("S3_ENDPOINT", ""),
+		S3Region:          getEnv("S3_REGION", "us-east-1"),
+		S3Bucket:          getEnv("S3_BUCKET", ""),
+		S3AccessKeyID:     getEnv("S3_ACCESS_KEY_ID", ""),
+		S3SecretAccessKey: getEnv("S3_SECRET_ACCESS_KEY", ""),
+
+		AvatarMaxBytes:  avatarMaxBytes,
+		AvatarMaxPixels: avatarMaxPixels,
+
+		GRPCEnabled: grpcEnabled,
+		GRPCPort:    getEnv("GRPC_PORT", "9090"),
+
+		GraphQLMaxDepth:      graphQLMaxDepth,
+		GraphQLMaxComplexity: graphQLMaxComplexity,
+
+		OpenAPIValidateRequests:  openAPIValidateRequests,
+		OpenAPIValidateResponses: openAPIValidateResponses,
+
+		APIDefaultVersion: apiDefaultVersion,
+		APIV1DeprecatedAt: getEnvTime("API_V1_DEPRECATED_AT"),
+		APIV1SunsetAt:     getEnvTime("API_V1_SUNSET_AT"),
+
+		IdempotencyWindowHours:  idempotencyWindowHours,
+		IdempotencyLockSeconds:  idempotencyLockSeconds,
+		IdempotencySecret:       getEnv("IDEMPOTENCY_SECRET", jwtSecret),
+		IdempotencyMaxBodyBytes: idempotencyMaxBodyBytes,
+
+		CompressionEnabled:  compressionEnabled,
+		CompressionMinBytes: compressionMinBytes,
+
+		BatchMaxRequests:    batchMaxRequests,
+		BatchMaxConcurrency: batchMaxConcurrency,
 	}
 
 }
 
+// Canonicalizer builds the canonical keys emails and usernames are compared
+// through, with the rules of EMAIL_CANONICAL_RULES
+func (c *Config) Canonicalizer() (*identity.Canonicalizer, error) {
+	emailRules, err := identity.ParseEmailRules(c.EmailCanonicalRules)
+	if err != nil {
+		return nil, fmt.Errorf("invalid EMAIL_CANONICAL_RULES: %w", err)
+	}
+	return identity.NewCanonicalizer(emailRules), nil
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
 	}
 	return defaultValue
 }
+
+// getEnvTime parses a date (2006-01-02) or an RFC 3339 time, returning the
+// zero time when the variable is unset or invalid
+func getEnvTime(key string) time.Time {
+	value := os.Getenv(key)
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t
+	}
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t
+	}
+	return time.Time{}
+}
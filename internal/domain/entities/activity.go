@@ -0,0 +1,22 @@
+package entities
+
+import "time"
+
+type ActivityType string
+
+const (
+	ActivitySignedUp ActivityType = "user.signed_up"
+	ActivitySignedIn ActivityType = "user.signed_in"
+	ActivityUpdated  ActivityType = "user.updated"
+	ActivityDeleted  ActivityType = "user.deleted"
+)
+
+// ActivityEvent is a user lifecycle event for the live activity feed. ID is
+// assigned by the feed when the event is published.
+type ActivityEvent struct {
+	ID         string                 `json:"id"`
+	Type       ActivityType           `json:"type"`
+	UserID     string                 `json:"user_id"`
+	OccurredAt time.Time              `json:"occurred_at"`
+	Data       map[string]interface{} `json:"data,omitempty"`
+}
@@ -0,0 +1,94 @@
+package entities
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type AttributeType string
+
+const (
+	AttributeString  AttributeType = "string"
+	AttributeInteger AttributeType = "integer"
+	AttributeNumber  AttributeType = "number"
+	AttributeBoolean AttributeType = "boolean"
+	// AttributeDate holds a calendar date as YYYY-MM-DD
+	AttributeDate AttributeType = "date"
+)
+
+// AttributeVisibility decides who can see an attribute, and who can change it
+type AttributeVisibility string
+
+const (
+	// VisibilityPublic attributes are shown to every signed in user
+	VisibilityPublic AttributeVisibility = "public"
+	// VisibilitySelf attributes are shown to the user and to admins
+	VisibilitySelf AttributeVisibility = "self"
+	// VisibilityAdmin attributes are shown to and changed by admins only
+	VisibilityAdmin AttributeVisibility = "admin"
+)
+
+// AttributeSchema defines a custom user attribute. Rules use the same tag
+// syntax as struct validation, for example "min=2,max=50" or "e164".
+type AttributeSchema struct {
+	ID         primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	Name       string              `bson:"name" json:"name"`
+	Label      string              `bson:"label" json:"label"`
+	Type       AttributeType       `bson:"type" json:"type"`
+	Rules      string              `bson:"rules" json:"rules,omitempty"`
+	Visibility AttributeVisibility `bson:"visibility" json:"visibility"`
+	Required   bool                `bson:"required" json:"required"`
+	CreatedAt  time.Time           `bson:"created_at" json:"created_at"`
+	UpdatedAt  time.Time           `bson:"updated_at" json:"updated_at"`
+}
+
+type CreateAttributeSchemaRequest struct {
+	Name       string              `json:"name" validate:"required,max=40,identifier"`
+	Label      string              `json:"label" validate:"required,max=100"`
+	Type       AttributeType       `json:"type" validate:"required,oneof=string integer number boolean date"`
+	Rules      string              `json:"rules" validate:"max=200"`
+	Visibility AttributeVisibility `json:"visibility" validate:"required,oneof=public self admin"`
+	Required   bool                `json:"required"`
+}
+
+// UpdateAttributeSchemaRequest cannot change the name or type, which stored
+// values depend on.
+type UpdateAttributeSchemaRequest struct {
+	Label      *string              `json:"label,omitempty" validate:"omitempty,min=1,max=100"`
+	Rules      *string              `json:"rules,omitempty" validate:"omitempty,max=200"`
+	Visibility *AttributeVisibility `json:"visibility,omitempty" validate:"omitempty,oneof=public self admin"`
+	Required   *bool                `json:"required,omitempty"`
+}
+
+// Viewer is the signed in user that another user is shown to or changed by
+type Viewer struct {
+	UserID string
+	Role   string
+}
+
+func (v Viewer) IsAdmin() bool {
+	return v.Role == string(RoleAdmin)
+}
+
+// CanSee reports whether the viewer may see an attribute of the given
+// user.
+func (v Viewer) CanSee(visibility AttributeVisibility, userID string) bool {
+	switch visibility {
+	case VisibilityPublic:
+		return true
+	case VisibilitySelf:
+		return v.IsAdmin() || v.UserID == userID
+	default:
+		return v.IsAdmin()
+	}
+}
+
+// CanChange reports whether the viewer may set an attribute of the given
+// user.
+func (v Viewer) CanChange(visibility AttributeVisibility, userID string) bool {
+	if visibility == VisibilityAdmin {
+		return v.IsAdmin()
+	}
+	return v.IsAdmin() || v.UserID == userID
+}
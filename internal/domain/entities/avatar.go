@@ -0,0 +1,51 @@
+package entities
+
+import (
+	"strconv"
+	"time"
+)
+
+// Avatar is a user's profile picture, stored as square variants of the
+// uploaded image. Each upload gets a new ID so variant URLs never serve a
+// stale cached image.
+type Avatar struct {
+	ID          string          `bson:"id" json:"id"`
+	ContentType string          `bson:"content_type" json:"content_type"`
+	Variants    []AvatarVariant `bson:"variants" json:"variants"`
+	UpdatedAt   time.Time       `bson:"updated_at" json:"updated_at"`
+}
+
+type AvatarVariant struct {
+	Size int    `bson:"size" json:"size"`
+	Key  string `bson:"key" json:"-"`
+	URL  string `bson:"url" json:"url"`
+}
+
+// URL returns the URL of the largest variant
+func (a *Avatar) URL() string {
+	var largest *AvatarVariant
+	for i := range a.Variants {
+		if largest == nil || a.Variants[i].Size > largest.Size {
+			largest = &a.Variants[i]
+		}
+	}
+	if largest == nil {
+		return ""
+	}
+	return largest.URL
+}
+
+// VariantURLs maps each variant size to its URL
+func (a *Avatar) VariantURLs() map[string]string {
+	urls := make(map[string]string, len(a.Variants))
+	for _, variant := range a.Variants {
+		urls[strconv.Itoa(variant.Size)] = variant.URL
+	}
+	return urls
+}
+
+func (a *Avatar) clone() *Avatar {
+	copied := *a
+	copied.Variants = append([]AvatarVariant(nil), a.Variants...)
+	return &copied
+}
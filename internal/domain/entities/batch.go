@@ -0,0 +1,37 @@
+package entities
+
+import "encoding/json"
+
+// BatchRequest runs several API requests at once. Atomic batches commit the
+// changes of all their requests or of none.
+type BatchRequest struct {
+	Atomic   bool               `json:"atomic"`
+	Requests []BatchItemRequest `json:"requests" validate:"required,min=1,dive"`
+}
+
+// BatchItemRequest is one request of a batch. Path is an API path with its
+// query string, such as /api/v1/users/{id}?fields=username.
+type BatchItemRequest struct {
+	// ID is echoed in the response, the index of the request when empty
+	ID      string            `json:"id,omitempty" validate:"omitempty,max=64"`
+	Method  string            `json:"method" validate:"required,oneof=GET POST PUT PATCH DELETE"`
+	Path    string            `json:"path" validate:"required,startswith=/api/"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty"`
+}
+
+// BatchItemResponse is the response to one request of a batch. Body is the
+// JSON the request was answered with, or a string for other bodies.
+type BatchItemResponse struct {
+	ID      string            `json:"id"`
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty"`
+}
+
+// BatchResponse lists the responses in the order of the requests. Committed
+// is false when an atomic batch was rolled back.
+type BatchResponse struct {
+	Committed bool                `json:"committed"`
+	Responses []BatchItemResponse `json:"responses"`
+}
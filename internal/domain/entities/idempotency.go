@@ -0,0 +1,32 @@
+package entities
+
+import "time"
+
+// IdempotencyRecord remembers a request sent with an Idempotency-Key. Until
+// the request completes Response is nil and retries are refused; afterwards
+// the response is replayed to them until the record expires.
+type IdempotencyRecord struct {
+	// Key combines the caller and the header value
+	Key string `bson:"_id"`
+	// UserID is the caller, empty for anonymous requests
+	UserID string `bson:"user_id,omitempty"`
+	// Fingerprint is a keyed hash of what the request asks for, a retry must
+	// match it
+	Fingerprint string              `bson:"fingerprint"`
+	Response    *IdempotentResponse `bson:"response,omitempty"`
+	// LockToken names the request holding the key until LockedUntil, only
+	// it may complete or release the key
+	LockToken   string    `bson:"lock_token"`
+	LockedUntil time.Time `bson:"locked_until"`
+	CreatedAt   time.Time `bson:"created_at"`
+	ExpiresAt   time.Time `bson:"expires_at"`
+}
+
+// IdempotentResponse is the response a completed request was given. Body is
+// encrypted with a key only the header value unlocks, responses such as sign
+// ups carry credentials.
+type IdempotentResponse struct {
+	Status int                 `bson:"status"`
+	Header map[string][]string `bson:"header"`
+	Body   []byte              `bson:"body"`
+}
@@ -0,0 +1,59 @@
+package entities
+
+type ImportConflictMode string
+
+const (
+	ImportConflictSkip   ImportConflictMode = "skip"
+	ImportConflictUpsert ImportConflictMode = "upsert"
+)
+
+type ImportRowStatus string
+
+const (
+	ImportRowCreated ImportRowStatus = "created"
+	ImportRowUpdated ImportRowStatus = "updated"
+	ImportRowSkipped ImportRowStatus = "skipped"
+	ImportRowFailed  ImportRowStatus = "failed"
+	ImportRowValid   ImportRowStatus = "valid"
+)
+
+// ImportUserRecord is a single row of an import file. Row is the 1-based
+// record number, not counting a CSV header. Either Password or PasswordHash
+// may be set. When neither is, the user can be invited instead.
+type ImportUserRecord struct {
+	Row          int    `json:"-"`
+	Email        string `json:"email"`
+	Username     string `json:"username"`
+	Password     string `json:"password"`
+	PasswordHash string `json:"password_hash"`
+	FirstName    string `json:"first_name"`
+	LastName     string `json:"last_name"`
+	Role         string `json:"role"`
+}
+
+type ImportOptions struct {
+	DryRun          bool
+	OnConflict      ImportConflictMode
+	SendInvitations bool
+}
+
+type ImportRowResult struct {
+	Row      int             `json:"row"`
+	Email    string          `json:"email,omitempty"`
+	Username string          `json:"username,omitempty"`
+	Status   ImportRowStatus `json:"status"`
+	UserID   string          `json:"user_id,omitempty"`
+	Invited  bool            `json:"invited,omitempty"`
+	Errors   []string        `json:"errors,omitempty"`
+}
+
+type ImportReport struct {
+	DryRun  bool              `json:"dry_run"`
+	Total   int               `json:"total"`
+	Created int               `json:"created"`
+	Updated int               `json:"updated"`
+	Skipped int               `json:"skipped"`
+	Failed  int               `json:"failed"`
+	Valid   int               `json:"valid"`
+	Rows    []ImportRowResult `json:"rows"`
+}
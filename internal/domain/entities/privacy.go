@@ -0,0 +1,106 @@
+package entities
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type ErasureStatus string
+
+const (
+	ErasurePendingConfirmation ErasureStatus = "pending_confirmation"
+	ErasureScheduled           ErasureStatus = "scheduled"
+	ErasureProcessing          ErasureStatus = "processing"
+)
+
+// PersonalDataExport holds everything stored about a user, keyed by the
+// collection or subsystem it came from.
+type PersonalDataExport struct {
+	GeneratedAt time.Time              `json:"generated_at"`
+	UserID      string                 `json:"user_id"`
+	Sections    map[string]interface{} `json:"sections"`
+}
+
+// PersonalUserRecord is the user document without the password hash
+type PersonalUserRecord struct {
+	ID        string    `json:"id"`
+	Email     string    `json:"email"`
+	Username  string    `json:"username"`
+	FirstName string    `json:"first_name"`
+	LastName  string    `json:"last_name"`
+	IsActive  bool      `json:"is_active"`
+	Role      string    `json:"role"`
+	Locale    string    `json:"locale,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+}
+
+type ErasureRequest struct {
+	ID                    primitive.ObjectID `bson:"_id,omitempty"`
+	UserID                primitive.ObjectID `bson:"user_id"`
+	Status                ErasureStatus      `bson:"status"`
+	ConfirmationTokenHash string             `bson:"confirmation_token_hash"`
+	RequestedAt           time.Time          `bson:"requested_at"`
+	ConfirmedAt           *time.Time         `bson:"confirmed_at,omitempty"`
+	ScheduledFor          *time.Time         `bson:"scheduled_for,omitempty"`
+	ClaimedAt             *time.Time         `bson:"claimed_at,omitempty"`
+}
+
+type ConfirmErasureRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required"`
+}
+
+type ErasureRequestResponse struct {
+	Status            ErasureStatus `json:"status"`
+	RequestedAt       time.Time     `json:"requested_at"`
+	ConfirmedAt       *time.Time    `json:"confirmed_at,omitempty"`
+	ScheduledFor      *time.Time    `json:"scheduled_for,omitempty"`
+	ConfirmationToken string        `json:"confirmation_token,omitempty"`
+}
+
+// ErasureStep records what happened to one collection during an erasure
+type ErasureStep struct {
+	Name   string `bson:"name" json:"name"`
+	Action string `bson:"action" json:"action"`
+	Count  int64  `bson:"count" json:"count"`
+}
+
+// ErasureCertificate proves that an erasure was carried out. It deliberately
+// holds no user ID, contact details, or hash of either, and its dates are
+// truncated to the day, so it cannot be traced back to the person.
+type ErasureCertificate struct {
+	ID          string        `bson:"_id" json:"id"`
+	RequestedOn time.Time     `bson:"requested_on" json:"requested_on"`
+	CompletedOn time.Time     `bson:"completed_on" json:"completed_on"`
+	Steps       []ErasureStep `bson:"steps" json:"steps"`
+}
+
+func (r *ErasureRequest) ToResponse() ErasureRequestResponse {
+	return ErasureRequestResponse{
+		Status:       r.Status,
+		RequestedAt:  r.RequestedAt,
+		ConfirmedAt:  r.ConfirmedAt,
+		ScheduledFor: r.ScheduledFor,
+	}
+}
+
+func (u *User) ToPersonalRecord() PersonalUserRecord {
+	return PersonalUserRecord{
+		ID:        u.ID.Hex(),
+		Email:     u.Email,
+		Username:  u.Username,
+		FirstName: u.FirstName,
+		LastName:  u.LastName,
+		IsActive:  u.IsActive,
+		Role:      u.Role,
+		Locale:    u.Locale,
+		CreatedAt: u.CreatedAt,
+		UpdatedAt: u.UpdatedAt,
+
+		Attributes: u.Attributes,
+	}
+}
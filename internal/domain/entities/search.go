@@ -0,0 +1,13 @@
+package entities
+
+type UserSearchHit struct {
+	User       *User
+	Score      float64
+	Highlights map[string]string
+}
+
+type UserSearchResult struct {
+	User       UserResponse      `json:"user"`
+	Score      float64           `json:"score"`
+	Highlights map[string]string `json:"highlights"`
+}
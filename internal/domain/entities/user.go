@@ -15,8 +15,18 @@ type User struct {
 	LastName  string             `bson:"last_name" json:"last_name"`
 	IsActive  bool               `bson:"is_active" json:"is_active"`
 	Role      string             `bson:"role" json:"role"`
+	Locale    string             `bson:"locale,omitempty" json:"locale,omitempty"`
+	Version   int64              `bson:"version" json:"version"`
 	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
 	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+	Avatar    *Avatar            `bson:"avatar,omitempty" json:"avatar,omitempty"`
+
+	// Attributes holds values for the admin-defined AttributeSchemas
+	Attributes map[string]interface{} `bson:"attributes" json:"attributes,omitempty"`
+
+	// Comparison keys maintained by the repository, see pkg/identity
+	EmailCanonical    string `bson:"email_canonical" json:"-"`
+	UsernameCanonical string `bson:"username_canonical" json:"-"`
 }
 
 type UserRole string
@@ -32,6 +42,10 @@ type SignUpRequest struct {
 	Password  string `json:"password" validate:"required,min=8,max=100"`
 	FirstName string `json:"first_name" validate:"required,min=1,max=50"`
 	LastName  string `json:"last_name" validate:"required,min=1,max=50"`
+	// Locale is the preferred language of messages, e.g. de or es-MX
+	Locale string `json:"locale,omitempty" validate:"omitempty,locale"`
+
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
 }
 
 type SignInRequest struct {
@@ -43,6 +57,30 @@ type UpdateUserRequest struct {
 	FirstName *string `json:"first_name,omitempty" validate:"omitempty,min=1,max=50"`
 	LastName  *string `json:"last_name,omitempty" validate:"omitempty,min=1,max=50"`
 	Username  *string `json:"username,omitempty" validate:"omitempty,min=3,max=20,alphanum"`
+	Locale    *string `json:"locale,omitempty" validate:"omitempty,locale"`
+
+	// Attributes sets custom attributes, a null value removes one. Others
+	// are left as they are.
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+
+	// ExpectedVersion is taken from the If-Match header, not from the body
+	ExpectedVersion *int64 `json:"-"`
+	// Viewer is the caller making the change
+	Viewer Viewer `json:"-"`
+}
+
+// PatchUserRequest carries a raw JSON Merge Patch or JSON Patch document
+type PatchUserRequest struct {
+	ContentType     string
+	Document        []byte
+	ExpectedVersion *int64
+	Viewer          Viewer
+}
+
+// UserPatch is a field-level change set keyed by BSON field name
+type UserPatch struct {
+	Set   map[string]interface{}
+	Unset []string
 }
 
 type AuthResponse struct {
@@ -58,12 +96,19 @@ type UserResponse struct {
 	LastName  string    `json:"last_name"`
 	IsActive  bool      `json:"is_active"`
 	Role      string    `json:"role"`
+	Locale    string    `json:"locale,omitempty"`
+	Version   int64     `json:"version"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+
+	AvatarURL      string            `json:"avatar_url,omitempty"`
+	AvatarVariants map[string]string `json:"avatar_variants,omitempty"`
+
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
 }
 
 func (u *User) ToResponse() UserResponse {
-	return UserResponse{
+	response := UserResponse{
 		ID:        u.ID.Hex(),
 		Email:     u.Email,
 		Username:  u.Username,
@@ -71,7 +116,35 @@ func (u *User) ToResponse() UserResponse {
 		LastName:  u.LastName,
 		IsActive:  u.IsActive,
 		Role:      u.Role,
+		Locale:    u.Locale,
+		Version:   u.Version,
 		CreatedAt: u.CreatedAt,
 		UpdatedAt: u.UpdatedAt,
 	}
+	if u.Avatar != nil {
+		response.AvatarURL = u.Avatar.URL()
+		response.AvatarVariants = u.Avatar.VariantURLs()
+	}
+	if len(u.Attributes) > 0 {
+		response.Attributes = make(map[string]interface{}, len(u.Attributes))
+		for name, value := range u.Attributes {
+			response.Attributes[name] = value
+		}
+	}
+	return response
+}
+
+// Clone returns a copy of the user that shares no mutable state with it
+func (u *User) Clone() *User {
+	copied := *u
+	if u.Avatar != nil {
+		copied.Avatar = u.Avatar.clone()
+	}
+	if u.Attributes != nil {
+		copied.Attributes = make(map[string]interface{}, len(u.Attributes))
+		for name, value := range u.Attributes {
+			copied.Attributes[name] = value
+		}
+	}
+	return &copied
 }
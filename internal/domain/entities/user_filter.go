@@ -0,0 +1,14 @@
+package entities
+
+import "time"
+
+// UserFilter narrows user listings. Zero values place no constraint.
+type UserFilter struct {
+	Role          string
+	IsActive      *bool
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	// Attributes matches custom attribute values, keyed by name. A value
+	// matches the string itself or the number or boolean it spells.
+	Attributes map[string]string
+}
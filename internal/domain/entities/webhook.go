@@ -0,0 +1,103 @@
+package entities
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// WebhookAllEvents subscribes a webhook to every event type
+const WebhookAllEvents = "*"
+
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryPending   WebhookDeliveryStatus = "pending"
+	WebhookDeliverySucceeded WebhookDeliveryStatus = "succeeded"
+	WebhookDeliveryDead      WebhookDeliveryStatus = "dead"
+)
+
+type Webhook struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty"`
+	URL         string             `bson:"url"`
+	Events      []string           `bson:"events"`
+	Description string             `bson:"description"`
+	Secret      string             `bson:"secret"`
+	IsActive    bool               `bson:"is_active"`
+	CreatedAt   time.Time          `bson:"created_at"`
+	UpdatedAt   time.Time          `bson:"updated_at"`
+}
+
+type CreateWebhookRequest struct {
+	URL         string   `json:"url" validate:"required,http_url,max=2048"`
+	Events      []string `json:"events" validate:"required,min=1,dive,oneof=* user.registered user.updated user.deleted"`
+	Description string   `json:"description" validate:"max=200"`
+}
+
+type UpdateWebhookRequest struct {
+	URL         *string   `json:"url,omitempty" validate:"omitempty,http_url,max=2048"`
+	Events      *[]string `json:"events,omitempty" validate:"omitempty,min=1,dive,oneof=* user.registered user.updated user.deleted"`
+	Description *string   `json:"description,omitempty" validate:"omitempty,max=200"`
+	IsActive    *bool     `json:"is_active,omitempty"`
+}
+
+// WebhookResponse never contains the signing secret, except right after the
+// webhook has been created.
+type WebhookResponse struct {
+	ID          string    `json:"id"`
+	URL         string    `json:"url"`
+	Events      []string  `json:"events"`
+	Description string    `json:"description"`
+	IsActive    bool      `json:"is_active"`
+	Secret      string    `json:"secret,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// WebhookDeliveryAttempt is one entry in a delivery log
+type WebhookDeliveryAttempt struct {
+	At         time.Time `bson:"at" json:"at"`
+	StatusCode int       `bson:"status_code,omitempty" json:"status_code,omitempty"`
+	Error      string    `bson:"error,omitempty" json:"error,omitempty"`
+	DurationMS int64     `bson:"duration_ms" json:"duration_ms"`
+}
+
+// WebhookDelivery is one event queued for one webhook. Payload is the exact
+// body that is signed and sent.
+type WebhookDelivery struct {
+	ID            primitive.ObjectID       `bson:"_id,omitempty" json:"id"`
+	WebhookID     primitive.ObjectID       `bson:"webhook_id" json:"webhook_id"`
+	EventID       primitive.ObjectID       `bson:"event_id" json:"event_id"`
+	EventType     string                   `bson:"event_type" json:"event_type"`
+	AggregateID   string                   `bson:"aggregate_id" json:"-"`
+	Payload       string                   `bson:"payload" json:"payload"`
+	Status        WebhookDeliveryStatus    `bson:"status" json:"status"`
+	Attempts      int                      `bson:"attempts" json:"attempts"`
+	NextAttemptAt *time.Time               `bson:"next_attempt_at,omitempty" json:"next_attempt_at,omitempty"`
+	LockedUntil   *time.Time               `bson:"locked_until,omitempty" json:"-"`
+	Log           []WebhookDeliveryAttempt `bson:"log" json:"log"`
+	CreatedAt     time.Time                `bson:"created_at" json:"created_at"`
+	DeliveredAt   *time.Time               `bson:"delivered_at,omitempty" json:"delivered_at,omitempty"`
+}
+
+func (w *Webhook) ToResponse() WebhookResponse {
+	return WebhookResponse{
+		ID:          w.ID.Hex(),
+		URL:         w.URL,
+		Events:      w.Events,
+		Description: w.Description,
+		IsActive:    w.IsActive,
+		CreatedAt:   w.CreatedAt,
+		UpdatedAt:   w.UpdatedAt,
+	}
+}
+
+// Subscribes reports whether the webhook wants events of eventType
+func (w *Webhook) Subscribes(eventType string) bool {
+	for _, subscribed := range w.Events {
+		if subscribed == WebhookAllEvents || subscribed == eventType {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,133 @@
+package events
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/entities"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type Type string
+
+const (
+	UserRegistered Type = "user.registered"
+	UserUpdated    Type = "user.updated"
+	UserDeleted    Type = "user.deleted"
+)
+
+const AggregateUser = "user"
+
+// Event is a domain event. Sequence increases with every event of the same
+// aggregate, and consumers can rely on receiving them in that order.
+type Event struct {
+	ID            primitive.ObjectID     `bson:"_id,omitempty" json:"id"`
+	Type          Type                   `bson:"type" json:"type"`
+	AggregateType string                 `bson:"aggregate_type" json:"aggregate_type"`
+	AggregateID   string                 `bson:"aggregate_id" json:"aggregate_id"`
+	Sequence      int64                  `bson:"sequence" json:"sequence"`
+	OccurredAt    time.Time              `bson:"occurred_at" json:"occurred_at"`
+	Data          map[string]interface{} `bson:"data" json:"data"`
+}
+
+// Publisher delivers events to consumers outside this service. Publish may be
+// called more than once for the same event.
+type Publisher interface {
+	Publish(ctx context.Context, event *Event) error
+}
+
+// NewUserRegistered uses the version of the newly created user as sequence.
+func NewUserRegistered(user *entities.User) *Event {
+	return newUserEvent(UserRegistered, user.ID, user.Version, map[string]interface{}{
+		"email":      user.Email,
+		"username":   user.Username,
+		"first_name": user.FirstName,
+		"last_name":  user.LastName,
+		"role":       user.Role,
+	})
+}
+
+// NewUserUpdated describes the change from before to after, or returns nil
+// when no published field changed.
+func NewUserUpdated(before, after *entities.User) *Event {
+	changes := ChangedFields(before, after)
+	if len(changes) == 0 {
+		return nil
+	}
+
+	data := map[string]interface{}{
+		"changes":    changes,
+		"username":   after.Username,
+		"first_name": after.FirstName,
+		"last_name":  after.LastName,
+		"role":       after.Role,
+		"is_active":  after.IsActive,
+	}
+	if after.Avatar != nil {
+		data["avatar_url"] = after.Avatar.URL()
+	}
+	if before.Username != after.Username {
+		data["previous_username"] = before.Username
+	}
+
+	return newUserEvent(UserUpdated, after.ID, after.Version, data)
+}
+
+// NewUserDeleted takes the version the user had when it was deleted.
+func NewUserDeleted(userID primitive.ObjectID, version int64) *Event {
+	return newUserEvent(UserDeleted, userID, version+1, map[string]interface{}{})
+}
+
+// ChangedFields lists the JSON names of the user fields that differ.
+func ChangedFields(before, after *entities.User) []string {
+	var changes []string
+	if before.Email != after.Email {
+		changes = append(changes, "email")
+	}
+	if before.Username != after.Username {
+		changes = append(changes, "username")
+	}
+	if before.FirstName != after.FirstName {
+		changes = append(changes, "first_name")
+	}
+	if before.LastName != after.LastName {
+		changes = append(changes, "last_name")
+	}
+	if before.Role != after.Role {
+		changes = append(changes, "role")
+	}
+	if before.Locale != after.Locale {
+		changes = append(changes, "locale")
+	}
+	if before.IsActive != after.IsActive {
+		changes = append(changes, "is_active")
+	}
+	if avatarID(before) != avatarID(after) {
+		changes = append(changes, "avatar")
+	}
+	if (len(before.Attributes) > 0 || len(after.Attributes) > 0) && !reflect.DeepEqual(before.Attributes, after.Attributes) {
+		changes = append(changes, "attributes")
+	}
+	return changes
+}
+
+func avatarID(user *entities.User) string {
+	if user.Avatar == nil {
+		return ""
+	}
+	return user.Avatar.ID
+}
+
+func newUserEvent(eventType Type, userID primitive.ObjectID, sequence int64, data map[string]interface{}) *Event {
+	data["user_id"] = userID.Hex()
+	return &Event{
+		ID:            primitive.NewObjectID(),
+		Type:          eventType,
+		AggregateType: AggregateUser,
+		AggregateID:   userID.Hex(),
+		Sequence:      sequence,
+		OccurredAt:    time.Now().UTC(),
+		Data:          data,
+	}
+}
@@ -0,0 +1,16 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/entities"
+)
+
+type AttributeSchemaRepository interface {
+	Create(ctx context.Context, schema *entities.AttributeSchema) error
+	GetByName(ctx context.Context, name string) (*entities.AttributeSchema, error)
+	// GetAll returns every schema ordered by name
+	GetAll(ctx context.Context) ([]*entities.AttributeSchema, error)
+	Update(ctx context.Context, schema *entities.AttributeSchema) error
+	Delete(ctx context.Context, name string) error
+}
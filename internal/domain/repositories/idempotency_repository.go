@@ -0,0 +1,22 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/entities"
+)
+
+// IdempotencyRepository stores requests made with an Idempotency-Key and the
+// responses they were given
+type IdempotencyRepository interface {
+	// Reserve claims record.Key for a new request. When the key is taken it
+	// returns false and the record holding it. Expired records, and claims
+	// abandoned past LockedUntil by a request with the same fingerprint, are
+	// taken over.
+	Reserve(ctx context.Context, record *entities.IdempotencyRecord) (*entities.IdempotencyRecord, bool, error)
+	// Complete stores the response of a reserved key for replay. It returns
+	// false when the reservation of lockToken was taken over meanwhile.
+	Complete(ctx context.Context, key, lockToken string, response *entities.IdempotentResponse) (bool, error)
+	// Release gives up a reserved key, so that the request can be retried
+	Release(ctx context.Context, key, lockToken string) error
+}
@@ -0,0 +1,35 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/events"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PendingEvent is an unpublished event with the number of failed attempts to
+// publish it
+type PendingEvent struct {
+	*events.Event
+	Attempts int
+}
+
+// OutboxRepository stores domain events next to the state change that caused
+// them. Add must be called inside the same transaction as that change.
+type OutboxRepository interface {
+	Add(ctx context.Context, event *events.Event) error
+	// ListPending returns unpublished events that are due, oldest first.
+	// Events waiting to be retried are left out with the later events of
+	// their aggregate, dead-lettered events for good.
+	ListPending(ctx context.Context, limit int) ([]*PendingEvent, error)
+	MarkPublished(ctx context.Context, id primitive.ObjectID, at time.Time) error
+	// MarkFailed records a failed attempt and when to retry the event.
+	MarkFailed(ctx context.Context, id primitive.ObjectID, reason string, retryAt time.Time) error
+	// MarkDeadLettered records a failed attempt after which the event is no
+	// longer retried.
+	MarkDeadLettered(ctx context.Context, id primitive.ObjectID, reason string, at time.Time) error
+	// AcquireRelayLease makes owner the only relay for the lease duration, so
+	// that events of one aggregate are never published out of order.
+	AcquireRelayLease(ctx context.Context, owner string, lease time.Duration) (bool, error)
+}
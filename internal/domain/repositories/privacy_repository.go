@@ -0,0 +1,29 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/entities"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PersonalDataProvider is implemented by every store that references users,
+// so that data subject access and erasure cover all of them.
+type PersonalDataProvider interface {
+	Name() string
+	ExportPersonalData(ctx context.Context, userID primitive.ObjectID) (interface{}, error)
+	ErasePersonalData(ctx context.Context, userID primitive.ObjectID) (entities.ErasureStep, error)
+}
+
+type ErasureRepository interface {
+	Create(ctx context.Context, request *entities.ErasureRequest) error
+	GetByUserID(ctx context.Context, userID primitive.ObjectID) (*entities.ErasureRequest, error)
+	Update(ctx context.Context, request *entities.ErasureRequest) error
+	Delete(ctx context.Context, id primitive.ObjectID) error
+	// ClaimDue atomically marks one due request as processing. Requests
+	// claimed before staleBefore are assumed abandoned and claimed again.
+	ClaimDue(ctx context.Context, now, staleBefore time.Time) (*entities.ErasureRequest, error)
+	CreateCertificate(ctx context.Context, certificate *entities.ErasureCertificate) error
+	ListCertificates(ctx context.Context, limit, offset int) ([]*entities.ErasureCertificate, error)
+}
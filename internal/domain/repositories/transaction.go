@@ -0,0 +1,17 @@
+package repositories
+
+import "context"
+
+// TxManager runs a unit of work atomically. Repository calls made with the
+// context passed to fn take part in the transaction. Nested calls join the
+// outer transaction.
+type TxManager interface {
+	WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+	// AfterCommit runs fn once the transaction of ctx has committed, and
+	// never if it aborts. Outside a transaction fn runs right away. Effects
+	// beyond the database, such as indexing, belong here.
+	AfterCommit(ctx context.Context, fn func(ctx context.Context))
+	// Supported reports whether units of work really run in a transaction.
+	// Deployments without transactions run them without one.
+	Supported() bool
+}
@@ -10,10 +10,14 @@ import (
 type UserRepository interface {
 	Create(ctx context.Context, user *entities.User) error
 	GetByID(ctx context.Context, id primitive.ObjectID) (*entities.User, error)
+	// GetByIDs returns the users that exist, in no particular order
+	GetByIDs(ctx context.Context, ids []primitive.ObjectID) ([]*entities.User, error)
 	GetByEmail(ctx context.Context, email string) (*entities.User, error)
 	GetByUsername(ctx context.Context, username string) (*entities.User, error)
-	GetAll(ctx context.Context, limit, offset int) ([]*entities.User, error)
+	GetAll(ctx context.Context, filter entities.UserFilter, limit, offset int) ([]*entities.User, error)
+	Stream(ctx context.Context, filter entities.UserFilter, fn func(user *entities.User) error) error
 	Update(ctx context.Context, id primitive.ObjectID, user *entities.User) error
+	Patch(ctx context.Context, id primitive.ObjectID, expectedVersion int64, patch *entities.UserPatch) (*entities.User, error)
 	Delete(ctx context.Context, id primitive.ObjectID) error
 	Count(ctx context.Context) (int64, error)
 }
@@ -0,0 +1,14 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/entities"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type UserSearchIndex interface {
+	Index(ctx context.Context, user *entities.User) error
+	Remove(ctx context.Context, id primitive.ObjectID) error
+	Search(ctx context.Context, query string, limit int) ([]*entities.UserSearchHit, error)
+}
@@ -0,0 +1,32 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/entities"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type WebhookRepository interface {
+	Create(ctx context.Context, webhook *entities.Webhook) error
+	GetByID(ctx context.Context, id primitive.ObjectID) (*entities.Webhook, error)
+	GetAll(ctx context.Context, limit, offset int) ([]*entities.Webhook, error)
+	// ListSubscribed returns the active webhooks subscribed to eventType
+	ListSubscribed(ctx context.Context, eventType string) ([]*entities.Webhook, error)
+	Update(ctx context.Context, webhook *entities.Webhook) error
+	Delete(ctx context.Context, id primitive.ObjectID) error
+}
+
+type WebhookDeliveryRepository interface {
+	// Create ignores a delivery that already exists for the same webhook and
+	// event, so publishing an event twice queues it only once.
+	Create(ctx context.Context, delivery *entities.WebhookDelivery) error
+	GetByID(ctx context.Context, id primitive.ObjectID) (*entities.WebhookDelivery, error)
+	ListByWebhook(ctx context.Context, webhookID primitive.ObjectID, status entities.WebhookDeliveryStatus, limit, offset int) ([]*entities.WebhookDelivery, error)
+	// ClaimDue locks one pending delivery whose next attempt is due until
+	// lockUntil, or returns nil when there is none.
+	ClaimDue(ctx context.Context, now, lockUntil time.Time) (*entities.WebhookDelivery, error)
+	Update(ctx context.Context, delivery *entities.WebhookDelivery) error
+	DeleteByWebhook(ctx context.Context, webhookID primitive.ObjectID) error
+}
@@ -0,0 +1,32 @@
+package services
+
+import (
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/entities"
+)
+
+// ActivityPublisher is how use cases feed the live activity feed. Publish
+// must not block.
+type ActivityPublisher interface {
+	Publish(event *entities.ActivityEvent)
+	// Forget drops the data of the buffered events about the user, so that
+	// a replay no longer carries their personal data.
+	Forget(userID string)
+}
+
+type ActivityFeed interface {
+	ActivityPublisher
+	// Subscribe delivers the buffered events after lastEventID and then every
+	// new event accepted by filter. An empty lastEventID skips the replay.
+	Subscribe(lastEventID string, filter func(event *entities.ActivityEvent) bool) *ActivitySubscription
+}
+
+type ActivitySubscription struct {
+	Replay []*entities.ActivityEvent
+	// Gap is set when events after lastEventID are no longer buffered, so
+	// the subscriber has to reload its state instead of relying on Replay.
+	Gap bool
+	// Events is closed when the subscriber falls too far behind. It can
+	// resubscribe with the ID of the last event it received.
+	Events <-chan *entities.ActivityEvent
+	Cancel func()
+}
@@ -0,0 +1,17 @@
+package services
+
+import (
+	"context"
+
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/entities"
+)
+
+type AttributeService interface {
+	CreateSchema(ctx context.Context, req *entities.CreateAttributeSchemaRequest) (*entities.AttributeSchema, error)
+	GetSchema(ctx context.Context, name string) (*entities.AttributeSchema, error)
+	// ListSchemas returns the schemas of the attributes viewer may see on
+	// their own profile.
+	ListSchemas(ctx context.Context, viewer entities.Viewer) ([]*entities.AttributeSchema, error)
+	UpdateSchema(ctx context.Context, name string, req *entities.UpdateAttributeSchemaRequest) (*entities.AttributeSchema, error)
+	DeleteSchema(ctx context.Context, name string) error
+}
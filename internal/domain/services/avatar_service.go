@@ -0,0 +1,26 @@
+package services
+
+import (
+	"context"
+
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/entities"
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/repositories"
+)
+
+// AvatarService is also the PersonalDataProvider that exports avatar
+// metadata and deletes the stored images during erasure.
+type AvatarService interface {
+	repositories.PersonalDataProvider
+
+	UploadAvatar(ctx context.Context, userID string, data []byte) (*entities.UserResponse, error)
+	DeleteAvatar(ctx context.Context, userID string) (*entities.UserResponse, error)
+}
+
+// BlobStore keeps binary objects, such as avatar images, under slash
+// separated keys and serves them from public URLs.
+type BlobStore interface {
+	Put(ctx context.Context, key, contentType string, data []byte) error
+	// Delete succeeds when the key does not exist
+	Delete(ctx context.Context, key string) error
+	URL(key string) string
+}
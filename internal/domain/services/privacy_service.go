@@ -0,0 +1,19 @@
+package services
+
+import (
+	"context"
+
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/entities"
+)
+
+type PrivacyService interface {
+	ExportPersonalData(ctx context.Context, userID string) (*entities.PersonalDataExport, error)
+	RequestErasure(ctx context.Context, userID string) (*entities.ErasureRequestResponse, error)
+	ConfirmErasure(ctx context.Context, userID string, req *entities.ConfirmErasureRequest) (*entities.ErasureRequestResponse, error)
+	CancelErasure(ctx context.Context, userID string) error
+	GetErasureStatus(ctx context.Context, userID string) (*entities.ErasureRequestResponse, error)
+	// ProcessDueErasures carries out every confirmed erasure whose grace
+	// period has passed and returns how many were completed.
+	ProcessDueErasures(ctx context.Context) (int, error)
+	ListErasureCertificates(ctx context.Context, limit, offset int) ([]*entities.ErasureCertificate, error)
+}
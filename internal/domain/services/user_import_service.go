@@ -0,0 +1,22 @@
+package services
+
+import (
+	"context"
+
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/entities"
+)
+
+// ImportSource streams records from an import file. Next returns io.EOF when
+// the file is exhausted. Errors wrapping errors.ErrMalformedRow only affect the
+// current row; any other error aborts the import.
+type ImportSource interface {
+	Next() (*entities.ImportUserRecord, error)
+}
+
+type InvitationSender interface {
+	SendInvitation(ctx context.Context, user *entities.User, temporaryPassword string) error
+}
+
+type UserImportService interface {
+	ImportUsers(ctx context.Context, source ImportSource, opts entities.ImportOptions) (*entities.ImportReport, error)
+}
@@ -9,8 +9,14 @@ import (
 type UserService interface {
 	SignUp(ctx context.Context, req *entities.SignUpRequest) (*entities.AuthResponse, error)
 	SignIn(ctx context.Context, req *entities.SignInRequest) (*entities.AuthResponse, error)
-	GetUserByID(ctx context.Context, id string) (*entities.UserResponse, error)
-	GetAllUsers(ctx context.Context, limit, offset int) ([]*entities.UserResponse, error)
+	GetUserByID(ctx context.Context, id string, viewer entities.Viewer) (*entities.UserResponse, error)
+	// GetUsersByIDs looks up several users at once. Users that do not exist
+	// are missing from the result, which is keyed by ID.
+	GetUsersByIDs(ctx context.Context, ids []string, viewer entities.Viewer) (map[string]*entities.UserResponse, error)
+	GetAllUsers(ctx context.Context, filter entities.UserFilter, limit, offset int) ([]*entities.UserResponse, error)
+	ExportUsers(ctx context.Context, filter entities.UserFilter, fn func(user *entities.UserResponse) error) error
 	UpdateUser(ctx context.Context, id string, req *entities.UpdateUserRequest) (*entities.UserResponse, error)
+	PatchUser(ctx context.Context, id string, req *entities.PatchUserRequest) (*entities.UserResponse, error)
 	DeleteUser(ctx context.Context, id string) error
+	SearchUsers(ctx context.Context, query string, limit int) ([]*entities.UserSearchResult, error)
 }
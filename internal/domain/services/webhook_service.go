@@ -0,0 +1,31 @@
+package services
+
+import (
+	"context"
+
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/entities"
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/events"
+)
+
+// WebhookService is also the events.Publisher that queues a delivery for
+// each subscribed webhook. The deliveries are sent by ProcessDueDeliveries.
+type WebhookService interface {
+	events.Publisher
+
+	CreateWebhook(ctx context.Context, req *entities.CreateWebhookRequest) (*entities.WebhookResponse, error)
+	GetWebhook(ctx context.Context, id string) (*entities.WebhookResponse, error)
+	GetAllWebhooks(ctx context.Context, limit, offset int) ([]*entities.WebhookResponse, error)
+	UpdateWebhook(ctx context.Context, id string, req *entities.UpdateWebhookRequest) (*entities.WebhookResponse, error)
+	DeleteWebhook(ctx context.Context, id string) error
+	ListDeliveries(ctx context.Context, webhookID string, status entities.WebhookDeliveryStatus, limit, offset int) ([]*entities.WebhookDelivery, error)
+	Redeliver(ctx context.Context, webhookID, deliveryID string) (*entities.WebhookDelivery, error)
+	// ProcessDueDeliveries sends every delivery whose next attempt is due and
+	// returns how many were attempted.
+	ProcessDueDeliveries(ctx context.Context) (int, error)
+}
+
+// WebhookSender signs a delivery payload and sends it to the webhook URL. It
+// returns the response status code, or an error when no response arrived.
+type WebhookSender interface {
+	Send(ctx context.Context, webhook *entities.Webhook, delivery *entities.WebhookDelivery) (int, error)
+}
@@ -0,0 +1,136 @@
+package activity
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/entities"
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/services"
+)
+
+const subscriberBuffer = 64
+
+type subscriber struct {
+	events chan *entities.ActivityEvent
+	filter func(event *entities.ActivityEvent) bool
+}
+
+// Broker is an in-process activity feed that keeps the most recent events
+// for replay. Event IDs are "<epoch>-<sequence>", where the epoch changes
+// with every process, so IDs from another instance or an earlier run are
+// reported as a gap instead of being misread.
+type Broker struct {
+	mu          sync.Mutex
+	epoch       string
+	sequence    uint64
+	capacity    int
+	buffer      []*entities.ActivityEvent
+	subscribers map[*subscriber]struct{}
+}
+
+func NewBroker(capacity int) *Broker {
+	return &Broker{
+		epoch:       strconv.FormatInt(time.Now().UnixNano(), 36),
+		capacity:    capacity,
+		subscribers: make(map[*subscriber]struct{}),
+	}
+}
+
+func (b *Broker) Publish(event *entities.ActivityEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.sequence++
+	published := *event
+	published.ID = b.epoch + "-" + strconv.FormatUint(b.sequence, 10)
+	if published.OccurredAt.IsZero() {
+		published.OccurredAt = time.Now().UTC()
+	}
+
+	b.buffer = append(b.buffer, &published)
+	if len(b.buffer) > b.capacity {
+		b.buffer = b.buffer[len(b.buffer)-b.capacity:]
+	}
+
+	for sub := range b.subscribers {
+		if !sub.filter(&published) {
+			continue
+		}
+		select {
+		case sub.events <- &published:
+		default:
+			// Too slow, it has to reconnect and replay
+			delete(b.subscribers, sub)
+			close(sub.events)
+		}
+	}
+}
+
+// Forget replaces the buffered events about the user by copies without
+// data. They stay in place, since replay relies on the buffer holding
+// consecutive sequences, and events already handed out are not modified.
+func (b *Broker) Forget(userID string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for i, event := range b.buffer {
+		if event.UserID != userID || event.Data == nil {
+			continue
+		}
+		redacted := *event
+		redacted.Data = nil
+		b.buffer[i] = &redacted
+	}
+}
+
+func (b *Broker) Subscribe(lastEventID string, filter func(event *entities.ActivityEvent) bool) *services.ActivitySubscription {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	sub := &subscriber{
+		events: make(chan *entities.ActivityEvent, subscriberBuffer),
+		filter: filter,
+	}
+	b.subscribers[sub] = struct{}{}
+
+	subscription := &services.ActivitySubscription{
+		Events: sub.events,
+		Cancel: func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			if _, ok := b.subscribers[sub]; ok {
+				delete(b.subscribers, sub)
+				close(sub.events)
+			}
+		},
+	}
+
+	if lastEventID != "" {
+		subscription.Replay, subscription.Gap = b.replay(lastEventID, filter)
+	}
+
+	return subscription
+}
+
+// replay must be called with the lock held.
+func (b *Broker) replay(lastEventID string, filter func(event *entities.ActivityEvent) bool) ([]*entities.ActivityEvent, bool) {
+	epoch, sequenceText, ok := strings.Cut(lastEventID, "-")
+	sequence, err := strconv.ParseUint(sequenceText, 10, 64)
+	if !ok || err != nil || epoch != b.epoch || sequence > b.sequence {
+		return nil, true
+	}
+
+	// The buffer holds the consecutive sequences ending at b.sequence
+	oldest := b.sequence - uint64(len(b.buffer)) + 1
+	gap := sequence+1 < oldest
+
+	var replay []*entities.ActivityEvent
+	for i, event := range b.buffer {
+		if oldest+uint64(i) > sequence && filter(event) {
+			replay = append(replay, event)
+		}
+	}
+	return replay, gap
+}
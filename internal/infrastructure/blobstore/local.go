@@ -0,0 +1,92 @@
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// LocalMediaRoute is where the API serves blobs kept on the local filesystem
+const LocalMediaRoute = "/media"
+
+var ErrInvalidKey = errors.New("invalid blob key")
+
+// LocalStore keeps blobs as files below a root directory. Files are written
+// to a temporary name and renamed, so readers never see a partial image.
+type LocalStore struct {
+	root    string
+	baseURL string
+}
+
+func NewLocalStore(root, baseURL string) (*LocalStore, error) {
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, err
+	}
+	return &LocalStore{
+		root:    root,
+		baseURL: strings.TrimRight(baseURL, "/"),
+	}, nil
+}
+
+func (s *LocalStore) Put(ctx context.Context, key, contentType string, data []byte) error {
+	filename, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(filename), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filename)
+}
+
+func (s *LocalStore) Delete(ctx context.Context, key string) error {
+	filename, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(filename); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	// Remove the directories the key created once they are empty
+	for dir := filepath.Dir(filename); dir != filepath.Clean(s.root); dir = filepath.Dir(dir) {
+		if os.Remove(dir) != nil {
+			break
+		}
+	}
+	return nil
+}
+
+func (s *LocalStore) URL(key string) string {
+	return s.baseURL + "/" + key
+}
+
+// path maps a key to a file below the root, rejecting keys that would
+// escape it.
+func (s *LocalStore) path(key string) (string, error) {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") || path.Clean(key) != key ||
+		key == ".." || strings.HasPrefix(key, "../") {
+		return "", ErrInvalidKey
+	}
+	return filepath.Join(s.root, filepath.FromSlash(key)), nil
+}
@@ -0,0 +1,28 @@
+package cache
+
+import "context"
+
+// Invalidator broadcasts cache invalidations to other application instances.
+// Subscribers are only notified of invalidations published by other instances.
+type Invalidator interface {
+	Publish(ctx context.Context, key string) error
+	Subscribe(fn func(key string))
+	Close() error
+}
+
+// LocalInvalidator is used when a single instance is running.
+type LocalInvalidator struct{}
+
+func NewLocalInvalidator() *LocalInvalidator {
+	return &LocalInvalidator{}
+}
+
+func (l *LocalInvalidator) Publish(ctx context.Context, key string) error {
+	return nil
+}
+
+func (l *LocalInvalidator) Subscribe(fn func(key string)) {}
+
+func (l *LocalInvalidator) Close() error {
+	return nil
+}
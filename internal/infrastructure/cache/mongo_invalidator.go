@@ -0,0 +1,135 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/kaa-dan/clean-architecture-go/pkg/logger"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	invalidationsCollection = "cache_invalidations"
+	invalidationsSizeBytes  = 1 << 20
+	tailRetryInterval       = time.Second
+)
+
+type invalidation struct {
+	ID     primitive.ObjectID `bson:"_id"`
+	Key    string             `bson:"key"`
+	Source string             `bson:"source"`
+}
+
+// MongoInvalidator shares invalidations through a capped collection that
+// every instance tails. It works on standalone servers as well as replica sets.
+type MongoInvalidator struct {
+	collection *mongo.Collection
+	source     string
+	ctx        context.Context
+	cancel     context.CancelFunc
+
+	mu          sync.Mutex
+	subscribers []func(key string)
+}
+
+func NewMongoInvalidator(client *mongo.Client, dbName string) (*MongoInvalidator, error) {
+	db := client.Database(dbName)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := options.CreateCollection().SetCapped(true).SetSizeInBytes(invalidationsSizeBytes)
+	if err := db.CreateCollection(ctx, invalidationsCollection, opts); err != nil {
+		// Code 48 is NamespaceExists
+		var commandErr mongo.CommandError
+		if !errors.As(err, &commandErr) || commandErr.Code != 48 {
+			return nil, err
+		}
+	}
+
+	runCtx, runCancel := context.WithCancel(context.Background())
+	invalidator := &MongoInvalidator{
+		collection: db.Collection(invalidationsCollection),
+		source:     primitive.NewObjectID().Hex(),
+		ctx:        runCtx,
+		cancel:     runCancel,
+	}
+
+	go invalidator.tail()
+
+	return invalidator, nil
+}
+
+func (m *MongoInvalidator) Publish(ctx context.Context, key string) error {
+	_, err := m.collection.InsertOne(ctx, invalidation{
+		ID:     primitive.NewObjectID(),
+		Key:    key,
+		Source: m.source,
+	})
+	return err
+}
+
+func (m *MongoInvalidator) Subscribe(fn func(key string)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.subscribers = append(m.subscribers, fn)
+}
+
+func (m *MongoInvalidator) Close() error {
+	m.cancel()
+	return nil
+}
+
+func (m *MongoInvalidator) tail() {
+	// Only invalidations published after startup are relevant
+	lastSeen := primitive.NewObjectIDFromTimestamp(time.Now())
+
+	for {
+		opts := options.Find().SetCursorType(options.TailableAwait)
+		cursor, err := m.collection.Find(m.ctx, bson.M{"_id": bson.M{"$gt": lastSeen}}, opts)
+		if err == nil {
+			for cursor.Next(m.ctx) {
+				var inv invalidation
+				if err := cursor.Decode(&inv); err != nil {
+					logger.Warnf("failed to decode cache invalidation: %v", err)
+					continue
+				}
+				lastSeen = inv.ID
+				if inv.Source != m.source {
+					m.notify(inv.Key)
+				}
+			}
+			err = cursor.Err()
+			cursor.Close(context.Background())
+		}
+
+		if m.ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			logger.Warnf("cache invalidation cursor failed: %v", err)
+		}
+
+		// A tailable cursor on an empty capped collection dies immediately
+		select {
+		case <-m.ctx.Done():
+			return
+		case <-time.After(tailRetryInterval):
+		}
+	}
+}
+
+func (m *MongoInvalidator) notify(key string) {
+	m.mu.Lock()
+	subscribers := append([]func(string){}, m.subscribers...)
+	m.mu.Unlock()
+
+	for _, fn := range subscribers {
+		fn(key)
+	}
+}
@@ -0,0 +1,146 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/kaa-dan/clean-architecture-go/pkg/logger"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	maxTransactionAttempts = 5
+	maxCommitAttempts      = 3
+	retryBackoff           = 50 * time.Millisecond
+
+	labelTransientTransactionError = "TransientTransactionError"
+	labelUnknownCommitResult       = "UnknownTransactionCommitResult"
+)
+
+// afterCommitKey holds the *afterCommit of the outermost transaction
+type afterCommitKey struct{}
+
+type afterCommit struct {
+	mu    sync.Mutex
+	hooks []func(ctx context.Context)
+}
+
+type TxManager struct {
+	client    *mongo.Client
+	supported bool
+}
+
+// NewTxManager checks whether the deployment supports multi-document
+// transactions. Standalone servers do not, in which case units of work run
+// without a transaction so local development keeps working.
+func NewTxManager(client *mongo.Client) *TxManager {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	supported := supportsTransactions(ctx, client)
+	if !supported {
+		logger.Warn("MongoDB deployment is not a replica set or sharded cluster, transactions are disabled")
+	}
+
+	return &TxManager{
+		client:    client,
+		supported: supported,
+	}
+}
+
+func (m *TxManager) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	// Join the transaction that is already running
+	if mongo.SessionFromContext(ctx) != nil || !m.supported {
+		return fn(ctx)
+	}
+
+	session, err := m.client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(context.Background())
+
+	for attempt := 1; ; attempt++ {
+		// Hooks of an aborted attempt are dropped with it
+		pending := &afterCommit{}
+		err := m.runTransaction(context.WithValue(ctx, afterCommitKey{}, pending), session, fn)
+		if err == nil {
+			for _, hook := range pending.hooks {
+				hook(ctx)
+			}
+			return nil
+		}
+
+		if attempt >= maxTransactionAttempts || !hasErrorLabel(err, labelTransientTransactionError) {
+			return err
+		}
+
+		logger.Debugf("retrying transient transaction error (attempt %d): %v", attempt, err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Duration(attempt) * retryBackoff):
+		}
+	}
+}
+
+func (m *TxManager) AfterCommit(ctx context.Context, fn func(ctx context.Context)) {
+	if pending, ok := ctx.Value(afterCommitKey{}).(*afterCommit); ok {
+		pending.mu.Lock()
+		pending.hooks = append(pending.hooks, fn)
+		pending.mu.Unlock()
+		return
+	}
+	fn(ctx)
+}
+
+func (m *TxManager) Supported() bool {
+	return m.supported
+}
+
+func (m *TxManager) runTransaction(ctx context.Context, session mongo.Session, fn func(ctx context.Context) error) error {
+	if err := session.StartTransaction(); err != nil {
+		return err
+	}
+
+	sessionCtx := mongo.NewSessionContext(ctx, session)
+	if err := fn(sessionCtx); err != nil {
+		if abortErr := session.AbortTransaction(context.Background()); abortErr != nil {
+			logger.Warnf("failed to abort transaction: %v", abortErr)
+		}
+		return err
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := session.CommitTransaction(sessionCtx)
+		if err == nil || attempt >= maxCommitAttempts || !hasErrorLabel(err, labelUnknownCommitResult) {
+			return err
+		}
+	}
+}
+
+func hasErrorLabel(err error, label string) bool {
+	var labeled mongo.LabeledError
+	return errors.As(err, &labeled) && labeled.HasErrorLabel(label)
+}
+
+func supportsTransactions(ctx context.Context, client *mongo.Client) bool {
+	var result struct {
+		SetName string `bson:"setName"`
+		Msg     string `bson:"msg"`
+	}
+
+	err := client.Database("admin").RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&result)
+	if err != nil {
+		err = client.Database("admin").RunCommand(ctx, bson.D{{Key: "isMaster", Value: 1}}).Decode(&result)
+	}
+	if err != nil {
+		logger.Warnf("failed to detect MongoDB topology: %v", err)
+		return false
+	}
+
+	return result.SetName != "" || result.Msg == "isdbgrid"
+}
@@ -0,0 +1,79 @@
+package importer
+
+import (
+	"encoding/csv"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/entities"
+	"github.com/kaa-dan/clean-architecture-go/pkg/errors"
+)
+
+var csvColumns = map[string]func(record *entities.ImportUserRecord, value string){
+	"email":         func(r *entities.ImportUserRecord, v string) { r.Email = v },
+	"username":      func(r *entities.ImportUserRecord, v string) { r.Username = v },
+	"password":      func(r *entities.ImportUserRecord, v string) { r.Password = v },
+	"password_hash": func(r *entities.ImportUserRecord, v string) { r.PasswordHash = v },
+	"first_name":    func(r *entities.ImportUserRecord, v string) { r.FirstName = v },
+	"last_name":     func(r *entities.ImportUserRecord, v string) { r.LastName = v },
+	"role":          func(r *entities.ImportUserRecord, v string) { r.Role = v },
+}
+
+// CSVSource reads a CSV file whose first line names the columns. Unknown
+// columns are ignored.
+type CSVSource struct {
+	reader  *csv.Reader
+	columns []func(record *entities.ImportUserRecord, value string)
+	row     int
+}
+
+func NewCSVSource(r io.Reader) (*CSVSource, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("%w: CSV file is empty", errors.ErrBadRequest)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid CSV header: %v", errors.ErrBadRequest, err)
+	}
+
+	columns := make([]func(*entities.ImportUserRecord, string), len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		columns[i] = csvColumns[name]
+	}
+
+	return &CSVSource{
+		reader:  reader,
+		columns: columns,
+	}, nil
+}
+
+func (s *CSVSource) Next() (*entities.ImportUserRecord, error) {
+	fields, err := s.reader.Read()
+	if err == io.EOF {
+		return nil, io.EOF
+	}
+	s.row++
+
+	if err != nil {
+		var parseErr *csv.ParseError
+		if stderrors.As(err, &parseErr) {
+			return nil, fmt.Errorf("%w: %v", errors.ErrMalformedRow, err)
+		}
+		return nil, err
+	}
+
+	record := &entities.ImportUserRecord{Row: s.row}
+	for i, value := range fields {
+		if i < len(s.columns) && s.columns[i] != nil {
+			s.columns[i](record, strings.TrimSpace(value))
+		}
+	}
+	return record, nil
+}
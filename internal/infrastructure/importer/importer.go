@@ -0,0 +1,25 @@
+package importer
+
+import (
+	"io"
+
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/services"
+	"github.com/kaa-dan/clean-architecture-go/pkg/errors"
+)
+
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+)
+
+// NewSource returns a streaming record source for the given file format.
+func NewSource(format string, r io.Reader) (services.ImportSource, error) {
+	switch format {
+	case FormatCSV:
+		return NewCSVSource(r)
+	case FormatNDJSON:
+		return NewNDJSONSource(r), nil
+	default:
+		return nil, errors.ErrUnsupportedFormat
+	}
+}
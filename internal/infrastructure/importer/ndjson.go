@@ -0,0 +1,53 @@
+package importer
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/entities"
+	"github.com/kaa-dan/clean-architecture-go/pkg/errors"
+)
+
+const maxNDJSONLineBytes = 1 << 20
+
+// NDJSONSource reads one JSON object per line. Blank lines are skipped.
+type NDJSONSource struct {
+	scanner *bufio.Scanner
+	row     int
+}
+
+func NewNDJSONSource(r io.Reader) *NDJSONSource {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxNDJSONLineBytes)
+
+	return &NDJSONSource{
+		scanner: scanner,
+	}
+}
+
+func (s *NDJSONSource) Next() (*entities.ImportUserRecord, error) {
+	for s.scanner.Scan() {
+		line := strings.TrimSpace(s.scanner.Text())
+		if line == "" {
+			continue
+		}
+		s.row++
+
+		var record entities.ImportUserRecord
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			return nil, fmt.Errorf("%w: %v", errors.ErrMalformedRow, err)
+		}
+		record.Row = s.row
+		record.Email = strings.TrimSpace(record.Email)
+		record.Username = strings.TrimSpace(record.Username)
+		return &record, nil
+	}
+
+	if err := s.scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
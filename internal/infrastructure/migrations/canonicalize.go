@@ -0,0 +1,89 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/kaa-dan/clean-architecture-go/pkg/identity"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var ErrIdentityCollisions = errors.New("users share a canonical email or username")
+
+// canonicalizeUsers backfills the canonical keys and moves the unique
+// constraints onto them. It refuses to run while collisions exist, because
+// the unique indexes could not be built. The indexes also use a
+// case-insensitive collation, as a safeguard for writes that bypass the
+// repository.
+func canonicalizeUsers(canonicalizer *identity.Canonicalizer) func(ctx context.Context, db *mongo.Database) error {
+	return func(ctx context.Context, db *mongo.Database) error {
+		collisions, err := FindCollisions(ctx, db, canonicalizer)
+		if err != nil {
+			return err
+		}
+		if len(collisions) > 0 {
+			return fmt.Errorf("%w: %d collision(s), run `migrate collisions` and resolve them first", ErrIdentityCollisions, len(collisions))
+		}
+
+		if err := RecomputeCanonicalKeys(ctx, db, canonicalizer); err != nil {
+			return err
+		}
+
+		users := db.Collection("users")
+		for _, name := range []string{"email_1", "username_1"} {
+			if _, err := users.Indexes().DropOne(ctx, name); err != nil && !isIndexNotFound(err) {
+				return err
+			}
+		}
+
+		collation := &options.Collation{Locale: "en", Strength: 2}
+		return CreateIndexes("users",
+			mongo.IndexModel{
+				Keys:    bson.D{{Key: "email_canonical", Value: 1}},
+				Options: options.Index().SetUnique(true).SetCollation(collation),
+			},
+			mongo.IndexModel{
+				Keys:    bson.D{{Key: "username_canonical", Value: 1}},
+				Options: options.Index().SetUnique(true).SetCollation(collation),
+			},
+		)(ctx, db)
+	}
+}
+
+func isIndexNotFound(err error) bool {
+	// Code 27 is IndexNotFound
+	var commandErr mongo.CommandError
+	return errors.As(err, &commandErr) && commandErr.Code == 27
+}
+
+// RecomputeCanonicalKeys rewrites the canonical keys of every user. It has to
+// run after the canonicalization rules change. Check for collisions first,
+// because the unique indexes reject keys that clash.
+func RecomputeCanonicalKeys(ctx context.Context, db *mongo.Database, canonicalizer *identity.Canonicalizer) error {
+	users := db.Collection("users")
+	cursor, err := users.Find(ctx, bson.M{}, options.Find().
+		SetProjection(bson.M{"email": 1, "username": 1}).
+		SetBatchSize(1000))
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var candidate collisionCandidate
+		if err := cursor.Decode(&candidate); err != nil {
+			return err
+		}
+		_, err := users.UpdateOne(ctx, bson.M{"_id": candidate.ID}, bson.M{"$set": bson.M{
+			"email_canonical":    canonicalizer.Email(candidate.Email),
+			"username_canonical": canonicalizer.Username(candidate.Username),
+		}})
+		if err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
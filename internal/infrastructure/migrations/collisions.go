@@ -0,0 +1,92 @@
+package migrations
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/kaa-dan/clean-architecture-go/pkg/identity"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Collision is a group of users whose email or username share a canonical
+// key. Only one of them can keep it once the canonical unique indexes exist.
+type Collision struct {
+	Field string          `json:"field"`
+	Key   string          `json:"key"`
+	Users []CollisionUser `json:"users"`
+}
+
+type CollisionUser struct {
+	ID        string    `json:"id"`
+	Value     string    `json:"value"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type collisionCandidate struct {
+	ID        primitive.ObjectID `bson:"_id"`
+	Email     string             `bson:"email"`
+	Username  string             `bson:"username"`
+	CreatedAt time.Time          `bson:"created_at"`
+}
+
+// FindCollisions canonicalizes every stored email and username and reports
+// the keys used by more than one user, oldest user first.
+func FindCollisions(ctx context.Context, db *mongo.Database, canonicalizer *identity.Canonicalizer) ([]Collision, error) {
+	opts := options.Find().
+		SetProjection(bson.M{"email": 1, "username": 1, "created_at": 1}).
+		SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}).
+		SetBatchSize(1000)
+
+	cursor, err := db.Collection("users").Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	emails := make(map[string][]CollisionUser)
+	usernames := make(map[string][]CollisionUser)
+	for cursor.Next(ctx) {
+		var candidate collisionCandidate
+		if err := cursor.Decode(&candidate); err != nil {
+			return nil, err
+		}
+
+		emailKey := canonicalizer.Email(candidate.Email)
+		emails[emailKey] = append(emails[emailKey], CollisionUser{
+			ID:        candidate.ID.Hex(),
+			Value:     candidate.Email,
+			CreatedAt: candidate.CreatedAt,
+		})
+
+		usernameKey := canonicalizer.Username(candidate.Username)
+		usernames[usernameKey] = append(usernames[usernameKey], CollisionUser{
+			ID:        candidate.ID.Hex(),
+			Value:     candidate.Username,
+			CreatedAt: candidate.CreatedAt,
+		})
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	collisions := collisionGroups("email", emails)
+	collisions = append(collisions, collisionGroups("username", usernames)...)
+	return collisions, nil
+}
+
+func collisionGroups(field string, groups map[string][]CollisionUser) []Collision {
+	var collisions []Collision
+	for key, users := range groups {
+		if len(users) > 1 {
+			collisions = append(collisions, Collision{Field: field, Key: key, Users: users})
+		}
+	}
+	sort.Slice(collisions, func(i, j int) bool {
+		return collisions[i].Key < collisions[j].Key
+	})
+	return collisions
+}
@@ -0,0 +1,41 @@
+package migrations
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type Migration struct {
+	Version     int
+	Description string
+	Up          func(ctx context.Context, db *mongo.Database) error
+}
+
+// CreateIndexes returns a migration step that creates indexes on a collection.
+func CreateIndexes(collection string, indexes ...mongo.IndexModel) func(ctx context.Context, db *mongo.Database) error {
+	return func(ctx context.Context, db *mongo.Database) error {
+		_, err := db.Collection(collection).Indexes().CreateMany(ctx, indexes)
+		return err
+	}
+}
+
+// Backfill returns a migration step that sets fields on every matching document.
+func Backfill(collection string, filter, set bson.M) func(ctx context.Context, db *mongo.Database) error {
+	return func(ctx context.Context, db *mongo.Database) error {
+		_, err := db.Collection(collection).UpdateMany(ctx, filter, bson.M{"$set": set})
+		return err
+	}
+}
+
+// RenameField returns a migration step that renames a field on every document.
+func RenameField(collection, from, to string) func(ctx context.Context, db *mongo.Database) error {
+	return func(ctx context.Context, db *mongo.Database) error {
+		_, err := db.Collection(collection).UpdateMany(ctx,
+			bson.M{from: bson.M{"$exists": true}},
+			bson.M{"$rename": bson.M{from: to}},
+		)
+		return err
+	}
+}
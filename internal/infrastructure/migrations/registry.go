@@ -0,0 +1,161 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/kaa-dan/clean-architecture-go/pkg/identity"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// All returns every migration in version order. Never change or remove a
+// migration once it has been released, add a new one instead.
+func All(canonicalizer *identity.Canonicalizer) []Migration {
+	return []Migration{
+		{
+			Version:     1,
+			Description: "create unique email and username indexes on users",
+			Up: CreateIndexes("users",
+				mongo.IndexModel{
+					Keys:    bson.D{{Key: "email", Value: 1}},
+					Options: options.Index().SetUnique(true),
+				},
+				mongo.IndexModel{
+					Keys:    bson.D{{Key: "username", Value: 1}},
+					Options: options.Index().SetUnique(true),
+				},
+			),
+		},
+		{
+			Version:     2,
+			Description: "backfill version on users created before optimistic concurrency",
+			Up: Backfill("users",
+				bson.M{"version": bson.M{"$exists": false}},
+				bson.M{"version": int64(1)},
+			),
+		},
+		{
+			Version:     3,
+			Description: "create erasure request and certificate indexes",
+			Up: func(ctx context.Context, db *mongo.Database) error {
+				err := CreateIndexes("erasure_requests",
+					mongo.IndexModel{
+						Keys:    bson.D{{Key: "user_id", Value: 1}},
+						Options: options.Index().SetUnique(true),
+					},
+					mongo.IndexModel{
+						Keys: bson.D{{Key: "status", Value: 1}, {Key: "scheduled_for", Value: 1}},
+					},
+				)(ctx, db)
+				if err != nil {
+					return err
+				}
+				return CreateIndexes("erasure_certificates",
+					mongo.IndexModel{Keys: bson.D{{Key: "completed_on", Value: -1}}},
+				)(ctx, db)
+			},
+		},
+		{
+			Version:     4,
+			Description: "create outbox indexes and expire published events after 7 days",
+			Up: CreateIndexes("outbox",
+				mongo.IndexModel{
+					Keys: bson.D{{Key: "published_at", Value: 1}, {Key: "occurred_at", Value: 1}},
+				},
+				mongo.IndexModel{
+					Keys: bson.D{{Key: "aggregate_type", Value: 1}, {Key: "aggregate_id", Value: 1}, {Key: "sequence", Value: 1}},
+				},
+				mongo.IndexModel{
+					Keys:    bson.D{{Key: "published_at", Value: 1}},
+					Options: options.Index().SetExpireAfterSeconds(7 * 24 * 60 * 60),
+				},
+			),
+		},
+		{
+			Version:     5,
+			Description: "create webhook and webhook delivery indexes",
+			Up: func(ctx context.Context, db *mongo.Database) error {
+				err := CreateIndexes("webhooks",
+					mongo.IndexModel{Keys: bson.D{{Key: "is_active", Value: 1}, {Key: "events", Value: 1}}},
+				)(ctx, db)
+				if err != nil {
+					return err
+				}
+				return CreateIndexes("webhook_deliveries",
+					mongo.IndexModel{
+						Keys:    bson.D{{Key: "webhook_id", Value: 1}, {Key: "event_id", Value: 1}},
+						Options: options.Index().SetUnique(true),
+					},
+					mongo.IndexModel{Keys: bson.D{{Key: "status", Value: 1}, {Key: "next_attempt_at", Value: 1}}},
+					mongo.IndexModel{Keys: bson.D{{Key: "webhook_id", Value: 1}, {Key: "created_at", Value: -1}}},
+					mongo.IndexModel{Keys: bson.D{{Key: "aggregate_id", Value: 1}}},
+				)(ctx, db)
+			},
+		},
+		{
+			Version:     6,
+			Description: "replace case-sensitive email and username indexes with canonical keys",
+			Up:          canonicalizeUsers(canonicalizer),
+		},
+		{
+			Version:     7,
+			Description: "create attribute schema index and index custom attribute values",
+			Up: func(ctx context.Context, db *mongo.Database) error {
+				err := CreateIndexes("attribute_schemas",
+					mongo.IndexModel{
+						Keys:    bson.D{{Key: "name", Value: 1}},
+						Options: options.Index().SetUnique(true),
+					},
+				)(ctx, db)
+				if err != nil {
+					return err
+				}
+				return CreateIndexes("users",
+					mongo.IndexModel{Keys: bson.D{{Key: "attributes.$**", Value: 1}}},
+				)(ctx, db)
+			},
+		},
+		{
+			Version:     8,
+			Description: "expire idempotency keys at expires_at",
+			Up: CreateIndexes("idempotency_keys",
+				mongo.IndexModel{
+					Keys:    bson.D{{Key: "expires_at", Value: 1}},
+					Options: options.Index().SetExpireAfterSeconds(0),
+				},
+			),
+		},
+		{
+			Version:     9,
+			Description: "index idempotency keys by user for data export and erasure",
+			Up: CreateIndexes("idempotency_keys",
+				mongo.IndexModel{Keys: bson.D{{Key: "user_id", Value: 1}}},
+			),
+		},
+		{
+			Version:     10,
+			Description: "create text index for user search",
+			Up: CreateIndexes("users",
+				mongo.IndexModel{
+					Keys: bson.D{
+						{Key: "username", Value: "text"},
+						{Key: "email", Value: "text"},
+						{Key: "first_name", Value: "text"},
+						{Key: "last_name", Value: "text"},
+					},
+					// Names and usernames are not stemmed, weights follow the ranking
+					Options: options.Index().
+						SetName("user_search").
+						SetDefaultLanguage("none").
+						SetWeights(bson.D{
+							{Key: "username", Value: 6},
+							{Key: "email", Value: 4},
+							{Key: "first_name", Value: 3},
+							{Key: "last_name", Value: 3},
+						}),
+				},
+			),
+		},
+	}
+}
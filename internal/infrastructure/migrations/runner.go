@@ -0,0 +1,263 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/kaa-dan/clean-architecture-go/pkg/logger"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	migrationsCollection = "schema_migrations"
+	lockCollection       = "schema_migrations_lock"
+	lockID               = "lock"
+
+	lockLease        = 2 * time.Minute
+	lockPollInterval = 2 * time.Second
+	lockWaitTimeout  = 10 * time.Minute
+)
+
+var (
+	ErrPendingMigrations = errors.New("database has pending migrations")
+	ErrLockTimeout       = errors.New("timed out waiting for migration lock")
+)
+
+type Status struct {
+	Version     int        `json:"version"`
+	Description string     `json:"description"`
+	Applied     bool       `json:"applied"`
+	AppliedAt   *time.Time `json:"applied_at,omitempty"`
+}
+
+type record struct {
+	Version     int       `bson:"_id"`
+	Description string    `bson:"description"`
+	AppliedAt   time.Time `bson:"applied_at"`
+	DurationMS  int64     `bson:"duration_ms"`
+}
+
+type Runner struct {
+	db         *mongo.Database
+	migrations []Migration
+	owner      string
+}
+
+func NewRunner(client *mongo.Client, dbName string, migrations []Migration) *Runner {
+	sorted := append([]Migration(nil), migrations...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Version < sorted[j].Version
+	})
+
+	hostname, _ := os.Hostname()
+
+	return &Runner{
+		db:         client.Database(dbName),
+		migrations: sorted,
+		owner:      fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), primitive.NewObjectID().Hex()),
+	}
+}
+
+func (r *Runner) Status(ctx context.Context) ([]Status, error) {
+	applied, err := r.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(r.migrations))
+	for _, migration := range r.migrations {
+		status := Status{
+			Version:     migration.Version,
+			Description: migration.Description,
+		}
+		if rec, ok := applied[migration.Version]; ok {
+			appliedAt := rec.AppliedAt
+			status.Applied = true
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+func (r *Runner) Pending(ctx context.Context) ([]Migration, error) {
+	applied, err := r.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+	for _, migration := range r.migrations {
+		if _, ok := applied[migration.Version]; !ok {
+			pending = append(pending, migration)
+		}
+	}
+	return pending, nil
+}
+
+// EnsureApplied returns ErrPendingMigrations if any migration has not run.
+func (r *Runner) EnsureApplied(ctx context.Context) error {
+	pending, err := r.Pending(ctx)
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("%w: %d migration(s), run `migrate up`", ErrPendingMigrations, len(pending))
+	}
+	return nil
+}
+
+// Up applies every pending migration in order while holding the migration
+// lock, so concurrently starting instances never run the same migration twice.
+func (r *Runner) Up(ctx context.Context) ([]Migration, error) {
+	release, err := r.acquireLock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	// Another instance may have finished while we were waiting for the lock
+	pending, err := r.Pending(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var completed []Migration
+	for _, migration := range pending {
+		logger.Infof("applying migration %d: %s", migration.Version, migration.Description)
+
+		started := time.Now()
+		if err := migration.Up(ctx, r.db); err != nil {
+			return completed, fmt.Errorf("migration %d failed: %w", migration.Version, err)
+		}
+
+		_, err := r.db.Collection(migrationsCollection).InsertOne(ctx, record{
+			Version:     migration.Version,
+			Description: migration.Description,
+			AppliedAt:   time.Now(),
+			DurationMS:  time.Since(started).Milliseconds(),
+		})
+		if err != nil {
+			return completed, fmt.Errorf("failed to record migration %d: %w", migration.Version, err)
+		}
+
+		completed = append(completed, migration)
+	}
+
+	return completed, nil
+}
+
+func (r *Runner) applied(ctx context.Context) (map[int]record, error) {
+	cursor, err := r.db.Collection(migrationsCollection).Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	applied := make(map[int]record)
+	for cursor.Next(ctx) {
+		var rec record
+		if err := cursor.Decode(&rec); err != nil {
+			return nil, err
+		}
+		applied[rec.Version] = rec
+	}
+
+	return applied, cursor.Err()
+}
+
+// acquireLock takes a leased lock document. The lease is renewed while the
+// lock is held, and an expired lease left behind by a crashed instance can be
+// taken over.
+func (r *Runner) acquireLock(ctx context.Context) (func(), error) {
+	collection := r.db.Collection(lockCollection)
+	deadline := time.Now().Add(lockWaitTimeout)
+
+	for {
+		acquired, err := r.tryLock(ctx, collection)
+		if err != nil {
+			return nil, err
+		}
+		if acquired {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			return nil, ErrLockTimeout
+		}
+
+		logger.Infof("waiting for migration lock held by another instance")
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(lockPollInterval):
+		}
+	}
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(lockLease / 3)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				_, err := collection.UpdateOne(context.Background(),
+					bson.M{"_id": lockID, "owner": r.owner},
+					bson.M{"$set": bson.M{"expires_at": time.Now().Add(lockLease)}},
+				)
+				if err != nil {
+					logger.Warnf("failed to renew migration lock: %v", err)
+				}
+			}
+		}
+	}()
+
+	release := func() {
+		close(stop)
+		<-done
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if _, err := collection.DeleteOne(ctx, bson.M{"_id": lockID, "owner": r.owner}); err != nil {
+			logger.Warnf("failed to release migration lock: %v", err)
+		}
+	}
+
+	return release, nil
+}
+
+func (r *Runner) tryLock(ctx context.Context, collection *mongo.Collection) (bool, error) {
+	now := time.Now()
+
+	// The upsert inserts a new lock when none exists and takes over an expired
+	// one. A live lock makes the insert fail with a duplicate key error.
+	_, err := collection.UpdateOne(ctx,
+		bson.M{"_id": lockID, "expires_at": bson.M{"$lt": now}},
+		bson.M{"$set": bson.M{
+			"owner":       r.owner,
+			"acquired_at": now,
+			"expires_at":  now.Add(lockLease),
+		}},
+		options.Update().SetUpsert(true),
+	)
+	if err == nil {
+		return true, nil
+	}
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	return false, err
+}
@@ -0,0 +1,21 @@
+package notification
+
+import (
+	"context"
+
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/entities"
+	"github.com/kaa-dan/clean-architecture-go/pkg/logger"
+)
+
+// LogInvitationSender logs invitations instead of emailing them. It never
+// logs the temporary password.
+type LogInvitationSender struct{}
+
+func NewLogInvitationSender() *LogInvitationSender {
+	return &LogInvitationSender{}
+}
+
+func (s *LogInvitationSender) SendInvitation(ctx context.Context, user *entities.User, temporaryPassword string) error {
+	logger.Infof("invitation for user %s sent to %s", user.ID.Hex(), user.Email)
+	return nil
+}
@@ -0,0 +1,141 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/events"
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/repositories"
+	"github.com/kaa-dan/clean-architecture-go/pkg/logger"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	relayLease       = 30 * time.Second
+	relayBaseBackoff = 5 * time.Second
+	relayMaxBackoff  = 10 * time.Minute
+)
+
+// Relay publishes events from the outbox. An event is only marked published
+// after the publisher accepted it, so a crash in between publishes it again.
+type Relay struct {
+	outbox      repositories.OutboxRepository
+	publisher   events.Publisher
+	batchSize   int
+	maxAttempts int
+	owner       string
+}
+
+func NewRelay(outbox repositories.OutboxRepository, publisher events.Publisher, batchSize, maxAttempts int) *Relay {
+	hostname, _ := os.Hostname()
+
+	return &Relay{
+		outbox:      outbox,
+		publisher:   publisher,
+		batchSize:   batchSize,
+		maxAttempts: maxAttempts,
+		owner:       fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), primitive.NewObjectID().Hex()),
+	}
+}
+
+// RunOnce publishes pending events until the outbox is drained. When an event
+// fails it is retried with backoff, and later events of the same aggregate
+// wait for it to keep them in order. After maxAttempts the event is
+// dead-lettered and no longer holds them back.
+func (r *Relay) RunOnce(ctx context.Context) error {
+	for {
+		acquired, err := r.outbox.AcquireRelayLease(ctx, r.owner, relayLease)
+		if err != nil {
+			return err
+		}
+		if !acquired {
+			return nil
+		}
+
+		pending, err := r.outbox.ListPending(ctx, r.batchSize)
+		if err != nil {
+			return err
+		}
+		if len(pending) == 0 {
+			return nil
+		}
+
+		published := 0
+		blocked := make(map[string]bool)
+		for _, event := range orderByAggregate(pending) {
+			key := event.AggregateType + "/" + event.AggregateID
+			if blocked[key] {
+				continue
+			}
+
+			if err := r.publisher.Publish(ctx, event.Event); err != nil {
+				blocked[key] = true
+				if err := r.markFailed(ctx, event, err); err != nil {
+					return err
+				}
+				continue
+			}
+
+			if err := r.outbox.MarkPublished(ctx, event.ID, time.Now()); err != nil {
+				return err
+			}
+			published++
+		}
+
+		// Failed events wait for their retry, so a full batch without any
+		// published event is followed by other events
+		if len(pending) < r.batchSize {
+			return nil
+		}
+	}
+}
+
+func (r *Relay) markFailed(ctx context.Context, event *repositories.PendingEvent, publishErr error) error {
+	attempts := event.Attempts + 1
+	if attempts >= r.maxAttempts {
+		logger.Errorf("giving up on event %s after %d attempts: %v", event.ID.Hex(), attempts, publishErr)
+		return r.outbox.MarkDeadLettered(ctx, event.ID, publishErr.Error(), time.Now())
+	}
+	logger.Warnf("failed to publish event %s: %v", event.ID.Hex(), publishErr)
+	return r.outbox.MarkFailed(ctx, event.ID, publishErr.Error(), time.Now().Add(relayBackoff(attempts)))
+}
+
+// relayBackoff doubles the delay after every failed attempt: 5s, 10s, 20s,
+// and so on, up to relayMaxBackoff.
+func relayBackoff(attempts int) time.Duration {
+	delay := relayBaseBackoff
+	for i := 1; i < attempts && delay < relayMaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > relayMaxBackoff {
+		delay = relayMaxBackoff
+	}
+	return delay
+}
+
+// orderByAggregate sorts the events of each aggregate by sequence while
+// keeping aggregates in the order of their oldest event.
+func orderByAggregate(pending []*repositories.PendingEvent) []*repositories.PendingEvent {
+	groups := make(map[string][]*repositories.PendingEvent)
+	var keys []string
+	for _, event := range pending {
+		key := event.AggregateType + "/" + event.AggregateID
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], event)
+	}
+
+	ordered := make([]*repositories.PendingEvent, 0, len(pending))
+	for _, key := range keys {
+		group := groups[key]
+		sort.SliceStable(group, func(i, j int) bool {
+			return group[i].Sequence < group[j].Sequence
+		})
+		ordered = append(ordered, group...)
+	}
+	return ordered
+}
@@ -0,0 +1,57 @@
+package publisher
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/events"
+)
+
+const httpPublishTimeout = 10 * time.Second
+
+// HTTPPublisher POSTs each event as JSON to a fixed URL. Any status outside
+// 2xx is treated as a failure and the event is retried. Consumers should
+// deduplicate on the Event-ID header, because delivery is at least once.
+type HTTPPublisher struct {
+	url    string
+	client *http.Client
+}
+
+func NewHTTPPublisher(url string) *HTTPPublisher {
+	return &HTTPPublisher{
+		url:    url,
+		client: &http.Client{Timeout: httpPublishTimeout},
+	}
+}
+
+func (p *HTTPPublisher) Publish(ctx context.Context, event *events.Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Event-ID", event.ID.Hex())
+	req.Header.Set("Event-Type", string(event.Type))
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("event consumer responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
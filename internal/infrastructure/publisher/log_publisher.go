@@ -0,0 +1,22 @@
+package publisher
+
+import (
+	"context"
+
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/events"
+	"github.com/kaa-dan/clean-architecture-go/pkg/logger"
+)
+
+// LogPublisher only logs events. It is the default until a real consumer is
+// configured.
+type LogPublisher struct{}
+
+func NewLogPublisher() *LogPublisher {
+	return &LogPublisher{}
+}
+
+func (p *LogPublisher) Publish(ctx context.Context, event *events.Event) error {
+	logger.Infof("published event %s %s for %s %s (sequence %d)",
+		event.ID.Hex(), event.Type, event.AggregateType, event.AggregateID, event.Sequence)
+	return nil
+}
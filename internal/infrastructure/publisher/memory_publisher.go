@@ -0,0 +1,51 @@
+package publisher
+
+import (
+	"context"
+	"sync"
+
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/events"
+)
+
+// MemoryPublisher keeps the most recent events in memory and passes every
+// event to in-process subscribers.
+type MemoryPublisher struct {
+	mu          sync.Mutex
+	capacity    int
+	events      []*events.Event
+	subscribers []func(event *events.Event)
+}
+
+func NewMemoryPublisher(capacity int) *MemoryPublisher {
+	return &MemoryPublisher{
+		capacity: capacity,
+	}
+}
+
+func (p *MemoryPublisher) Publish(ctx context.Context, event *events.Event) error {
+	p.mu.Lock()
+	p.events = append(p.events, event)
+	if len(p.events) > p.capacity {
+		p.events = p.events[len(p.events)-p.capacity:]
+	}
+	subscribers := append([]func(*events.Event){}, p.subscribers...)
+	p.mu.Unlock()
+
+	for _, fn := range subscribers {
+		fn(event)
+	}
+	return nil
+}
+
+func (p *MemoryPublisher) Subscribe(fn func(event *events.Event)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.subscribers = append(p.subscribers, fn)
+}
+
+// Events returns the retained events, oldest first.
+func (p *MemoryPublisher) Events() []*events.Event {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]*events.Event(nil), p.events...)
+}
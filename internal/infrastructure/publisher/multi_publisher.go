@@ -0,0 +1,31 @@
+package publisher
+
+import (
+	"context"
+	"errors"
+
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/events"
+)
+
+// MultiPublisher publishes every event to all of its publishers. When one
+// of them fails the event is retried for all, which at-least-once delivery
+// already allows.
+type MultiPublisher struct {
+	publishers []events.Publisher
+}
+
+func NewMultiPublisher(publishers ...events.Publisher) *MultiPublisher {
+	return &MultiPublisher{
+		publishers: publishers,
+	}
+}
+
+func (p *MultiPublisher) Publish(ctx context.Context, event *events.Event) error {
+	var errs []error
+	for _, publisher := range p.publishers {
+		if err := publisher.Publish(ctx, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,96 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/entities"
+	"github.com/kaa-dan/clean-architecture-go/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type AttributeSchemaRepository struct {
+	collection *mongo.Collection
+}
+
+func NewAttributeSchemaRepository(client *mongo.Client, dbName string) *AttributeSchemaRepository {
+	return &AttributeSchemaRepository{
+		collection: client.Database(dbName).Collection("attribute_schemas"),
+	}
+}
+
+func (r *AttributeSchemaRepository) Create(ctx context.Context, schema *entities.AttributeSchema) error {
+	schema.CreatedAt = time.Now()
+	schema.UpdatedAt = time.Now()
+
+	result, err := r.collection.InsertOne(ctx, schema)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return errors.ErrAttributeAlreadyExists
+		}
+		return err
+	}
+
+	schema.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (r *AttributeSchemaRepository) GetByName(ctx context.Context, name string) (*entities.AttributeSchema, error) {
+	var schema entities.AttributeSchema
+	err := r.collection.FindOne(ctx, bson.M{"name": name}).Decode(&schema)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.ErrAttributeNotFound
+		}
+		return nil, err
+	}
+	return &schema, nil
+}
+
+func (r *AttributeSchemaRepository) GetAll(ctx context.Context) ([]*entities.AttributeSchema, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "name", Value: 1}})
+
+	cursor, err := r.collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var schemas []*entities.AttributeSchema
+	for cursor.Next(ctx) {
+		var schema entities.AttributeSchema
+		if err := cursor.Decode(&schema); err != nil {
+			return nil, err
+		}
+		schemas = append(schemas, &schema)
+	}
+
+	return schemas, cursor.Err()
+}
+
+func (r *AttributeSchemaRepository) Update(ctx context.Context, schema *entities.AttributeSchema) error {
+	schema.UpdatedAt = time.Now()
+
+	result, err := r.collection.ReplaceOne(ctx, bson.M{"_id": schema.ID}, schema)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errors.ErrAttributeNotFound
+	}
+	return nil
+}
+
+func (r *AttributeSchemaRepository) Delete(ctx context.Context, name string) error {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"name": name})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return errors.ErrAttributeNotFound
+	}
+	return nil
+}
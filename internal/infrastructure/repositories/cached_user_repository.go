@@ -0,0 +1,141 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/entities"
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/repositories"
+	infracache "github.com/kaa-dan/clean-architecture-go/internal/infrastructure/cache"
+	"github.com/kaa-dan/clean-architecture-go/pkg/cache"
+	"github.com/kaa-dan/clean-architecture-go/pkg/logger"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// CachedUserRepository is a read-through cache for GetByID and GetByIDs in
+// front of another UserRepository. Writes invalidate the cached user locally
+// and on every other instance through the Invalidator. Inside a transaction
+// the invalidation waits for the commit, as a concurrent read could otherwise
+// cache the previous version again before the write becomes visible.
+type CachedUserRepository struct {
+	repositories.UserRepository
+	backend     cache.Backend
+	invalidator infracache.Invalidator
+	txManager   repositories.TxManager
+}
+
+func NewCachedUserRepository(
+	inner repositories.UserRepository,
+	backend cache.Backend,
+	invalidator infracache.Invalidator,
+	txManager repositories.TxManager,
+) *CachedUserRepository {
+	invalidator.Subscribe(backend.Delete)
+
+	return &CachedUserRepository{
+		UserRepository: inner,
+		backend:        backend,
+		invalidator:    invalidator,
+		txManager:      txManager,
+	}
+}
+
+func (r *CachedUserRepository) GetByID(ctx context.Context, id primitive.ObjectID) (*entities.User, error) {
+	// Reads inside a transaction must see its own writes and must not
+	// populate the cache with data that may still be rolled back
+	if mongo.SessionFromContext(ctx) != nil {
+		return r.UserRepository.GetByID(ctx, id)
+	}
+
+	key := userCacheKey(id)
+	if cached, ok := r.backend.Get(key); ok {
+		return cached.(*entities.User).Clone(), nil
+	}
+
+	user, err := r.UserRepository.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	r.backend.Set(key, user.Clone())
+	return user, nil
+}
+
+// GetByIDs serves cached users from the cache and loads the rest in one
+// query, caching them for later reads
+func (r *CachedUserRepository) GetByIDs(ctx context.Context, ids []primitive.ObjectID) ([]*entities.User, error) {
+	if mongo.SessionFromContext(ctx) != nil {
+		return r.UserRepository.GetByIDs(ctx, ids)
+	}
+
+	var (
+		users   []*entities.User
+		missing []primitive.ObjectID
+	)
+	for _, id := range ids {
+		if cached, ok := r.backend.Get(userCacheKey(id)); ok {
+			users = append(users, cached.(*entities.User).Clone())
+			continue
+		}
+		missing = append(missing, id)
+	}
+	if len(missing) == 0 {
+		return users, nil
+	}
+
+	loaded, err := r.UserRepository.GetByIDs(ctx, missing)
+	if err != nil {
+		return nil, err
+	}
+	for _, user := range loaded {
+		r.backend.Set(userCacheKey(user.ID), user.Clone())
+	}
+
+	return append(users, loaded...), nil
+}
+
+func (r *CachedUserRepository) Update(ctx context.Context, id primitive.ObjectID, user *entities.User) error {
+	defer r.invalidateAfterCommit(ctx, id)
+	return r.UserRepository.Update(ctx, id, user)
+}
+
+func (r *CachedUserRepository) Patch(ctx context.Context, id primitive.ObjectID, expectedVersion int64, patch *entities.UserPatch) (*entities.User, error) {
+	defer r.invalidateAfterCommit(ctx, id)
+	return r.UserRepository.Patch(ctx, id, expectedVersion, patch)
+}
+
+func (r *CachedUserRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	defer r.invalidateAfterCommit(ctx, id)
+	return r.UserRepository.Delete(ctx, id)
+}
+
+func (r *CachedUserRepository) CacheStats() cache.Stats {
+	return r.backend.Stats()
+}
+
+// invalidateAfterCommit invalidates the user once the transaction of ctx has
+// committed, or right away outside a transaction. An aborted transaction
+// changed nothing to invalidate.
+func (r *CachedUserRepository) invalidateAfterCommit(ctx context.Context, id primitive.ObjectID) {
+	r.txManager.AfterCommit(ctx, func(context.Context) {
+		r.invalidate(id)
+	})
+}
+
+func (r *CachedUserRepository) invalidate(id primitive.ObjectID) {
+	key := userCacheKey(id)
+	r.backend.Delete(key)
+
+	// Publish outside of any transaction so the invalidation is not rolled back
+	publishCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := r.invalidator.Publish(publishCtx, key); err != nil {
+		logger.Warnf("failed to publish cache invalidation for %s: %v", key, err)
+	}
+}
+
+func userCacheKey(id primitive.ObjectID) string {
+	return "user:" + id.Hex()
+}
@@ -0,0 +1,123 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/entities"
+	"github.com/kaa-dan/clean-architecture-go/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type ErasureRepository struct {
+	requests     *mongo.Collection
+	certificates *mongo.Collection
+}
+
+func NewErasureRepository(client *mongo.Client, dbName string) *ErasureRepository {
+	db := client.Database(dbName)
+	return &ErasureRepository{
+		requests:     db.Collection("erasure_requests"),
+		certificates: db.Collection("erasure_certificates"),
+	}
+}
+
+func (r *ErasureRepository) Create(ctx context.Context, request *entities.ErasureRequest) error {
+	result, err := r.requests.InsertOne(ctx, request)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return errors.ErrErasureAlreadyRequested
+		}
+		return err
+	}
+
+	request.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (r *ErasureRepository) GetByUserID(ctx context.Context, userID primitive.ObjectID) (*entities.ErasureRequest, error) {
+	var request entities.ErasureRequest
+	err := r.requests.FindOne(ctx, bson.M{"user_id": userID}).Decode(&request)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.ErrErasureNotFound
+		}
+		return nil, err
+	}
+	return &request, nil
+}
+
+func (r *ErasureRepository) Update(ctx context.Context, request *entities.ErasureRequest) error {
+	result, err := r.requests.ReplaceOne(ctx, bson.M{"_id": request.ID}, request)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errors.ErrErasureNotFound
+	}
+	return nil
+}
+
+func (r *ErasureRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	result, err := r.requests.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return errors.ErrErasureNotFound
+	}
+	return nil
+}
+
+func (r *ErasureRepository) ClaimDue(ctx context.Context, now, staleBefore time.Time) (*entities.ErasureRequest, error) {
+	filter := bson.M{"$or": []bson.M{
+		{"status": entities.ErasureScheduled, "scheduled_for": bson.M{"$lte": now}},
+		{"status": entities.ErasureProcessing, "claimed_at": bson.M{"$lt": staleBefore}},
+	}}
+	update := bson.M{"$set": bson.M{"status": entities.ErasureProcessing, "claimed_at": now}}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "scheduled_for", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	var request entities.ErasureRequest
+	err := r.requests.FindOneAndUpdate(ctx, filter, update, opts).Decode(&request)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &request, nil
+}
+
+func (r *ErasureRepository) CreateCertificate(ctx context.Context, certificate *entities.ErasureCertificate) error {
+	_, err := r.certificates.InsertOne(ctx, certificate)
+	return err
+}
+
+func (r *ErasureRepository) ListCertificates(ctx context.Context, limit, offset int) ([]*entities.ErasureCertificate, error) {
+	opts := options.Find().
+		SetLimit(int64(limit)).
+		SetSkip(int64(offset)).
+		SetSort(bson.D{{Key: "completed_on", Value: -1}, {Key: "_id", Value: 1}})
+
+	cursor, err := r.certificates.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var certificates []*entities.ErasureCertificate
+	for cursor.Next(ctx) {
+		var certificate entities.ErasureCertificate
+		if err := cursor.Decode(&certificate); err != nil {
+			return nil, err
+		}
+		certificates = append(certificates, &certificate)
+	}
+
+	return certificates, cursor.Err()
+}
@@ -0,0 +1,130 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/entities"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// IdempotencyRepository keeps records until expires_at, where a TTL index
+// removes them
+type IdempotencyRepository struct {
+	collection *mongo.Collection
+}
+
+func NewIdempotencyRepository(client *mongo.Client, dbName string) *IdempotencyRepository {
+	return &IdempotencyRepository{
+		collection: client.Database(dbName).Collection("idempotency_keys"),
+	}
+}
+
+func (r *IdempotencyRepository) Reserve(ctx context.Context, record *entities.IdempotencyRecord) (*entities.IdempotencyRecord, bool, error) {
+	for attempt := 0; ; attempt++ {
+		now := time.Now()
+
+		// Creates the record or takes over one that may be reused. A record
+		// held by another request makes the upsert fail with a duplicate key
+		// error. The TTL monitor runs once a minute, so expired records may
+		// still be there.
+		_, err := r.collection.UpdateOne(ctx,
+			bson.M{"_id": record.Key, "$or": []bson.M{
+				{"expires_at": bson.M{"$lt": now}},
+				{"response": nil, "locked_until": bson.M{"$lt": now}, "fingerprint": record.Fingerprint},
+			}},
+			bson.M{
+				"$set": bson.M{
+					"user_id":      record.UserID,
+					"fingerprint":  record.Fingerprint,
+					"lock_token":   record.LockToken,
+					"locked_until": record.LockedUntil,
+					"created_at":   record.CreatedAt,
+					"expires_at":   record.ExpiresAt,
+				},
+				"$unset": bson.M{"response": ""},
+			},
+			options.Update().SetUpsert(true),
+		)
+		if err == nil {
+			return nil, true, nil
+		}
+		if !mongo.IsDuplicateKeyError(err) {
+			return nil, false, err
+		}
+
+		var existing entities.IdempotencyRecord
+		err = r.collection.FindOne(ctx, bson.M{"_id": record.Key}).Decode(&existing)
+		// The holder may have released the key in the meantime
+		if err == mongo.ErrNoDocuments && attempt < 2 {
+			continue
+		}
+		if err != nil {
+			return nil, false, err
+		}
+		return &existing, false, nil
+	}
+}
+
+func (r *IdempotencyRepository) Complete(ctx context.Context, key, lockToken string, response *entities.IdempotentResponse) (bool, error) {
+	result, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": key, "lock_token": lockToken, "response": nil},
+		bson.M{"$set": bson.M{"response": response}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
+
+func (r *IdempotencyRepository) Release(ctx context.Context, key, lockToken string) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": key, "lock_token": lockToken, "response": nil})
+	return err
+}
+
+func (r *IdempotencyRepository) Name() string {
+	return "idempotency_keys"
+}
+
+// ExportPersonalData lists when the user sent idempotency keys. Keys and
+// responses are only stored hashed and encrypted, there is nothing more to
+// read.
+func (r *IdempotencyRepository) ExportPersonalData(ctx context.Context, userID primitive.ObjectID) (interface{}, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	cursor, err := r.collection.Find(ctx, bson.M{"user_id": userID.Hex()}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	type exportedKey struct {
+		CreatedAt time.Time `json:"created_at"`
+		ExpiresAt time.Time `json:"expires_at"`
+		Status    int       `json:"status,omitempty"`
+	}
+	exported := []exportedKey{}
+	for cursor.Next(ctx) {
+		var record entities.IdempotencyRecord
+		if err := cursor.Decode(&record); err != nil {
+			return nil, err
+		}
+		key := exportedKey{CreatedAt: record.CreatedAt, ExpiresAt: record.ExpiresAt}
+		if record.Response != nil {
+			key.Status = record.Response.Status
+		}
+		exported = append(exported, key)
+	}
+
+	return exported, cursor.Err()
+}
+
+func (r *IdempotencyRepository) ErasePersonalData(ctx context.Context, userID primitive.ObjectID) (entities.ErasureStep, error) {
+	result, err := r.collection.DeleteMany(ctx, bson.M{"user_id": userID.Hex()})
+	if err != nil {
+		return entities.ErasureStep{}, err
+	}
+	return entities.ErasureStep{Name: r.Name(), Action: "deleted", Count: result.DeletedCount}, nil
+}
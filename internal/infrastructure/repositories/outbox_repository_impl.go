@@ -0,0 +1,216 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/entities"
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/events"
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/repositories"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const outboxRelayLockID = "relay"
+
+type outboxRecord struct {
+	events.Event   `bson:",inline"`
+	PublishedAt    *time.Time `bson:"published_at"`
+	Attempts       int        `bson:"attempts"`
+	LastError      string     `bson:"last_error,omitempty"`
+	NextAttemptAt  *time.Time `bson:"next_attempt_at,omitempty"`
+	DeadLetteredAt *time.Time `bson:"dead_lettered_at,omitempty"`
+}
+
+type OutboxRepository struct {
+	collection *mongo.Collection
+	locks      *mongo.Collection
+}
+
+// NewOutboxRepository expects the indexes created by the schema migrations.
+// Published events expire through a TTL index on published_at.
+func NewOutboxRepository(client *mongo.Client, dbName string) *OutboxRepository {
+	db := client.Database(dbName)
+	return &OutboxRepository{
+		collection: db.Collection("outbox"),
+		locks:      db.Collection("outbox_relay_lock"),
+	}
+}
+
+func (r *OutboxRepository) Add(ctx context.Context, event *events.Event) error {
+	if event.ID.IsZero() {
+		event.ID = primitive.NewObjectID()
+	}
+	_, err := r.collection.InsertOne(ctx, outboxRecord{Event: *event})
+	return err
+}
+
+func (r *OutboxRepository) ListPending(ctx context.Context, limit int) ([]*repositories.PendingEvent, error) {
+	now := time.Now()
+	filter := bson.M{
+		"published_at":     nil,
+		"dead_lettered_at": nil,
+		"next_attempt_at":  bson.M{"$not": bson.M{"$gt": now}},
+	}
+
+	// Later events of an aggregate wait for the retry of an earlier one
+	waiting, err := r.waitingForRetry(ctx, now)
+	if err != nil {
+		return nil, err
+	}
+	if len(waiting) > 0 {
+		filter["$nor"] = waiting
+	}
+
+	opts := options.Find().
+		SetLimit(int64(limit)).
+		SetSort(bson.D{{Key: "occurred_at", Value: 1}, {Key: "_id", Value: 1}})
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var pending []*repositories.PendingEvent
+	for cursor.Next(ctx) {
+		var record outboxRecord
+		if err := cursor.Decode(&record); err != nil {
+			return nil, err
+		}
+		event := record.Event
+		pending = append(pending, &repositories.PendingEvent{Event: &event, Attempts: record.Attempts})
+	}
+
+	return pending, cursor.Err()
+}
+
+// waitingForRetry matches the events of every aggregate from its earliest
+// event that waits to be retried on
+func (r *OutboxRepository) waitingForRetry(ctx context.Context, now time.Time) (bson.A, error) {
+	opts := options.Find().SetProjection(bson.M{"aggregate_type": 1, "aggregate_id": 1, "sequence": 1})
+	cursor, err := r.collection.Find(ctx, bson.M{
+		"published_at":     nil,
+		"dead_lettered_at": nil,
+		"next_attempt_at":  bson.M{"$gt": now},
+	}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	earliest := make(map[[2]string]int64)
+	for cursor.Next(ctx) {
+		var record outboxRecord
+		if err := cursor.Decode(&record); err != nil {
+			return nil, err
+		}
+		key := [2]string{record.AggregateType, record.AggregateID}
+		if sequence, ok := earliest[key]; !ok || record.Sequence < sequence {
+			earliest[key] = record.Sequence
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	waiting := make(bson.A, 0, len(earliest))
+	for key, sequence := range earliest {
+		waiting = append(waiting, bson.M{
+			"aggregate_type": key[0],
+			"aggregate_id":   key[1],
+			"sequence":       bson.M{"$gte": sequence},
+		})
+	}
+	return waiting, nil
+}
+
+func (r *OutboxRepository) MarkPublished(ctx context.Context, id primitive.ObjectID, at time.Time) error {
+	_, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": id},
+		bson.M{"$set": bson.M{"published_at": at}, "$unset": bson.M{"last_error": ""}},
+	)
+	return err
+}
+
+func (r *OutboxRepository) MarkFailed(ctx context.Context, id primitive.ObjectID, reason string, retryAt time.Time) error {
+	_, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": id},
+		bson.M{"$set": bson.M{"last_error": reason, "next_attempt_at": retryAt}, "$inc": bson.M{"attempts": 1}},
+	)
+	return err
+}
+
+func (r *OutboxRepository) MarkDeadLettered(ctx context.Context, id primitive.ObjectID, reason string, at time.Time) error {
+	_, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": id},
+		bson.M{
+			"$set":   bson.M{"last_error": reason, "dead_lettered_at": at},
+			"$unset": bson.M{"next_attempt_at": ""},
+			"$inc":   bson.M{"attempts": 1},
+		},
+	)
+	return err
+}
+
+func (r *OutboxRepository) AcquireRelayLease(ctx context.Context, owner string, lease time.Duration) (bool, error) {
+	now := time.Now()
+
+	// Renews our own lease or takes over an expired one. A live lease held by
+	// another relay makes the upsert fail with a duplicate key error.
+	_, err := r.locks.UpdateOne(ctx,
+		bson.M{"_id": outboxRelayLockID, "$or": []bson.M{
+			{"owner": owner},
+			{"expires_at": bson.M{"$lt": now}},
+		}},
+		bson.M{"$set": bson.M{"owner": owner, "expires_at": now.Add(lease)}},
+		options.Update().SetUpsert(true),
+	)
+	if err == nil {
+		return true, nil
+	}
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	return false, err
+}
+
+func (r *OutboxRepository) Name() string {
+	return "outbox"
+}
+
+func (r *OutboxRepository) ExportPersonalData(ctx context.Context, userID primitive.ObjectID) (interface{}, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "sequence", Value: 1}})
+	cursor, err := r.collection.Find(ctx, r.userEventsQuery(userID), opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	exported := []events.Event{}
+	for cursor.Next(ctx) {
+		var record outboxRecord
+		if err := cursor.Decode(&record); err != nil {
+			return nil, err
+		}
+		exported = append(exported, record.Event)
+	}
+
+	return exported, cursor.Err()
+}
+
+// ErasePersonalData drops every event about the user, including unpublished
+// ones. The erasure adds a UserDeleted event afterwards.
+func (r *OutboxRepository) ErasePersonalData(ctx context.Context, userID primitive.ObjectID) (entities.ErasureStep, error) {
+	result, err := r.collection.DeleteMany(ctx, r.userEventsQuery(userID))
+	if err != nil {
+		return entities.ErasureStep{}, err
+	}
+	return entities.ErasureStep{Name: r.Name(), Action: "deleted", Count: result.DeletedCount}, nil
+}
+
+func (r *OutboxRepository) userEventsQuery(userID primitive.ObjectID) bson.M {
+	return bson.M{"aggregate_type": events.AggregateUser, "aggregate_id": userID.Hex()}
+}
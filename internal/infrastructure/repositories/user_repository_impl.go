@@ -2,59 +2,47 @@ package repositories
 
 import (
 	"context"
-
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/kaa-dan/clean-architecture-go/internal/domain/entities"
 	"github.com/kaa-dan/clean-architecture-go/pkg/errors"
+	"github.com/kaa-dan/clean-architecture-go/pkg/identity"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// canonicalCollation must match the collation of the canonical unique
+// indexes, or lookups cannot use them.
+var canonicalCollation = &options.Collation{Locale: "en", Strength: 2}
+
 type UserRepository struct {
-	collection *mongo.Collection
+	collection    *mongo.Collection
+	canonicalizer *identity.Canonicalizer
 }
 
-func NewUserRepository(client *mongo.Client, dbName string) *UserRepository {
-	collection := client.Database(dbName).Collection("users")
-
-	//Create indexes
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-
-	defer cancel()
-
-	//Email index (unique)
-
-	emailIndex := mongo.IndexModel{
-		Keys:    bson.D{{Key: "email", Value: 1}},
-		Options: options.Index().SetUnique(true),
-	}
-
-	//Username index (unique)
-	usernameIndex := mongo.IndexModel{
-		Keys:    bson.D{{Key: "username", Value: 1}},
-		Options: options.Index().SetUnique(true),
-	}
-
-	collection.Indexes().CreateMany(ctx, []mongo.IndexModel{emailIndex, usernameIndex})
-
+// NewUserRepository expects the indexes created by the schema migrations in
+// internal/infrastructure/migrations. Emails and usernames are stored as
+// typed but compared through their canonical keys.
+func NewUserRepository(client *mongo.Client, dbName string, canonicalizer *identity.Canonicalizer) *UserRepository {
 	return &UserRepository{
-		collection: collection,
+		collection:    client.Database(dbName).Collection("users"),
+		canonicalizer: canonicalizer,
 	}
 }
 
 func (r *UserRepository) Create(ctx context.Context, user *entities.User) error {
+	r.canonicalize(user)
 	user.CreatedAt = time.Now()
 	user.UpdatedAt = time.Now()
+	user.Version = 1
 
 	result, err := r.collection.InsertOne(ctx, user)
 	if err != nil {
-		if mongo.IsDuplicateKeyError(err) {
-			return errors.ErrUserNotFound
-		}
-		return err
+		return duplicateUserError(err)
 	}
 
 	user.ID = result.InsertedID.(primitive.ObjectID)
@@ -62,6 +50,18 @@ func (r *UserRepository) Create(ctx context.Context, user *entities.User) error
 
 }
 
+// duplicateUserError tells which unique key a write collided with, an email
+// or a username taken by another user
+func duplicateUserError(err error) error {
+	if !mongo.IsDuplicateKeyError(err) {
+		return err
+	}
+	if strings.Contains(err.Error(), "username") {
+		return errors.ErrUsernameAlreadyExists
+	}
+	return errors.ErrUserAlreadyExists
+}
+
 func (r *UserRepository) GetByID(ctx context.Context, id primitive.ObjectID) (*entities.User, error) {
 	var user entities.User
 	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&user)
@@ -74,9 +74,35 @@ func (r *UserRepository) GetByID(ctx context.Context, id primitive.ObjectID) (*e
 	return &user, nil
 }
 
+func (r *UserRepository) GetByIDs(ctx context.Context, ids []primitive.ObjectID) ([]*entities.User, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	cursor, err := r.collection.Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var users []*entities.User
+	for cursor.Next(ctx) {
+		var user entities.User
+		if err := cursor.Decode(&user); err != nil {
+			return nil, err
+		}
+		users = append(users, &user)
+	}
+
+	return users, cursor.Err()
+}
+
 func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*entities.User, error) {
 	var user entities.User
-	err := r.collection.FindOne(ctx, bson.M{"email": email}).Decode(&user)
+	err := r.collection.FindOne(ctx,
+		bson.M{"email_canonical": r.canonicalizer.Email(email)},
+		options.FindOne().SetCollation(canonicalCollation),
+	).Decode(&user)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return nil, errors.ErrUserNotFound
@@ -88,7 +114,10 @@ func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*entitie
 
 func (r *UserRepository) GetByUsername(ctx context.Context, username string) (*entities.User, error) {
 	var user entities.User
-	err := r.collection.FindOne(ctx, bson.M{"username": username}).Decode(&user)
+	err := r.collection.FindOne(ctx,
+		bson.M{"username_canonical": r.canonicalizer.Username(username)},
+		options.FindOne().SetCollation(canonicalCollation),
+	).Decode(&user)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return nil, errors.ErrUserNotFound
@@ -98,13 +127,13 @@ func (r *UserRepository) GetByUsername(ctx context.Context, username string) (*e
 	return &user, nil
 }
 
-func (r *UserRepository) GetAll(ctx context.Context, limit, offset int) ([]*entities.User, error) {
+func (r *UserRepository) GetAll(ctx context.Context, filter entities.UserFilter, limit, offset int) ([]*entities.User, error) {
 	opts := options.Find()
 	opts.SetLimit(int64(limit))
 	opts.SetSkip(int64(offset))
 	opts.SetSort(bson.D{{Key: "created_at", Value: -1}})
 
-	cursor, err := r.collection.Find(ctx, bson.M{}, opts)
+	cursor, err := r.collection.Find(ctx, userFilterQuery(filter), opts)
 	if err != nil {
 		return nil, err
 	}
@@ -122,22 +151,122 @@ func (r *UserRepository) GetAll(ctx context.Context, limit, offset int) ([]*enti
 	return users, cursor.Err()
 }
 
+// Stream calls fn for every matching user while iterating the cursor, so
+// memory use does not grow with the size of the result.
+func (r *UserRepository) Stream(ctx context.Context, filter entities.UserFilter, fn func(user *entities.User) error) error {
+	opts := options.Find()
+	opts.SetBatchSize(500)
+	opts.SetSort(bson.D{{Key: "created_at", Value: -1}})
+
+	cursor, err := r.collection.Find(ctx, userFilterQuery(filter), opts)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var user entities.User
+		if err := cursor.Decode(&user); err != nil {
+			return err
+		}
+		if err := fn(&user); err != nil {
+			return err
+		}
+	}
+
+	return cursor.Err()
+}
+
+// Update writes the user only if the stored version still matches
+// user.Version, and increments the version on success.
 func (r *UserRepository) Update(ctx context.Context, id primitive.ObjectID, user *entities.User) error {
+	expectedVersion := user.Version
+
+	filter := bson.M{"_id": id, "version": expectedVersion}
+	if expectedVersion == 0 {
+		// Documents created before versioning have no version field
+		filter["version"] = bson.M{"$in": bson.A{0, nil}}
+	}
+
+	r.canonicalize(user)
 	user.UpdatedAt = time.Now()
+	user.Version = expectedVersion + 1
 
 	update := bson.M{"$set": user}
-	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	result, err := r.collection.UpdateOne(ctx, filter, update)
 	if err != nil {
-		return err
+		user.Version = expectedVersion
+		return duplicateUserError(err)
 	}
 
 	if result.MatchedCount == 0 {
-		return errors.ErrUserNotFound
+		user.Version = expectedVersion
+
+		count, err := r.collection.CountDocuments(ctx, bson.M{"_id": id})
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return errors.ErrUserNotFound
+		}
+		return errors.ErrVersionConflict
 	}
 
 	return nil
 }
 
+// Patch applies a field-level change set if the stored version still matches
+// expectedVersion and returns the updated user.
+func (r *UserRepository) Patch(ctx context.Context, id primitive.ObjectID, expectedVersion int64, patch *entities.UserPatch) (*entities.User, error) {
+	filter := bson.M{"_id": id, "version": expectedVersion}
+	if expectedVersion == 0 {
+		filter["version"] = bson.M{"$in": bson.A{0, nil}}
+	}
+
+	set := bson.M{}
+	for field, value := range patch.Set {
+		set[field] = value
+	}
+	if username, ok := set["username"].(string); ok {
+		set["username"] = identity.Normalize(username)
+		set["username_canonical"] = r.canonicalizer.Username(username)
+	}
+	set["updated_at"] = time.Now()
+	set["version"] = expectedVersion + 1
+
+	update := bson.M{"$set": set}
+	if len(patch.Unset) > 0 {
+		unset := bson.M{}
+		for _, field := range patch.Unset {
+			unset[field] = ""
+		}
+		update["$unset"] = unset
+	}
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var user entities.User
+	err := r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&user)
+	if err == nil {
+		return &user, nil
+	}
+	if err != mongo.ErrNoDocuments {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, errors.ErrUsernameAlreadyExists
+		}
+		return nil, err
+	}
+
+	count, err := r.collection.CountDocuments(ctx, bson.M{"_id": id})
+	if err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return nil, errors.ErrUserNotFound
+	}
+	return nil, errors.ErrVersionConflict
+}
+
 func (r *UserRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
 	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
 	if err != nil {
@@ -154,3 +283,51 @@ func (r *UserRepository) Delete(ctx context.Context, id primitive.ObjectID) erro
 func (r *UserRepository) Count(ctx context.Context) (int64, error) {
 	return r.collection.CountDocuments(ctx, bson.M{})
 }
+
+// canonicalize normalizes the stored values and derives their comparison keys.
+func (r *UserRepository) canonicalize(user *entities.User) {
+	user.Email = identity.Normalize(user.Email)
+	user.Username = identity.Normalize(user.Username)
+	user.EmailCanonical = r.canonicalizer.Email(user.Email)
+	user.UsernameCanonical = r.canonicalizer.Username(user.Username)
+}
+
+func userFilterQuery(filter entities.UserFilter) bson.M {
+	query := bson.M{}
+	if filter.Role != "" {
+		query["role"] = filter.Role
+	}
+	if filter.IsActive != nil {
+		query["is_active"] = *filter.IsActive
+	}
+
+	createdAt := bson.M{}
+	if filter.CreatedAfter != nil {
+		createdAt["$gte"] = *filter.CreatedAfter
+	}
+	if filter.CreatedBefore != nil {
+		createdAt["$lt"] = *filter.CreatedBefore
+	}
+	if len(createdAt) > 0 {
+		query["created_at"] = createdAt
+	}
+
+	for name, value := range filter.Attributes {
+		query["attributes."+name] = bson.M{"$in": attributeCandidates(value)}
+	}
+
+	return query
+}
+
+// attributeCandidates lists the stored values a filter string can stand for,
+// since query strings carry no type.
+func attributeCandidates(value string) bson.A {
+	candidates := bson.A{value}
+	if number, err := strconv.ParseFloat(value, 64); err == nil {
+		candidates = append(candidates, number)
+	}
+	if value == "true" || value == "false" {
+		candidates = append(candidates, value == "true")
+	}
+	return candidates
+}
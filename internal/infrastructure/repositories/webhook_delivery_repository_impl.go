@@ -0,0 +1,150 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/entities"
+	"github.com/kaa-dan/clean-architecture-go/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type WebhookDeliveryRepository struct {
+	collection *mongo.Collection
+}
+
+// NewWebhookDeliveryRepository expects the unique webhook_id and event_id
+// index created by the schema migrations.
+func NewWebhookDeliveryRepository(client *mongo.Client, dbName string) *WebhookDeliveryRepository {
+	return &WebhookDeliveryRepository{
+		collection: client.Database(dbName).Collection("webhook_deliveries"),
+	}
+}
+
+func (r *WebhookDeliveryRepository) Create(ctx context.Context, delivery *entities.WebhookDelivery) error {
+	delivery.CreatedAt = time.Now()
+
+	result, err := r.collection.InsertOne(ctx, delivery)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil
+		}
+		return err
+	}
+
+	delivery.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (r *WebhookDeliveryRepository) GetByID(ctx context.Context, id primitive.ObjectID) (*entities.WebhookDelivery, error) {
+	var delivery entities.WebhookDelivery
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&delivery)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.ErrDeliveryNotFound
+		}
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+func (r *WebhookDeliveryRepository) ListByWebhook(ctx context.Context, webhookID primitive.ObjectID, status entities.WebhookDeliveryStatus, limit, offset int) ([]*entities.WebhookDelivery, error) {
+	filter := bson.M{"webhook_id": webhookID}
+	if status != "" {
+		filter["status"] = status
+	}
+
+	opts := options.Find()
+	opts.SetLimit(int64(limit))
+	opts.SetSkip(int64(offset))
+	opts.SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}})
+
+	return r.find(ctx, filter, opts)
+}
+
+func (r *WebhookDeliveryRepository) ClaimDue(ctx context.Context, now, lockUntil time.Time) (*entities.WebhookDelivery, error) {
+	filter := bson.M{
+		"status":          entities.WebhookDeliveryPending,
+		"next_attempt_at": bson.M{"$lte": now},
+		"$or": []bson.M{
+			{"locked_until": nil},
+			{"locked_until": bson.M{"$lt": now}},
+		},
+	}
+	update := bson.M{"$set": bson.M{"locked_until": lockUntil}}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "next_attempt_at", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	var delivery entities.WebhookDelivery
+	err := r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&delivery)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+func (r *WebhookDeliveryRepository) Update(ctx context.Context, delivery *entities.WebhookDelivery) error {
+	result, err := r.collection.ReplaceOne(ctx, bson.M{"_id": delivery.ID}, delivery)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errors.ErrDeliveryNotFound
+	}
+	return nil
+}
+
+func (r *WebhookDeliveryRepository) DeleteByWebhook(ctx context.Context, webhookID primitive.ObjectID) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"webhook_id": webhookID})
+	return err
+}
+
+func (r *WebhookDeliveryRepository) Name() string {
+	return "webhook_deliveries"
+}
+
+func (r *WebhookDeliveryRepository) ExportPersonalData(ctx context.Context, userID primitive.ObjectID) (interface{}, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	deliveries, err := r.find(ctx, bson.M{"aggregate_id": userID.Hex()}, opts)
+	if err != nil {
+		return nil, err
+	}
+	if deliveries == nil {
+		deliveries = []*entities.WebhookDelivery{}
+	}
+	return deliveries, nil
+}
+
+func (r *WebhookDeliveryRepository) ErasePersonalData(ctx context.Context, userID primitive.ObjectID) (entities.ErasureStep, error) {
+	result, err := r.collection.DeleteMany(ctx, bson.M{"aggregate_id": userID.Hex()})
+	if err != nil {
+		return entities.ErasureStep{}, err
+	}
+	return entities.ErasureStep{Name: r.Name(), Action: "deleted", Count: result.DeletedCount}, nil
+}
+
+func (r *WebhookDeliveryRepository) find(ctx context.Context, filter bson.M, opts *options.FindOptions) ([]*entities.WebhookDelivery, error) {
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var deliveries []*entities.WebhookDelivery
+	for cursor.Next(ctx) {
+		var delivery entities.WebhookDelivery
+		if err := cursor.Decode(&delivery); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, &delivery)
+	}
+
+	return deliveries, cursor.Err()
+}
@@ -0,0 +1,108 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/entities"
+	"github.com/kaa-dan/clean-architecture-go/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type WebhookRepository struct {
+	collection *mongo.Collection
+}
+
+func NewWebhookRepository(client *mongo.Client, dbName string) *WebhookRepository {
+	return &WebhookRepository{
+		collection: client.Database(dbName).Collection("webhooks"),
+	}
+}
+
+func (r *WebhookRepository) Create(ctx context.Context, webhook *entities.Webhook) error {
+	webhook.CreatedAt = time.Now()
+	webhook.UpdatedAt = time.Now()
+
+	result, err := r.collection.InsertOne(ctx, webhook)
+	if err != nil {
+		return err
+	}
+
+	webhook.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (r *WebhookRepository) GetByID(ctx context.Context, id primitive.ObjectID) (*entities.Webhook, error) {
+	var webhook entities.Webhook
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&webhook)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.ErrWebhookNotFound
+		}
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+func (r *WebhookRepository) GetAll(ctx context.Context, limit, offset int) ([]*entities.Webhook, error) {
+	opts := options.Find()
+	opts.SetLimit(int64(limit))
+	opts.SetSkip(int64(offset))
+	opts.SetSort(bson.D{{Key: "created_at", Value: -1}})
+
+	return r.find(ctx, bson.M{}, opts)
+}
+
+func (r *WebhookRepository) ListSubscribed(ctx context.Context, eventType string) ([]*entities.Webhook, error) {
+	filter := bson.M{
+		"is_active": true,
+		"events":    bson.M{"$in": bson.A{eventType, entities.WebhookAllEvents}},
+	}
+	return r.find(ctx, filter, options.Find())
+}
+
+func (r *WebhookRepository) Update(ctx context.Context, webhook *entities.Webhook) error {
+	webhook.UpdatedAt = time.Now()
+
+	result, err := r.collection.ReplaceOne(ctx, bson.M{"_id": webhook.ID}, webhook)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errors.ErrWebhookNotFound
+	}
+	return nil
+}
+
+func (r *WebhookRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return errors.ErrWebhookNotFound
+	}
+	return nil
+}
+
+func (r *WebhookRepository) find(ctx context.Context, filter bson.M, opts *options.FindOptions) ([]*entities.Webhook, error) {
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var webhooks []*entities.Webhook
+	for cursor.Next(ctx) {
+		var webhook entities.Webhook
+		if err := cursor.Decode(&webhook); err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, &webhook)
+	}
+
+	return webhooks, cursor.Err()
+}
@@ -0,0 +1,44 @@
+package search
+
+import (
+	"sort"
+
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/entities"
+	"github.com/kaa-dan/clean-architecture-go/pkg/search"
+)
+
+func userFields(user *entities.User) []search.Field {
+	return []search.Field{
+		{Name: "username", Value: user.Username, Weight: 3},
+		{Name: "email", Value: user.Email, Weight: 2},
+		{Name: "first_name", Value: user.FirstName, Weight: 1.5},
+		{Name: "last_name", Value: user.LastName, Weight: 1.5},
+	}
+}
+
+func rank(terms []string, users []*entities.User, limit int) []*entities.UserSearchHit {
+	hits := make([]*entities.UserSearchHit, 0, len(users))
+	for _, user := range users {
+		result := search.Score(terms, userFields(user))
+		if result.Score <= 0 {
+			continue
+		}
+		hits = append(hits, &entities.UserSearchHit{
+			User:       user,
+			Score:      result.Score,
+			Highlights: result.Highlights,
+		})
+	}
+
+	sort.SliceStable(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].User.Username < hits[j].User.Username
+	})
+
+	if limit > 0 && len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits
+}
@@ -0,0 +1,79 @@
+package search
+
+import (
+	"context"
+	"sync"
+
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/entities"
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/repositories"
+	"github.com/kaa-dan/clean-architecture-go/pkg/search"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MemoryIndex keeps a copy of every indexed user in memory. It works with any
+// UserRepository implementation as long as the index is kept up to date.
+type MemoryIndex struct {
+	mu    sync.RWMutex
+	users map[primitive.ObjectID]*entities.User
+}
+
+func NewMemoryIndex() *MemoryIndex {
+	return &MemoryIndex{
+		users: make(map[primitive.ObjectID]*entities.User),
+	}
+}
+
+func (m *MemoryIndex) Index(ctx context.Context, user *entities.User) error {
+	copied := *user
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.users[user.ID] = &copied
+	return nil
+}
+
+func (m *MemoryIndex) Remove(ctx context.Context, id primitive.ObjectID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.users, id)
+	return nil
+}
+
+func (m *MemoryIndex) Search(ctx context.Context, query string, limit int) ([]*entities.UserSearchHit, error) {
+	terms := search.Terms(query)
+	if len(terms) == 0 {
+		return []*entities.UserSearchHit{}, nil
+	}
+
+	m.mu.RLock()
+	users := make([]*entities.User, 0, len(m.users))
+	for _, user := range m.users {
+		copied := *user
+		users = append(users, &copied)
+	}
+	m.mu.RUnlock()
+
+	return rank(terms, users, limit), nil
+}
+
+// Load indexes every user stored in the repository.
+func (m *MemoryIndex) Load(ctx context.Context, userRepo repositories.UserRepository) error {
+	const pageSize = 100
+
+	for offset := 0; ; offset += pageSize {
+		users, err := userRepo.GetAll(ctx, entities.UserFilter{}, pageSize, offset)
+		if err != nil {
+			return err
+		}
+
+		for _, user := range users {
+			if err := m.Index(ctx, user); err != nil {
+				return err
+			}
+		}
+
+		if len(users) < pageSize {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/entities"
+	"github.com/kaa-dan/clean-architecture-go/pkg/logger"
+	"github.com/kaa-dan/clean-architecture-go/pkg/search"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	candidatePrefixLength = 2
+	maxCandidates         = 1000
+
+	// errorCodeIndexNotFound is returned for $text without a text index
+	errorCodeIndexNotFound = 27
+)
+
+var searchableFields = []string{"username", "email", "first_name", "last_name"}
+
+// MongoIndex searches the users collection directly and ranks candidates in
+// memory. Candidates are selected by the whole terms first: whole words
+// through the text index, best matches first, then words starting with the
+// terms. Only when that finds too few users are fuzzy candidates scanned,
+// words sharing the first characters of a term or containing the rest of it,
+// so a typo anywhere in a term is still found. Until the text index of
+// migration 10 exists only the prefix scans run.
+type MongoIndex struct {
+	collection *mongo.Collection
+
+	missingTextIndex sync.Once
+}
+
+func NewMongoIndex(client *mongo.Client, dbName string) *MongoIndex {
+	return &MongoIndex{
+		collection: client.Database(dbName).Collection("users"),
+	}
+}
+
+// Index is a no-op, the users collection is the index.
+func (m *MongoIndex) Index(ctx context.Context, user *entities.User) error {
+	return nil
+}
+
+// Remove is a no-op, the users collection is the index.
+func (m *MongoIndex) Remove(ctx context.Context, id primitive.ObjectID) error {
+	return nil
+}
+
+func (m *MongoIndex) Search(ctx context.Context, query string, limit int) ([]*entities.UserSearchHit, error) {
+	terms := search.Terms(query)
+	if len(terms) == 0 {
+		return []*entities.UserSearchHit{}, nil
+	}
+
+	candidates := candidateSet{}
+
+	textScore := bson.M{"$meta": "textScore"}
+	textOpts := options.Find().
+		SetProjection(bson.M{"text_score": textScore}).
+		SetSort(bson.D{{Key: "text_score", Value: textScore}}).
+		SetLimit(maxCandidates)
+	err := m.collect(ctx, candidates, bson.M{"$text": bson.M{"$search": strings.Join(terms, " ")}}, textOpts)
+	if err != nil {
+		var serverErr mongo.ServerError
+		if !errors.As(err, &serverErr) || !serverErr.HasErrorCode(errorCodeIndexNotFound) {
+			return nil, err
+		}
+		m.missingTextIndex.Do(func() {
+			logger.Warn("users have no text index, run the pending migrations for better search results")
+		})
+	}
+
+	var prefixes bson.A
+	for _, term := range terms {
+		prefixes = append(prefixes, anyField(wordPrefix(term))...)
+	}
+	if err := m.collect(ctx, candidates, bson.M{"$or": prefixes}, newestFirst()); err != nil {
+		return nil, err
+	}
+
+	hits := rank(terms, candidates.users(), limit)
+	if limit > 0 && len(hits) >= limit {
+		return hits, nil
+	}
+
+	var fuzzy bson.A
+	for _, term := range terms {
+		runes := []rune(term)
+		switch {
+		case len(runes) > candidatePrefixLength+1:
+			// Long enough for typos. A typo among the first characters
+			// leaves the rest intact, which also finds the term inside words.
+			fuzzy = append(fuzzy, anyField(wordPrefix(string(runes[:candidatePrefixLength])))...)
+			fuzzy = append(fuzzy, anyField(substring(string(runes[candidatePrefixLength:])))...)
+		case len(runes) > candidatePrefixLength:
+			// Long enough to match inside words
+			fuzzy = append(fuzzy, anyField(substring(term))...)
+		}
+	}
+	if len(fuzzy) == 0 {
+		return hits, nil
+	}
+	if err := m.collect(ctx, candidates, bson.M{"$or": fuzzy}, newestFirst()); err != nil {
+		return nil, err
+	}
+
+	return rank(terms, candidates.users(), limit), nil
+}
+
+// collect adds the users matching filter to candidates
+func (m *MongoIndex) collect(ctx context.Context, candidates candidateSet, filter bson.M, opts *options.FindOptions) error {
+	cursor, err := m.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var user entities.User
+		if err := cursor.Decode(&user); err != nil {
+			return err
+		}
+		candidates[user.ID] = &user
+	}
+	return cursor.Err()
+}
+
+// wordPrefix matches text at the start of a word
+func wordPrefix(text string) primitive.Regex {
+	return primitive.Regex{Pattern: `(^|[^\p{L}\p{N}])` + regexp.QuoteMeta(text), Options: "i"}
+}
+
+func substring(text string) primitive.Regex {
+	return primitive.Regex{Pattern: regexp.QuoteMeta(text), Options: "i"}
+}
+
+// anyField matches pattern against each searchable field
+func anyField(pattern primitive.Regex) bson.A {
+	conditions := make(bson.A, 0, len(searchableFields))
+	for _, field := range searchableFields {
+		conditions = append(conditions, bson.M{field: pattern})
+	}
+	return conditions
+}
+
+func newestFirst() *options.FindOptions {
+	return options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}}).
+		SetLimit(maxCandidates)
+}
+
+type candidateSet map[primitive.ObjectID]*entities.User
+
+func (s candidateSet) users() []*entities.User {
+	users := make([]*entities.User, 0, len(s))
+	for _, user := range s {
+		users = append(users, user)
+	}
+	return users
+}
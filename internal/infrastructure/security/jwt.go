@@ -18,6 +18,7 @@ type Claims struct {
 	Email    string `json:"email"`
 	Username string `json:"username"`
 	Role     string `json:"role"`
+	Locale   string `json:"locale,omitempty"`
 	jwt.RegisteredClaims
 }
 
@@ -34,6 +35,7 @@ func (j *JWTManager) GenerateToken(user *entities.User) (string, error) {
 		Email:    user.Email,
 		Username: user.Username,
 		Role:     user.Role,
+		Locale:   user.Locale,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Duration(j.expiryHours) * time.Hour)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
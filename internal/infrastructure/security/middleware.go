@@ -6,6 +6,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/kaa-dan/clean-architecture-go/internal/domain/entities"
+	"github.com/kaa-dan/clean-architecture-go/pkg/i18n"
 	"github.com/kaa-dan/clean-architecture-go/pkg/response"
 )
 
@@ -21,32 +22,55 @@ func NewAuthMiddleware(jwtManager *JWTManager) *AuthMiddleware {
 
 func (a *AuthMiddleware) RequireAuth() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
+		if c.GetHeader("Authorization") == "" {
 			response.Error(c, http.StatusUnauthorized, "Authorization header required")
 			c.Abort()
 			return
 		}
 
-		tokenParts := strings.Split(authHeader, " ")
-		if len(tokenParts) != 2 || tokenParts[0] != "Bearer" {
-			response.Error(c, http.StatusUnauthorized, "Invalid authorization header format")
-			c.Abort()
-			return
+		if a.authenticate(c) {
+			c.Next()
 		}
+	}
+}
 
-		claims, err := a.jwtManager.ValidateToken(tokenParts[1])
-		if err != nil {
-			response.Error(c, http.StatusUnauthorized, "Invalid token")
-			c.Abort()
+// OptionalAuth identifies the caller when an Authorization header is sent
+// and lets anonymous requests through. An invalid token is still rejected.
+func (a *AuthMiddleware) OptionalAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" {
+			c.Next()
 			return
 		}
 
-		c.Set("user_id", claims.UserID)
-		c.Set("user_email", claims.Email)
-		c.Set("user_role", claims.Role)
-		c.Next()
+		if a.authenticate(c) {
+			c.Next()
+		}
+	}
+}
+
+func (a *AuthMiddleware) authenticate(c *gin.Context) bool {
+	tokenParts := strings.Split(c.GetHeader("Authorization"), " ")
+	if len(tokenParts) != 2 || tokenParts[0] != "Bearer" {
+		response.Error(c, http.StatusUnauthorized, "Invalid authorization header format")
+		c.Abort()
+		return false
+	}
+
+	claims, err := a.jwtManager.ValidateToken(tokenParts[1])
+	if err != nil {
+		response.Error(c, http.StatusUnauthorized, "Invalid token")
+		c.Abort()
+		return false
+	}
+
+	c.Set("user_id", claims.UserID)
+	c.Set("user_email", claims.Email)
+	c.Set("user_role", claims.Role)
+	if claims.Locale != "" {
+		c.Set(i18n.ContextKey, claims.Locale)
 	}
+	return true
 }
 
 func (a *AuthMiddleware) RequireAdmin() gin.HandlerFunc {
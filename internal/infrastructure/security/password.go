@@ -20,6 +20,12 @@ func (p *PasswordManager) HashPassword(password string) (string, error) {
 	return string(hashedBytes), nil
 }
 
+// ValidateHash checks that a pre-hashed password is a usable bcrypt hash.
+func (p *PasswordManager) ValidateHash(hashedPassword string) error {
+	_, err := bcrypt.Cost([]byte(hashedPassword))
+	return err
+}
+
 func (p *PasswordManager) VerifyPassword(hashedPassword, password string) error {
 	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
 }
@@ -0,0 +1,74 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/entities"
+)
+
+const (
+	SignatureHeader = "Webhook-Signature"
+	sendTimeout     = 10 * time.Second
+)
+
+// HTTPSender POSTs deliveries and signs them with the webhook secret.
+// Redirects are not followed, a 3xx response counts as a failure.
+type HTTPSender struct {
+	client *http.Client
+}
+
+func NewHTTPSender() *HTTPSender {
+	return &HTTPSender{
+		client: &http.Client{
+			Timeout: sendTimeout,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+func (s *HTTPSender) Send(ctx context.Context, webhook *entities.Webhook, delivery *entities.WebhookDelivery) (int, error) {
+	payload := []byte(delivery.Payload)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "clean-architecture-go-webhooks/1")
+	req.Header.Set("Webhook-ID", delivery.ID.Hex())
+	req.Header.Set("Webhook-Event", delivery.EventType)
+	req.Header.Set(SignatureHeader, Sign(webhook.Secret, time.Now(), payload))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	return resp.StatusCode, nil
+}
+
+// Sign returns the signature header value "t=<unix seconds>,v1=<hex>", where
+// the hex part is the HMAC-SHA256 of "<unix seconds>.<payload>" keyed with the
+// secret. Receivers should reject timestamps that are too old to stop replays.
+func Sign(secret string, timestamp time.Time, payload []byte) string {
+	ts := strconv.FormatInt(timestamp.Unix(), 10)
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+
+	return "t=" + ts + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
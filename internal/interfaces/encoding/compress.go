@@ -0,0 +1,285 @@
+package encoding
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kaa-dan/clean-architecture-go/pkg/codec"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	encodingGzip = "gzip"
+	encodingZstd = "zstd"
+)
+
+// Media types worth compressing besides text/*, +json and +xml. Images,
+// archives and spreadsheets are compressed already.
+var compressibleTypes = map[string]bool{
+	codec.MediaTypeJSON:      true,
+	codec.MediaTypeMsgPack:   true,
+	codec.MediaTypeProtobuf:  true,
+	"application/x-ndjson":   true,
+	"application/javascript": true,
+	"application/xml":        true,
+}
+
+// Compressor compresses responses with gzip or zstd, whichever the client
+// prefers, once they reach minSize bytes. Smaller responses are sent as they
+// are, the savings would not pay for the work.
+type Compressor struct {
+	minSize int
+	gzip    sync.Pool
+	zstd    sync.Pool
+}
+
+func NewCompressor(minSize int) *Compressor {
+	return &Compressor{
+		minSize: minSize,
+		gzip: sync.Pool{New: func() interface{} {
+			return gzip.NewWriter(io.Discard)
+		}},
+		zstd: sync.Pool{New: func() interface{} {
+			// Responses are compressed on the request goroutine
+			encoder, _ := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+			return encoder
+		}},
+	}
+}
+
+// Handle compresses the responses of requests that accept gzip or zstd.
+// Responses to other requests pass through it as well, to be marked with
+// Vary: Accept-Encoding.
+func (cp *Compressor) Handle() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method == http.MethodHead {
+			c.Next()
+			return
+		}
+
+		writer := &compressWriter{
+			ResponseWriter: c.Writer,
+			compressor:     cp,
+			encoding:       negotiateEncoding(c.GetHeader("Accept-Encoding")),
+		}
+		c.Writer = writer
+		defer func() {
+			writer.close()
+			c.Writer = writer.ResponseWriter
+		}()
+		c.Next()
+	}
+}
+
+// negotiateEncoding picks the supported content coding with the highest
+// quality in Accept-Encoding, zstd on ties. It returns "" for none.
+func negotiateEncoding(header string) string {
+	qualities := map[string]float64{}
+	for _, item := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(item), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		quality := 1.0
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(q, 64)
+			if err != nil {
+				continue
+			}
+			quality = parsed
+		}
+
+		switch name {
+		case encodingGzip, encodingZstd:
+			qualities[name] = quality
+		case "*":
+			for _, encoding := range []string{encodingGzip, encodingZstd} {
+				if _, named := qualities[encoding]; !named {
+					qualities[encoding] = quality
+				}
+			}
+		}
+	}
+
+	best := ""
+	for _, encoding := range []string{encodingZstd, encodingGzip} {
+		if qualities[encoding] > 0 && (best == "" || qualities[encoding] > qualities[best]) {
+			best = encoding
+		}
+	}
+	return best
+}
+
+// compressWriter holds back the body until it reaches the minimum size, and
+// decides then whether to compress it
+type compressWriter struct {
+	gin.ResponseWriter
+	compressor *Compressor
+	// encoding is empty when the client accepts neither
+	encoding string
+
+	buffer  []byte
+	decided bool
+	encoder io.WriteCloser
+}
+
+func (w *compressWriter) Write(data []byte) (int, error) {
+	if !w.decided {
+		if w.encoding == "" || !w.compressible() {
+			w.decide(false)
+		} else {
+			w.buffer = append(w.buffer, data...)
+			if len(w.buffer) < w.compressor.minSize {
+				return len(data), nil
+			}
+			if err := w.decide(true); err != nil {
+				return 0, err
+			}
+			return len(data), nil
+		}
+	}
+
+	if w.encoder != nil {
+		return w.encoder.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// WriteHeaderNow sends the headers of a response without a body
+func (w *compressWriter) WriteHeaderNow() {
+	if !w.decided {
+		w.decide(false)
+	}
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+// Flush sends what was written so far. The size of a streamed body is not
+// known, so it is compressed if its type allows.
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		w.decide(w.encoding != "" && w.compressible())
+	}
+	if flusher, ok := w.encoder.(interface{ Flush() error }); ok {
+		flusher.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// Unwrap lets http.ResponseController reach the connection, so that streams
+// can lift the write deadline
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *compressWriter) Written() bool {
+	return len(w.buffer) > 0 || w.ResponseWriter.Written()
+}
+
+// close sends a body that stayed below the minimum size and completes a
+// compressed one
+func (w *compressWriter) close() {
+	if !w.decided {
+		w.decide(false)
+	}
+	if w.encoder == nil {
+		return
+	}
+	w.encoder.Close()
+	switch encoder := w.encoder.(type) {
+	case *gzip.Writer:
+		encoder.Reset(io.Discard)
+		w.compressor.gzip.Put(encoder)
+	case *zstd.Encoder:
+		encoder.Reset(nil)
+		w.compressor.zstd.Put(encoder)
+	}
+	w.encoder = nil
+}
+
+// compressible reports whether the response may be compressed, judging by
+// its status and headers
+func (w *compressWriter) compressible() bool {
+	switch w.ResponseWriter.Status() {
+	case http.StatusNoContent, http.StatusNotModified, http.StatusPartialContent:
+		return false
+	}
+
+	header := w.Header()
+	if header.Get("Content-Encoding") != "" || header.Get("Content-Range") != "" {
+		return false
+	}
+	if length, err := strconv.Atoi(header.Get("Content-Length")); err == nil && length < w.compressor.minSize {
+		return false
+	}
+
+	mediaType, _, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		return false
+	}
+	switch {
+	case mediaType == "text/event-stream":
+		// Events are flushed one by one, too small to gain anything
+		return false
+	case strings.HasPrefix(mediaType, "text/"),
+		strings.HasSuffix(mediaType, "+json"),
+		strings.HasSuffix(mediaType, "+xml"):
+		return true
+	}
+	return compressibleTypes[mediaType]
+}
+
+// decide settles the encoding of the body and writes what was held back
+func (w *compressWriter) decide(compress bool) error {
+	w.decided = true
+	buffered := w.buffer
+	w.buffer = nil
+
+	header := w.Header()
+	if w.compressible() {
+		// Whether another response is compressed depends on the request
+		addVary(header, "Accept-Encoding")
+	}
+	if compress {
+		header.Set("Content-Encoding", w.encoding)
+		header.Del("Content-Length")
+		switch w.encoding {
+		case encodingGzip:
+			encoder := w.compressor.gzip.Get().(*gzip.Writer)
+			encoder.Reset(w.ResponseWriter)
+			w.encoder = encoder
+		case encodingZstd:
+			encoder := w.compressor.zstd.Get().(*zstd.Encoder)
+			encoder.Reset(w.ResponseWriter)
+			w.encoder = encoder
+		}
+	}
+
+	if len(buffered) == 0 {
+		return nil
+	}
+	if w.encoder != nil {
+		_, err := w.encoder.Write(buffered)
+		return err
+	}
+	_, err := w.ResponseWriter.Write(buffered)
+	return err
+}
+
+func addVary(header http.Header, name string) {
+	for _, value := range header.Values("Vary") {
+		for _, named := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(named), name) {
+				return
+			}
+		}
+	}
+	header.Add("Vary", name)
+}
@@ -0,0 +1,43 @@
+// Package encoding decodes request bodies sent in the binary encodings of
+// pkg/codec and compresses responses.
+package encoding
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kaa-dan/clean-architecture-go/pkg/codec"
+	"github.com/kaa-dan/clean-architecture-go/pkg/errors"
+	"github.com/kaa-dan/clean-architecture-go/pkg/response"
+)
+
+// DecodeBodies converts MessagePack and Protobuf request bodies to JSON, so
+// that request validation and the handlers read every encoding the same way.
+// Other bodies pass unchanged.
+func DecodeBodies() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		format, ok := codec.ForContentType(c.GetHeader("Content-Type"))
+		if !ok || format == codec.JSON || c.Request.Body == nil {
+			c.Next()
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err == nil {
+			body, err = format.ToJSON(body)
+		}
+		if err != nil {
+			response.HandleError(c, errors.ErrInvalidRequestBody)
+			c.Abort()
+			return
+		}
+
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		c.Request.ContentLength = int64(len(body))
+		c.Request.Header.Set("Content-Length", strconv.Itoa(len(body)))
+		c.Request.Header.Set("Content-Type", codec.MediaTypeJSON)
+		c.Next()
+	}
+}
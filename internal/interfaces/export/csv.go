@@ -0,0 +1,46 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"strings"
+)
+
+type csvWriter struct {
+	writer *csv.Writer
+}
+
+func newCSVWriter(w io.Writer) *csvWriter {
+	return &csvWriter{writer: csv.NewWriter(w)}
+}
+
+func (c *csvWriter) WriteHeader(columns []string) error {
+	return c.writer.Write(columns)
+}
+
+func (c *csvWriter) WriteRow(values []interface{}) error {
+	record := make([]string, len(values))
+	for i, value := range values {
+		record[i] = escapeFormula(formatValue(value))
+	}
+	return c.writer.Write(record)
+}
+
+func (c *csvWriter) Flush() error {
+	c.writer.Flush()
+	return c.writer.Error()
+}
+
+func (c *csvWriter) Close() error {
+	c.writer.Flush()
+	return c.writer.Error()
+}
+
+// escapeFormula stops spreadsheet applications from evaluating user supplied
+// values such as "=HYPERLINK(...)" as formulas.
+func escapeFormula(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
@@ -0,0 +1,126 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/entities"
+	"github.com/kaa-dan/clean-architecture-go/pkg/errors"
+)
+
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+	FormatXLSX   = "xlsx"
+)
+
+// Writer encodes rows as they are produced, so an export never has to be
+// held in memory.
+type Writer interface {
+	WriteHeader(columns []string) error
+	WriteRow(values []interface{}) error
+	Flush() error
+	Close() error
+}
+
+type Column struct {
+	Name  string
+	Value func(user *entities.UserResponse) interface{}
+}
+
+// userColumns lists every exportable field. Secrets such as the password
+// hash are not part of entities.UserResponse and can never be selected.
+var userColumns = []Column{
+	{Name: "id", Value: func(u *entities.UserResponse) interface{} { return u.ID }},
+	{Name: "email", Value: func(u *entities.UserResponse) interface{} { return u.Email }},
+	{Name: "username", Value: func(u *entities.UserResponse) interface{} { return u.Username }},
+	{Name: "first_name", Value: func(u *entities.UserResponse) interface{} { return u.FirstName }},
+	{Name: "last_name", Value: func(u *entities.UserResponse) interface{} { return u.LastName }},
+	{Name: "is_active", Value: func(u *entities.UserResponse) interface{} { return u.IsActive }},
+	{Name: "role", Value: func(u *entities.UserResponse) interface{} { return u.Role }},
+	{Name: "version", Value: func(u *entities.UserResponse) interface{} { return u.Version }},
+	{Name: "created_at", Value: func(u *entities.UserResponse) interface{} { return u.CreatedAt }},
+	{Name: "updated_at", Value: func(u *entities.UserResponse) interface{} { return u.UpdatedAt }},
+}
+
+// UserColumns resolves a comma separated column selection. An empty
+// selection exports every column. Custom attributes are selected as
+// attributes.<name>.
+func UserColumns(selection string) ([]Column, error) {
+	if strings.TrimSpace(selection) == "" {
+		return userColumns, nil
+	}
+
+	byName := make(map[string]Column, len(userColumns))
+	for _, column := range userColumns {
+		byName[column.Name] = column
+	}
+
+	var columns []Column
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(selection, ",") {
+		name = strings.TrimSpace(name)
+		column, ok := byName[name]
+		if attribute, isAttribute := strings.CutPrefix(name, "attributes."); isAttribute && attribute != "" {
+			column, ok = attributeColumn(name, attribute), true
+		}
+		if !ok {
+			return nil, fmt.Errorf("unknown column %q", name)
+		}
+		if !seen[name] {
+			seen[name] = true
+			columns = append(columns, column)
+		}
+	}
+	return columns, nil
+}
+
+func attributeColumn(name, attribute string) Column {
+	return Column{
+		Name: name,
+		Value: func(u *entities.UserResponse) interface{} {
+			return u.Attributes[attribute]
+		},
+	}
+}
+
+func NewWriter(format string, w io.Writer) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(w), nil
+	case FormatNDJSON:
+		return newNDJSONWriter(w), nil
+	case FormatXLSX:
+		return newXLSXWriter(w)
+	default:
+		return nil, errors.ErrUnsupportedFormat
+	}
+}
+
+func ContentType(format string) string {
+	switch format {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatNDJSON:
+		return "application/x-ndjson"
+	case FormatXLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	default:
+		return "application/octet-stream"
+	}
+}
+
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case time.Time:
+		return v.UTC().Format(time.RFC3339)
+	case string:
+		return v
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(v)
+	}
+}
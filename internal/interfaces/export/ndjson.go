@@ -0,0 +1,59 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"time"
+)
+
+type ndjsonWriter struct {
+	writer  io.Writer
+	columns []string
+	buf     bytes.Buffer
+}
+
+func newNDJSONWriter(w io.Writer) *ndjsonWriter {
+	return &ndjsonWriter{writer: w}
+}
+
+func (n *ndjsonWriter) WriteHeader(columns []string) error {
+	n.columns = columns
+	return nil
+}
+
+// WriteRow keeps the selected column order, which a map would not.
+func (n *ndjsonWriter) WriteRow(values []interface{}) error {
+	n.buf.Reset()
+	n.buf.WriteByte('{')
+	for i, value := range values {
+		if i > 0 {
+			n.buf.WriteByte(',')
+		}
+
+		key, _ := json.Marshal(n.columns[i])
+		n.buf.Write(key)
+		n.buf.WriteByte(':')
+
+		if t, ok := value.(time.Time); ok {
+			value = t.UTC().Format(time.RFC3339)
+		}
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		n.buf.Write(encoded)
+	}
+	n.buf.WriteString("}\n")
+
+	_, err := n.writer.Write(n.buf.Bytes())
+	return err
+}
+
+func (n *ndjsonWriter) Flush() error {
+	return nil
+}
+
+func (n *ndjsonWriter) Close() error {
+	return nil
+}
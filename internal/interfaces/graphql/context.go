@@ -0,0 +1,53 @@
+package graphql
+
+import (
+	"context"
+
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/entities"
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/services"
+	"github.com/kaa-dan/clean-architecture-go/pkg/dataloader"
+)
+
+// maxUserBatch bounds the number of IDs in a single lookup
+const maxUserBatch = 100
+
+type requestKey struct{}
+
+// request is the per-request state resolvers share: the caller and the
+// loaders that batch their lookups
+type request struct {
+	viewer entities.Viewer
+	users  *dataloader.Loader
+}
+
+func withRequest(ctx context.Context, userService services.UserService, viewer entities.Viewer) context.Context {
+	users := dataloader.New(func(ctx context.Context, ids []string) (map[string]interface{}, error) {
+		found, err := userService.GetUsersByIDs(ctx, ids, viewer)
+		if err != nil {
+			return nil, err
+		}
+		values := make(map[string]interface{}, len(found))
+		for id, user := range found {
+			values[id] = user
+		}
+		return values, nil
+	}, maxUserBatch)
+
+	return context.WithValue(ctx, requestKey{}, &request{viewer: viewer, users: users})
+}
+
+func requestFrom(ctx context.Context) *request {
+	if r, ok := ctx.Value(requestKey{}).(*request); ok {
+		return r
+	}
+	return &request{}
+}
+
+// requireViewer fails unless the caller is authenticated
+func requireViewer(ctx context.Context) (entities.Viewer, error) {
+	viewer := requestFrom(ctx).viewer
+	if viewer.UserID == "" {
+		return viewer, newError("UNAUTHENTICATED", "Authentication required")
+	}
+	return viewer, nil
+}
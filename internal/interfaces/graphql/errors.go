@@ -0,0 +1,67 @@
+package graphql
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/kaa-dan/clean-architecture-go/pkg/errors"
+	"github.com/kaa-dan/clean-architecture-go/pkg/logger"
+	"github.com/kaa-dan/clean-architecture-go/pkg/validator"
+)
+
+// resolverError carries a machine readable code in the "extensions" of a
+// GraphQL error, next to the message the REST API would send. reason is the
+// stable error code of the REST API, when there is one.
+type resolverError struct {
+	message string
+	code    string
+	reason  errors.Code
+}
+
+func (e *resolverError) Error() string {
+	return e.message
+}
+
+func (e *resolverError) Extensions() map[string]interface{} {
+	extensions := map[string]interface{}{"code": e.code}
+	if e.reason != "" {
+		extensions["reason"] = e.reason
+	}
+	return extensions
+}
+
+func newError(code, message string) error {
+	return &resolverError{message: message, code: code}
+}
+
+// toError maps the pkg/errors sentinels to error codes, following the HTTP
+// status each sentinel has in the REST API. Unknown errors are logged and
+// hidden.
+func toError(err error) error {
+	if validator.IsValidationError(err) {
+		return newError("BAD_USER_INPUT", strings.Join(validator.ErrorMessages(err), "; "))
+	}
+
+	description := errors.Describe(err)
+
+	var code string
+	switch description.Status {
+	case http.StatusBadRequest, http.StatusUnsupportedMediaType, http.StatusRequestEntityTooLarge,
+		http.StatusUnprocessableEntity:
+		code = "BAD_USER_INPUT"
+	case http.StatusUnauthorized:
+		code = "UNAUTHENTICATED"
+	case http.StatusForbidden:
+		code = "FORBIDDEN"
+	case http.StatusNotFound:
+		code = "NOT_FOUND"
+	case http.StatusConflict:
+		code = "CONFLICT"
+	case http.StatusPreconditionFailed:
+		code = "PRECONDITION_FAILED"
+	default:
+		logger.Errorf("graphql request failed: %v", err)
+		return newError("INTERNAL_SERVER_ERROR", errors.ErrInternalServer.Error())
+	}
+	return &resolverError{message: description.Message, code: code, reason: description.Code}
+}
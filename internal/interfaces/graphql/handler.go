@@ -0,0 +1,130 @@
+// Package graphql serves the user API as a GraphQL endpoint, next to the
+// REST interface.
+package graphql
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	graphqllib "github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/entities"
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/services"
+)
+
+type Handler struct {
+	schema      graphqllib.Schema
+	userService services.UserService
+	limits      Limits
+}
+
+func NewHandler(userService services.UserService, limits Limits) (*Handler, error) {
+	schema, err := NewSchema(userService)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Handler{
+		schema:      schema,
+		userService: userService,
+		limits:      limits,
+	}, nil
+}
+
+type graphQLRequest struct {
+	Query         string                 `json:"query" form:"query"`
+	OperationName string                 `json:"operationName" form:"operationName"`
+	Variables     map[string]interface{} `json:"variables" form:"-"`
+}
+
+// Query executes a GraphQL request sent as a JSON body, or as query
+// parameters with GET. Mutations are only accepted with POST.
+func (h *Handler) Query(c *gin.Context) {
+	var req graphQLRequest
+	var err error
+	if c.Request.Method == http.MethodGet {
+		err = c.ShouldBindQuery(&req)
+		if variables := c.Query("variables"); err == nil && variables != "" {
+			err = json.Unmarshal([]byte(variables), &req.Variables)
+		}
+	} else {
+		err = c.ShouldBindJSON(&req)
+	}
+	if err != nil || req.Query == "" {
+		requestError(c, http.StatusBadRequest, "Invalid GraphQL request, a query is required")
+		return
+	}
+
+	doc, err := parser.Parse(parser.ParseParams{
+		Source: source.NewSource(&source.Source{Body: []byte(req.Query), Name: "GraphQL request"}),
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, &graphqllib.Result{Errors: []gqlerrors.FormattedError{gqlerrors.FormatError(err)}})
+		return
+	}
+
+	if validation := graphqllib.ValidateDocument(&h.schema, doc, nil); !validation.IsValid {
+		c.JSON(http.StatusBadRequest, &graphqllib.Result{Errors: validation.Errors})
+		return
+	}
+
+	operation := findOperation(doc, req.OperationName)
+	if operation == nil {
+		requestError(c, http.StatusBadRequest, "Unknown or ambiguous operation")
+		return
+	}
+	if operation.Operation != ast.OperationTypeQuery && c.Request.Method == http.MethodGet {
+		requestError(c, http.StatusMethodNotAllowed, "Mutations must be sent with POST")
+		return
+	}
+
+	if err := h.limits.check(doc, operation, req.Variables); err != nil {
+		requestError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	viewer := entities.Viewer{
+		UserID: c.GetString("user_id"),
+		Role:   c.GetString("user_role"),
+	}
+	result := graphqllib.Execute(graphqllib.ExecuteParams{
+		Schema:        h.schema,
+		AST:           doc,
+		OperationName: req.OperationName,
+		Args:          req.Variables,
+		Context:       withRequest(c.Request.Context(), h.userService, viewer),
+	})
+
+	c.JSON(http.StatusOK, result)
+}
+
+// findOperation returns the operation to execute: the one named, or the
+// only one in the document
+func findOperation(doc *ast.Document, name string) *ast.OperationDefinition {
+	var found *ast.OperationDefinition
+	for _, definition := range doc.Definitions {
+		operation, ok := definition.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		if name == "" {
+			if found != nil {
+				return nil
+			}
+			found = operation
+			continue
+		}
+		if operation.Name != nil && operation.Name.Value == name {
+			return operation
+		}
+	}
+	return found
+}
+
+func requestError(c *gin.Context, status int, message string) {
+	c.JSON(status, &graphqllib.Result{Errors: []gqlerrors.FormattedError{gqlerrors.NewFormattedError(message)}})
+}
@@ -0,0 +1,106 @@
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// listFields maps fields that return a page of results to the argument
+// holding the page size. Their selections are counted once per result.
+var listFields = map[string]string{
+	"users": "first",
+}
+
+// Limits caps the cost of a query before it is executed
+type Limits struct {
+	MaxDepth      int
+	MaxComplexity int
+}
+
+// analyzer measures the depth and complexity of a validated document. Each
+// field costs one, fields of list results cost one per result, and
+// introspection fields are free.
+type analyzer struct {
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]interface{}
+}
+
+func (l Limits) check(doc *ast.Document, operation *ast.OperationDefinition, variables map[string]interface{}) error {
+	a := analyzer{
+		fragments: make(map[string]*ast.FragmentDefinition),
+		variables: variables,
+	}
+	for _, definition := range doc.Definitions {
+		if fragment, ok := definition.(*ast.FragmentDefinition); ok {
+			a.fragments[fragment.Name.Value] = fragment
+		}
+	}
+
+	depth, complexity := a.selectionSet(operation.SelectionSet)
+	if l.MaxDepth > 0 && depth > l.MaxDepth {
+		return fmt.Errorf("query depth %d exceeds the limit of %d", depth, l.MaxDepth)
+	}
+	if l.MaxComplexity > 0 && complexity > l.MaxComplexity {
+		return fmt.Errorf("query complexity %d exceeds the limit of %d", complexity, l.MaxComplexity)
+	}
+	return nil
+}
+
+func (a *analyzer) selectionSet(set *ast.SelectionSet) (depth, complexity int) {
+	if set == nil {
+		return 0, 0
+	}
+
+	for _, selection := range set.Selections {
+		var d, c int
+		switch s := selection.(type) {
+		case *ast.Field:
+			if strings.HasPrefix(s.Name.Value, "__") {
+				continue
+			}
+			d, c = a.selectionSet(s.SelectionSet)
+			d++
+			c = 1 + c*a.multiplier(s)
+		case *ast.InlineFragment:
+			d, c = a.selectionSet(s.SelectionSet)
+		case *ast.FragmentSpread:
+			// Validation has already rejected unknown and cyclic fragments
+			if fragment, ok := a.fragments[s.Name.Value]; ok {
+				d, c = a.selectionSet(fragment.SelectionSet)
+			}
+		}
+		depth = max(depth, d)
+		complexity += c
+	}
+	return depth, complexity
+}
+
+func (a *analyzer) multiplier(field *ast.Field) int {
+	argName, ok := listFields[field.Name.Value]
+	if !ok {
+		return 1
+	}
+
+	size := defaultPageSize
+	for _, arg := range field.Arguments {
+		if arg.Name.Value != argName {
+			continue
+		}
+		switch v := arg.Value.(type) {
+		case *ast.IntValue:
+			size, _ = strconv.Atoi(v.Value)
+		case *ast.Variable:
+			if n, ok := a.variables[v.Name.Value].(float64); ok {
+				size = int(n)
+			}
+		}
+	}
+
+	if size <= 0 {
+		return defaultPageSize
+	}
+	return min(size, maxPageSize)
+}
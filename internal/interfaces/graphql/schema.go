@@ -0,0 +1,482 @@
+package graphql
+
+import (
+	"encoding/base64"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	graphqllib "github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/entities"
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/services"
+	"github.com/kaa-dan/clean-architecture-go/pkg/errors"
+	"github.com/kaa-dan/clean-architecture-go/pkg/validator"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	defaultPageSize = 10
+	maxPageSize     = 100
+)
+
+// jsonScalar carries arbitrary JSON, used for custom attributes
+var jsonScalar = graphqllib.NewScalar(graphqllib.ScalarConfig{
+	Name:        "JSON",
+	Description: "Any JSON value",
+	Serialize: func(value interface{}) interface{} {
+		return value
+	},
+	ParseValue: func(value interface{}) interface{} {
+		return value
+	},
+	ParseLiteral: parseJSONLiteral,
+})
+
+func parseJSONLiteral(value ast.Value) interface{} {
+	switch v := value.(type) {
+	case *ast.StringValue:
+		return v.Value
+	case *ast.EnumValue:
+		return v.Value
+	case *ast.BooleanValue:
+		return v.Value
+	case *ast.IntValue:
+		n, _ := strconv.ParseFloat(v.Value, 64)
+		return n
+	case *ast.FloatValue:
+		n, _ := strconv.ParseFloat(v.Value, 64)
+		return n
+	case *ast.ListValue:
+		values := make([]interface{}, 0, len(v.Values))
+		for _, item := range v.Values {
+			values = append(values, parseJSONLiteral(item))
+		}
+		return values
+	case *ast.ObjectValue:
+		values := make(map[string]interface{}, len(v.Fields))
+		for _, field := range v.Fields {
+			values[field.Name.Value] = parseJSONLiteral(field.Value)
+		}
+		return values
+	}
+	return nil
+}
+
+type resolver struct {
+	userService services.UserService
+	validator   *validator.Validator
+}
+
+// NewSchema builds the GraphQL schema. Every field is resolved through
+// services.UserService with the same access rules as the REST API.
+func NewSchema(userService services.UserService) (graphqllib.Schema, error) {
+	r := &resolver{
+		userService: userService,
+		validator:   validator.New(),
+	}
+
+	avatarVariantType := graphqllib.NewObject(graphqllib.ObjectConfig{
+		Name: "AvatarVariant",
+		Fields: graphqllib.Fields{
+			"size": &graphqllib.Field{Type: graphqllib.NewNonNull(graphqllib.Int)},
+			"url":  &graphqllib.Field{Type: graphqllib.NewNonNull(graphqllib.String)},
+		},
+	})
+
+	userType := graphqllib.NewObject(graphqllib.ObjectConfig{
+		Name:        "User",
+		Description: "A user. Custom attributes are limited to those the caller may see.",
+		Fields: graphqllib.Fields{
+			"id":             userField(graphqllib.ID, func(u *entities.UserResponse) interface{} { return u.ID }),
+			"email":          userField(graphqllib.String, func(u *entities.UserResponse) interface{} { return u.Email }),
+			"username":       userField(graphqllib.String, func(u *entities.UserResponse) interface{} { return u.Username }),
+			"firstName":      userField(graphqllib.String, func(u *entities.UserResponse) interface{} { return u.FirstName }),
+			"lastName":       userField(graphqllib.String, func(u *entities.UserResponse) interface{} { return u.LastName }),
+			"isActive":       userField(graphqllib.Boolean, func(u *entities.UserResponse) interface{} { return u.IsActive }),
+			"role":           userField(graphqllib.String, func(u *entities.UserResponse) interface{} { return u.Role }),
+			"version":        userField(graphqllib.Int, func(u *entities.UserResponse) interface{} { return u.Version }),
+			"createdAt":      userField(graphqllib.DateTime, func(u *entities.UserResponse) interface{} { return u.CreatedAt }),
+			"updatedAt":      userField(graphqllib.DateTime, func(u *entities.UserResponse) interface{} { return u.UpdatedAt }),
+			"avatarVariants": userField(graphqllib.NewList(graphqllib.NewNonNull(avatarVariantType)), avatarVariants),
+			"avatarUrl": &graphqllib.Field{
+				Type: graphqllib.String,
+				Resolve: func(p graphqllib.ResolveParams) (interface{}, error) {
+					if url := p.Source.(*entities.UserResponse).AvatarURL; url != "" {
+						return url, nil
+					}
+					return nil, nil
+				},
+			},
+			"attributes": &graphqllib.Field{
+				Type: jsonScalar,
+				Resolve: func(p graphqllib.ResolveParams) (interface{}, error) {
+					if attributes := p.Source.(*entities.UserResponse).Attributes; attributes != nil {
+						return attributes, nil
+					}
+					return map[string]interface{}{}, nil
+				},
+			},
+		},
+	})
+
+	userEdgeType := graphqllib.NewObject(graphqllib.ObjectConfig{
+		Name: "UserEdge",
+		Fields: graphqllib.Fields{
+			"cursor": &graphqllib.Field{Type: graphqllib.NewNonNull(graphqllib.String)},
+			"node":   &graphqllib.Field{Type: graphqllib.NewNonNull(userType)},
+		},
+	})
+
+	pageInfoType := graphqllib.NewObject(graphqllib.ObjectConfig{
+		Name: "PageInfo",
+		Fields: graphqllib.Fields{
+			"hasNextPage": &graphqllib.Field{Type: graphqllib.NewNonNull(graphqllib.Boolean)},
+			"endCursor":   &graphqllib.Field{Type: graphqllib.String},
+		},
+	})
+
+	userConnectionType := graphqllib.NewObject(graphqllib.ObjectConfig{
+		Name: "UserConnection",
+		Fields: graphqllib.Fields{
+			"edges":    &graphqllib.Field{Type: graphqllib.NewNonNull(graphqllib.NewList(graphqllib.NewNonNull(userEdgeType)))},
+			"pageInfo": &graphqllib.Field{Type: graphqllib.NewNonNull(pageInfoType)},
+		},
+	})
+
+	userFilterType := graphqllib.NewInputObject(graphqllib.InputObjectConfig{
+		Name: "UserFilter",
+		Fields: graphqllib.InputObjectConfigFieldMap{
+			"role":          &graphqllib.InputObjectFieldConfig{Type: graphqllib.String},
+			"isActive":      &graphqllib.InputObjectFieldConfig{Type: graphqllib.Boolean},
+			"createdAfter":  &graphqllib.InputObjectFieldConfig{Type: graphqllib.DateTime},
+			"createdBefore": &graphqllib.InputObjectFieldConfig{Type: graphqllib.DateTime},
+			"attributes": &graphqllib.InputObjectFieldConfig{
+				Type:        jsonScalar,
+				Description: "Custom attribute values to match, keyed by name",
+			},
+		},
+	})
+
+	updateUserInputType := graphqllib.NewInputObject(graphqllib.InputObjectConfig{
+		Name: "UpdateUserInput",
+		Fields: graphqllib.InputObjectConfigFieldMap{
+			"firstName": &graphqllib.InputObjectFieldConfig{Type: graphqllib.String},
+			"lastName":  &graphqllib.InputObjectFieldConfig{Type: graphqllib.String},
+			"username":  &graphqllib.InputObjectFieldConfig{Type: graphqllib.String},
+			"attributes": &graphqllib.InputObjectFieldConfig{
+				Type:        jsonScalar,
+				Description: "Attributes to set, a null value removes one",
+			},
+		},
+	})
+
+	query := graphqllib.NewObject(graphqllib.ObjectConfig{
+		Name: "Query",
+		Fields: graphqllib.Fields{
+			"viewer": &graphqllib.Field{
+				Type:        graphqllib.NewNonNull(userType),
+				Description: "The authenticated caller",
+				Resolve:     r.viewer,
+			},
+			"user": &graphqllib.Field{
+				Type:        userType,
+				Description: "A user by ID, or null when there is none",
+				Args: graphqllib.FieldConfigArgument{
+					"id": &graphqllib.ArgumentConfig{Type: graphqllib.NewNonNull(graphqllib.ID)},
+				},
+				Resolve: r.user,
+			},
+			"users": &graphqllib.Field{
+				Type:        graphqllib.NewNonNull(userConnectionType),
+				Description: "Users, newest first. Admin only.",
+				Args: graphqllib.FieldConfigArgument{
+					"first":  &graphqllib.ArgumentConfig{Type: graphqllib.Int, DefaultValue: defaultPageSize},
+					"after":  &graphqllib.ArgumentConfig{Type: graphqllib.String},
+					"filter": &graphqllib.ArgumentConfig{Type: userFilterType},
+				},
+				Resolve: r.users,
+			},
+		},
+	})
+
+	mutation := graphqllib.NewObject(graphqllib.ObjectConfig{
+		Name: "Mutation",
+		Fields: graphqllib.Fields{
+			"updateUser": &graphqllib.Field{
+				Type:        graphqllib.NewNonNull(userType),
+				Description: "Update a user. Limited to the user themselves and admins.",
+				Args: graphqllib.FieldConfigArgument{
+					"id":              &graphqllib.ArgumentConfig{Type: graphqllib.NewNonNull(graphqllib.ID)},
+					"input":           &graphqllib.ArgumentConfig{Type: graphqllib.NewNonNull(updateUserInputType)},
+					"expectedVersion": &graphqllib.ArgumentConfig{Type: graphqllib.Int},
+				},
+				Resolve: r.updateUser,
+			},
+			"deleteUser": &graphqllib.Field{
+				Type:        graphqllib.NewNonNull(graphqllib.Boolean),
+				Description: "Delete a user. Limited to the user themselves and admins.",
+				Args: graphqllib.FieldConfigArgument{
+					"id": &graphqllib.ArgumentConfig{Type: graphqllib.NewNonNull(graphqllib.ID)},
+				},
+				Resolve: r.deleteUser,
+			},
+		},
+	})
+
+	return graphqllib.NewSchema(graphqllib.SchemaConfig{
+		Query:    query,
+		Mutation: mutation,
+	})
+}
+
+func userField(fieldType graphqllib.Output, value func(u *entities.UserResponse) interface{}) *graphqllib.Field {
+	return &graphqllib.Field{
+		Type: graphqllib.NewNonNull(fieldType),
+		Resolve: func(p graphqllib.ResolveParams) (interface{}, error) {
+			return value(p.Source.(*entities.UserResponse)), nil
+		},
+	}
+}
+
+func avatarVariants(u *entities.UserResponse) interface{} {
+	variants := make([]map[string]interface{}, 0, len(u.AvatarVariants))
+	for size, url := range u.AvatarVariants {
+		n, _ := strconv.Atoi(size)
+		variants = append(variants, map[string]interface{}{"size": n, "url": url})
+	}
+	sort.Slice(variants, func(i, j int) bool {
+		return variants[i]["size"].(int) > variants[j]["size"].(int)
+	})
+	return variants
+}
+
+func (r *resolver) viewer(p graphqllib.ResolveParams) (interface{}, error) {
+	viewer, err := requireViewer(p.Context)
+	if err != nil {
+		return nil, err
+	}
+	return r.loadUser(p, viewer.UserID, true), nil
+}
+
+func (r *resolver) user(p graphqllib.ResolveParams) (interface{}, error) {
+	if _, err := requireViewer(p.Context); err != nil {
+		return nil, err
+	}
+
+	id, _ := p.Args["id"].(string)
+	if _, err := primitive.ObjectIDFromHex(id); err != nil {
+		return nil, toError(errors.ErrInvalidUserID)
+	}
+	return r.loadUser(p, id, false), nil
+}
+
+// loadUser queues id on the request's user loader, so every user looked up
+// in the same query is fetched in one batch
+func (r *resolver) loadUser(p graphqllib.ResolveParams, id string, required bool) func() (interface{}, error) {
+	thunk := requestFrom(p.Context).users.Load(p.Context, id)
+	return func() (interface{}, error) {
+		value, err := thunk()
+		if err != nil {
+			return nil, toError(err)
+		}
+		if value == nil {
+			if required {
+				return nil, toError(errors.ErrUserNotFound)
+			}
+			return nil, nil
+		}
+		return value, nil
+	}
+}
+
+func (r *resolver) users(p graphqllib.ResolveParams) (interface{}, error) {
+	viewer, err := requireViewer(p.Context)
+	if err != nil {
+		return nil, err
+	}
+	if !viewer.IsAdmin() {
+		return nil, newError("FORBIDDEN", "Admin access required")
+	}
+
+	limit, _ := p.Args["first"].(int)
+	if limit > maxPageSize {
+		limit = maxPageSize
+	}
+	if limit <= 0 {
+		limit = defaultPageSize
+	}
+
+	offset := 0
+	if after, ok := p.Args["after"].(string); ok {
+		offset, err = decodeCursor(after)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	filter, err := parseUserFilter(p.Args["filter"])
+	if err != nil {
+		return nil, err
+	}
+
+	// Fetch one more than requested to learn whether there is a next page
+	users, err := r.userService.GetAllUsers(p.Context, filter, limit+1, offset)
+	if err != nil {
+		return nil, toError(err)
+	}
+
+	hasNextPage := len(users) > limit
+	if hasNextPage {
+		users = users[:limit]
+	}
+
+	edges := make([]map[string]interface{}, 0, len(users))
+	var endCursor interface{}
+	for i, user := range users {
+		cursor := encodeCursor(offset + i + 1)
+		edges = append(edges, map[string]interface{}{"cursor": cursor, "node": user})
+		endCursor = cursor
+	}
+
+	return map[string]interface{}{
+		"edges": edges,
+		"pageInfo": map[string]interface{}{
+			"hasNextPage": hasNextPage,
+			"endCursor":   endCursor,
+		},
+	}, nil
+}
+
+func (r *resolver) updateUser(p graphqllib.ResolveParams) (interface{}, error) {
+	id, _ := p.Args["id"].(string)
+	viewer, err := requireSelfOrAdmin(p, id, "You can only update your own profile")
+	if err != nil {
+		return nil, err
+	}
+
+	input, _ := p.Args["input"].(map[string]interface{})
+	req := entities.UpdateUserRequest{
+		FirstName: optionalString(input, "firstName"),
+		LastName:  optionalString(input, "lastName"),
+		Username:  optionalString(input, "username"),
+		Viewer:    viewer,
+	}
+	if value, ok := input["attributes"]; ok {
+		attributes, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, newError("BAD_USER_INPUT", "attributes must be an object")
+		}
+		req.Attributes = attributes
+	}
+	if version, ok := p.Args["expectedVersion"].(int); ok {
+		expected := int64(version)
+		req.ExpectedVersion = &expected
+	}
+
+	if err := r.validator.Validate(&req); err != nil {
+		return nil, toError(err)
+	}
+
+	user, err := r.userService.UpdateUser(p.Context, id, &req)
+	if err != nil {
+		return nil, toError(err)
+	}
+	return user, nil
+}
+
+func (r *resolver) deleteUser(p graphqllib.ResolveParams) (interface{}, error) {
+	id, _ := p.Args["id"].(string)
+	if _, err := requireSelfOrAdmin(p, id, "You can only delete your own profile"); err != nil {
+		return nil, err
+	}
+
+	if err := r.userService.DeleteUser(p.Context, id); err != nil {
+		return nil, toError(err)
+	}
+	return true, nil
+}
+
+func requireSelfOrAdmin(p graphqllib.ResolveParams, id, message string) (entities.Viewer, error) {
+	viewer, err := requireViewer(p.Context)
+	if err != nil {
+		return viewer, err
+	}
+	if viewer.UserID != id && !viewer.IsAdmin() {
+		return viewer, newError("FORBIDDEN", message)
+	}
+	return viewer, nil
+}
+
+func parseUserFilter(value interface{}) (entities.UserFilter, error) {
+	var filter entities.UserFilter
+	args, _ := value.(map[string]interface{})
+
+	if role, ok := args["role"].(string); ok {
+		if role != string(entities.RoleUser) && role != string(entities.RoleAdmin) {
+			return filter, newError("BAD_USER_INPUT", "role must be user or admin")
+		}
+		filter.Role = role
+	}
+	if isActive, ok := args["isActive"].(bool); ok {
+		filter.IsActive = &isActive
+	}
+	if createdAfter, ok := args["createdAfter"].(time.Time); ok {
+		filter.CreatedAfter = &createdAfter
+	}
+	if createdBefore, ok := args["createdBefore"].(time.Time); ok {
+		filter.CreatedBefore = &createdBefore
+	}
+
+	if value, ok := args["attributes"]; ok {
+		attributes, ok := value.(map[string]interface{})
+		if !ok {
+			return filter, newError("BAD_USER_INPUT", "attributes must be an object")
+		}
+		filter.Attributes = make(map[string]string, len(attributes))
+		for name, value := range attributes {
+			if !validator.IsIdentifier(name) {
+				return filter, newError("BAD_USER_INPUT", name+" is not a valid attribute name")
+			}
+			switch v := value.(type) {
+			case string:
+				filter.Attributes[name] = v
+			case bool:
+				filter.Attributes[name] = strconv.FormatBool(v)
+			case float64:
+				filter.Attributes[name] = strconv.FormatFloat(v, 'f', -1, 64)
+			default:
+				return filter, newError("BAD_USER_INPUT", name+" must be a string, number or boolean")
+			}
+		}
+	}
+
+	return filter, nil
+}
+
+func optionalString(values map[string]interface{}, key string) *string {
+	if value, ok := values[key].(string); ok {
+		return &value
+	}
+	return nil
+}
+
+// Cursors are opaque to clients. They hold the offset of the next page.
+const cursorPrefix = "offset:"
+
+func encodeCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(cursorPrefix + strconv.Itoa(offset)))
+}
+
+func decodeCursor(cursor string) (int, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err == nil {
+		if value, ok := strings.CutPrefix(string(decoded), cursorPrefix); ok {
+			if offset, err := strconv.Atoi(value); err == nil && offset >= 0 {
+				return offset, nil
+			}
+		}
+	}
+	return 0, newError("BAD_USER_INPUT", "invalid cursor")
+}
@@ -0,0 +1,62 @@
+package grpc
+
+import (
+	"time"
+
+	userv1 "github.com/kaa-dan/clean-architecture-go/api/proto/user/v1"
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/entities"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func toProtoUser(user *entities.UserResponse) *userv1.User {
+	return &userv1.User{
+		Id:             user.ID,
+		Email:          user.Email,
+		Username:       user.Username,
+		FirstName:      user.FirstName,
+		LastName:       user.LastName,
+		IsActive:       user.IsActive,
+		Role:           user.Role,
+		Version:        user.Version,
+		CreatedAt:      timestamppb.New(user.CreatedAt),
+		UpdatedAt:      timestamppb.New(user.UpdatedAt),
+		AvatarUrl:      user.AvatarURL,
+		AvatarVariants: user.AvatarVariants,
+		Attributes:     toProtoStruct(user.Attributes),
+	}
+}
+
+// toProtoStruct converts attribute values to a Struct. Attribute values are
+// strings, numbers and booleans, which all have a protobuf counterpart.
+func toProtoStruct(values map[string]interface{}) *structpb.Struct {
+	if len(values) == 0 {
+		return nil
+	}
+	fields := make(map[string]*structpb.Value, len(values))
+	for name, value := range values {
+		converted, err := structpb.NewValue(value)
+		if err != nil {
+			continue
+		}
+		fields[name] = converted
+	}
+	return &structpb.Struct{Fields: fields}
+}
+
+// fromProtoStruct converts a Struct to the map the JSON API decodes into,
+// so numbers arrive as float64 and null as nil
+func fromProtoStruct(values *structpb.Struct) map[string]interface{} {
+	if values == nil {
+		return nil
+	}
+	return values.AsMap()
+}
+
+func fromProtoTime(ts *timestamppb.Timestamp) *time.Time {
+	if ts == nil {
+		return nil
+	}
+	t := ts.AsTime()
+	return &t
+}
@@ -0,0 +1,44 @@
+package grpc
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/kaa-dan/clean-architecture-go/pkg/errors"
+	"github.com/kaa-dan/clean-architecture-go/pkg/logger"
+	"github.com/kaa-dan/clean-architecture-go/pkg/validator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// toStatus maps the pkg/errors sentinels to gRPC status codes. It follows
+// the HTTP status each sentinel has in the REST API, so both interfaces
+// report a failure the same way. Unknown errors are logged and hidden.
+func toStatus(err error) error {
+	if validator.IsValidationError(err) {
+		return status.Error(codes.InvalidArgument, strings.Join(validator.ErrorMessages(err), "; "))
+	}
+
+	description := errors.Describe(err)
+
+	var code codes.Code
+	switch description.Status {
+	case http.StatusBadRequest, http.StatusUnsupportedMediaType, http.StatusRequestEntityTooLarge,
+		http.StatusUnprocessableEntity:
+		code = codes.InvalidArgument
+	case http.StatusUnauthorized:
+		code = codes.Unauthenticated
+	case http.StatusForbidden:
+		code = codes.PermissionDenied
+	case http.StatusNotFound:
+		code = codes.NotFound
+	case http.StatusConflict:
+		code = codes.AlreadyExists
+	case http.StatusPreconditionFailed:
+		code = codes.FailedPrecondition
+	default:
+		logger.Errorf("grpc request failed: %v", err)
+		return status.Error(codes.Internal, errors.ErrInternalServer.Error())
+	}
+	return status.Error(code, description.Message)
+}
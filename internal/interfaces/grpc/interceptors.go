@@ -0,0 +1,91 @@
+package grpc
+
+import (
+	"context"
+	"runtime/debug"
+	"strings"
+
+	userv1 "github.com/kaa-dan/clean-architecture-go/api/proto/user/v1"
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/entities"
+	"github.com/kaa-dan/clean-architecture-go/internal/infrastructure/security"
+	"github.com/kaa-dan/clean-architecture-go/pkg/logger"
+	grpclib "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type viewerKey struct{}
+
+// publicMethods can be called without a token. Methods of other services,
+// such as health checks and reflection, are public as well.
+var publicMethods = map[string]bool{
+	userv1.UserService_SignUp_FullMethodName: true,
+	userv1.UserService_SignIn_FullMethodName: true,
+}
+
+// adminMethods are limited to admins, like the /admin REST routes
+var adminMethods = map[string]bool{
+	userv1.UserService_ListUsers_FullMethodName: true,
+}
+
+// authInterceptor validates the bearer token in the "authorization" metadata
+// with the same JWTManager as the REST API and stores the caller as a
+// Viewer in the context.
+func authInterceptor(jwtManager *security.JWTManager) grpclib.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpclib.UnaryServerInfo, handler grpclib.UnaryHandler) (interface{}, error) {
+		if publicMethods[info.FullMethod] || !strings.HasPrefix(info.FullMethod, "/"+userv1.UserService_ServiceDesc.ServiceName+"/") {
+			return handler(ctx, req)
+		}
+
+		md, _ := metadata.FromIncomingContext(ctx)
+		values := md.Get("authorization")
+		if len(values) == 0 {
+			return nil, status.Error(codes.Unauthenticated, "authorization metadata required")
+		}
+
+		token, ok := strings.CutPrefix(values[0], "Bearer ")
+		if !ok {
+			return nil, status.Error(codes.Unauthenticated, "invalid authorization metadata format")
+		}
+
+		claims, err := jwtManager.ValidateToken(token)
+		if err != nil {
+			return nil, status.Error(codes.Unauthenticated, "invalid token")
+		}
+
+		if adminMethods[info.FullMethod] && claims.Role != string(entities.RoleAdmin) {
+			return nil, status.Error(codes.PermissionDenied, "admin access required")
+		}
+
+		viewer := entities.Viewer{UserID: claims.UserID, Role: claims.Role}
+		return handler(context.WithValue(ctx, viewerKey{}, viewer), req)
+	}
+}
+
+// recoveryInterceptor turns a panic in a handler into an Internal error
+// instead of taking the whole server down, like gin.Recovery does.
+func recoveryInterceptor(ctx context.Context, req interface{}, info *grpclib.UnaryServerInfo, handler grpclib.UnaryHandler) (resp interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Errorf("panic in %s: %v\n%s", info.FullMethod, r, debug.Stack())
+			err = status.Error(codes.Internal, "internal server error")
+		}
+	}()
+	return handler(ctx, req)
+}
+
+func streamRecoveryInterceptor(srv interface{}, stream grpclib.ServerStream, info *grpclib.StreamServerInfo, handler grpclib.StreamHandler) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Errorf("panic in %s: %v\n%s", info.FullMethod, r, debug.Stack())
+			err = status.Error(codes.Internal, "internal server error")
+		}
+	}()
+	return handler(srv, stream)
+}
+
+func viewerFromContext(ctx context.Context) entities.Viewer {
+	viewer, _ := ctx.Value(viewerKey{}).(entities.Viewer)
+	return viewer
+}
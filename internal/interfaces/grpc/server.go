@@ -0,0 +1,63 @@
+// Package grpc serves the user API over gRPC, next to the REST interface.
+package grpc
+
+import (
+	"context"
+	"net"
+
+	userv1 "github.com/kaa-dan/clean-architecture-go/api/proto/user/v1"
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/services"
+	"github.com/kaa-dan/clean-architecture-go/internal/infrastructure/security"
+	grpclib "google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+)
+
+// Server is a gRPC server with the user service, the standard health
+// service and server reflection registered
+type Server struct {
+	server *grpclib.Server
+	health *health.Server
+}
+
+func NewServer(userService services.UserService, jwtManager *security.JWTManager) *Server {
+	server := grpclib.NewServer(
+		grpclib.ChainUnaryInterceptor(recoveryInterceptor, authInterceptor(jwtManager)),
+		grpclib.ChainStreamInterceptor(streamRecoveryInterceptor),
+	)
+
+	userv1.RegisterUserServiceServer(server, NewUserServer(userService))
+
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+	healthServer.SetServingStatus(userv1.UserService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(server, healthServer)
+
+	reflection.Register(server)
+
+	return &Server{server: server, health: healthServer}
+}
+
+// Serve accepts connections on lis until the server is shut down
+func (s *Server) Serve(lis net.Listener) error {
+	return s.server.Serve(lis)
+}
+
+// Shutdown reports NOT_SERVING to health checks and waits for running calls
+// to finish. Calls still running when ctx is done are cancelled.
+func (s *Server) Shutdown(ctx context.Context) {
+	s.health.Shutdown()
+
+	done := make(chan struct{})
+	go func() {
+		s.server.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		s.server.Stop()
+	}
+}
@@ -0,0 +1,181 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+
+	userv1 "github.com/kaa-dan/clean-architecture-go/api/proto/user/v1"
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/entities"
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/services"
+	"github.com/kaa-dan/clean-architecture-go/pkg/validator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// UserServer serves services.UserService over gRPC. It applies the same
+// validation and access rules as the REST handlers.
+type UserServer struct {
+	userv1.UnimplementedUserServiceServer
+
+	userService services.UserService
+	validator   *validator.Validator
+}
+
+func NewUserServer(userService services.UserService) *UserServer {
+	return &UserServer{
+		userService: userService,
+		validator:   validator.New(),
+	}
+}
+
+func (s *UserServer) SignUp(ctx context.Context, req *userv1.SignUpRequest) (*userv1.AuthResponse, error) {
+	signUp := entities.SignUpRequest{
+		Email:      req.GetEmail(),
+		Username:   req.GetUsername(),
+		Password:   req.GetPassword(),
+		FirstName:  req.GetFirstName(),
+		LastName:   req.GetLastName(),
+		Attributes: fromProtoStruct(req.GetAttributes()),
+	}
+	if err := s.validator.Validate(&signUp); err != nil {
+		return nil, toStatus(err)
+	}
+
+	result, err := s.userService.SignUp(ctx, &signUp)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toProtoAuthResponse(result), nil
+}
+
+func (s *UserServer) SignIn(ctx context.Context, req *userv1.SignInRequest) (*userv1.AuthResponse, error) {
+	signIn := entities.SignInRequest{
+		Email:    req.GetEmail(),
+		Password: req.GetPassword(),
+	}
+	if err := s.validator.Validate(&signIn); err != nil {
+		return nil, toStatus(err)
+	}
+
+	result, err := s.userService.SignIn(ctx, &signIn)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toProtoAuthResponse(result), nil
+}
+
+func (s *UserServer) GetUser(ctx context.Context, req *userv1.GetUserRequest) (*userv1.User, error) {
+	viewer := viewerFromContext(ctx)
+	id := req.GetId()
+	if id == "" {
+		id = viewer.UserID
+	}
+
+	user, err := s.userService.GetUserByID(ctx, id, viewer)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toProtoUser(user), nil
+}
+
+func (s *UserServer) ListUsers(ctx context.Context, req *userv1.ListUsersRequest) (*userv1.ListUsersResponse, error) {
+	limit := int(req.GetLimit())
+	offset := int(req.GetOffset())
+	if limit > 100 {
+		limit = 100
+	}
+	if limit <= 0 {
+		limit = 10
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	filter := entities.UserFilter{
+		Role:          req.GetRole(),
+		IsActive:      req.IsActive,
+		CreatedAfter:  fromProtoTime(req.GetCreatedAfter()),
+		CreatedBefore: fromProtoTime(req.GetCreatedBefore()),
+	}
+	if filter.Role != "" && filter.Role != string(entities.RoleUser) && filter.Role != string(entities.RoleAdmin) {
+		return nil, status.Error(codes.InvalidArgument, "role must be user or admin")
+	}
+	for name, value := range req.GetAttributes() {
+		if !validator.IsIdentifier(name) {
+			return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("%s is not a valid attribute name", name))
+		}
+		if filter.Attributes == nil {
+			filter.Attributes = make(map[string]string)
+		}
+		filter.Attributes[name] = value
+	}
+
+	users, err := s.userService.GetAllUsers(ctx, filter, limit, offset)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	resp := &userv1.ListUsersResponse{
+		Users:  make([]*userv1.User, 0, len(users)),
+		Limit:  int32(limit),
+		Offset: int32(offset),
+	}
+	for _, user := range users {
+		resp.Users = append(resp.Users, toProtoUser(user))
+	}
+	return resp, nil
+}
+
+func (s *UserServer) UpdateUser(ctx context.Context, req *userv1.UpdateUserRequest) (*userv1.User, error) {
+	viewer := viewerFromContext(ctx)
+	if err := requireSelfOrAdmin(viewer, req.GetId(), "You can only update your own profile"); err != nil {
+		return nil, err
+	}
+
+	update := entities.UpdateUserRequest{
+		FirstName:       req.FirstName,
+		LastName:        req.LastName,
+		Username:        req.Username,
+		Attributes:      fromProtoStruct(req.GetAttributes()),
+		ExpectedVersion: req.ExpectedVersion,
+		Viewer:          viewer,
+	}
+	if err := s.validator.Validate(&update); err != nil {
+		return nil, toStatus(err)
+	}
+
+	user, err := s.userService.UpdateUser(ctx, req.GetId(), &update)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toProtoUser(user), nil
+}
+
+func (s *UserServer) DeleteUser(ctx context.Context, req *userv1.DeleteUserRequest) (*userv1.DeleteUserResponse, error) {
+	if err := requireSelfOrAdmin(viewerFromContext(ctx), req.GetId(), "You can only delete your own profile"); err != nil {
+		return nil, err
+	}
+
+	if err := s.userService.DeleteUser(ctx, req.GetId()); err != nil {
+		return nil, toStatus(err)
+	}
+	return &userv1.DeleteUserResponse{}, nil
+}
+
+func requireSelfOrAdmin(viewer entities.Viewer, id, message string) error {
+	if id == "" {
+		return status.Error(codes.InvalidArgument, "User ID is required")
+	}
+	if viewer.UserID != id && !viewer.IsAdmin() {
+		return status.Error(codes.PermissionDenied, message)
+	}
+	return nil
+}
+
+func toProtoAuthResponse(result *entities.AuthResponse) *userv1.AuthResponse {
+	user := result.User.ToResponse()
+	return &userv1.AuthResponse{
+		Token: result.Token,
+		User:  toProtoUser(&user),
+	}
+}
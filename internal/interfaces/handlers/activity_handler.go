@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/entities"
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/services"
+	"github.com/kaa-dan/clean-architecture-go/pkg/logger"
+)
+
+const (
+	activityHeartbeatInterval = 15 * time.Second
+	activityRetryMillis       = 3000
+)
+
+type ActivityHandler struct {
+	activityFeed services.ActivityFeed
+}
+
+func NewActivityHandler(activityFeed services.ActivityFeed) *ActivityHandler {
+	return &ActivityHandler{
+		activityFeed: activityFeed,
+	}
+}
+
+// Stream sends user lifecycle events as Server-Sent Events. Admins receive
+// every event, other users only their own. A reconnecting client gets the
+// events it missed from Last-Event-ID, or a "gap" event when they are gone.
+func (h *ActivityHandler) Stream(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	role, _ := c.Get("user_role")
+
+	var types map[entities.ActivityType]bool
+	if param := c.Query("types"); param != "" {
+		types = make(map[entities.ActivityType]bool)
+		for _, name := range strings.Split(param, ",") {
+			types[entities.ActivityType(strings.TrimSpace(name))] = true
+		}
+	}
+
+	filter := func(event *entities.ActivityEvent) bool {
+		if role != string(entities.RoleAdmin) && event.UserID != userID {
+			return false
+		}
+		return types == nil || types[event.Type]
+	}
+
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("last_event_id")
+	}
+
+	subscription := h.activityFeed.Subscribe(lastEventID, filter)
+	defer subscription.Cancel()
+
+	// The stream stays open far beyond the server write timeout
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil {
+		logger.Warnf("failed to clear write deadline for activity stream: %v", err)
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	c.Render(-1, sse.Event{Event: "ready", Retry: activityRetryMillis, Data: gin.H{"replayed": len(subscription.Replay)}})
+	if subscription.Gap {
+		c.Render(-1, sse.Event{Event: "gap", Data: gin.H{"message": "some events are no longer available, reload the users list"}})
+	}
+	for _, event := range subscription.Replay {
+		c.Render(-1, sse.Event{Id: event.ID, Event: string(event.Type), Data: event})
+	}
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(activityHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case event, ok := <-subscription.Events:
+			if !ok {
+				// Dropped for falling behind, the client reconnects and replays
+				return
+			}
+			c.Render(-1, sse.Event{Id: event.ID, Event: string(event.Type), Data: event})
+			c.Writer.Flush()
+		case <-heartbeat.C:
+			if _, err := c.Writer.WriteString(": heartbeat\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/entities"
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/services"
+	"github.com/kaa-dan/clean-architecture-go/pkg/response"
+	"github.com/kaa-dan/clean-architecture-go/pkg/validator"
+)
+
+type AttributeHandler struct {
+	attributeService services.AttributeService
+	validator        *validator.Validator
+}
+
+func NewAttributeHandler(attributeService services.AttributeService) *AttributeHandler {
+	return &AttributeHandler{
+		attributeService: attributeService,
+		validator:        validator.New(),
+	}
+}
+
+func (h *AttributeHandler) CreateSchema(c *gin.Context) {
+	var req entities.CreateAttributeSchemaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := h.validator.Validate(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+
+	schema, err := h.attributeService.CreateSchema(c.Request.Context(), &req)
+	if err != nil {
+		response.HandleError(c, err)
+		return
+	}
+
+	response.Success(c, http.StatusCreated, schema)
+}
+
+func (h *AttributeHandler) GetSchema(c *gin.Context) {
+	schema, err := h.attributeService.GetSchema(c.Request.Context(), c.Param("name"))
+	if err != nil {
+		response.HandleError(c, err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, schema)
+}
+
+// ListSchemas returns every schema to admins, and the schemas of the
+// attributes they can see on their own profile to other users.
+func (h *AttributeHandler) ListSchemas(c *gin.Context) {
+	schemas, err := h.attributeService.ListSchemas(c.Request.Context(), currentViewer(c))
+	if err != nil {
+		response.HandleError(c, err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, gin.H{"attributes": schemas})
+}
+
+func (h *AttributeHandler) UpdateSchema(c *gin.Context) {
+	var req entities.UpdateAttributeSchemaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := h.validator.Validate(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+
+	schema, err := h.attributeService.UpdateSchema(c.Request.Context(), c.Param("name"), &req)
+	if err != nil {
+		response.HandleError(c, err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, schema)
+}
+
+func (h *AttributeHandler) DeleteSchema(c *gin.Context) {
+	if err := h.attributeService.DeleteSchema(c.Request.Context(), c.Param("name")); err != nil {
+		response.HandleError(c, err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, gin.H{"message": "Attribute deleted successfully"})
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	stderrors "errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/services"
+	"github.com/kaa-dan/clean-architecture-go/pkg/errors"
+	"github.com/kaa-dan/clean-architecture-go/pkg/response"
+)
+
+// multipartOverhead leaves room for the boundaries and part headers around
+// the file itself.
+const multipartOverhead = 64 << 10
+
+type AvatarHandler struct {
+	avatarService services.AvatarService
+	maxBytes      int64
+}
+
+func NewAvatarHandler(avatarService services.AvatarService, maxBytes int64) *AvatarHandler {
+	return &AvatarHandler{
+		avatarService: avatarService,
+		maxBytes:      maxBytes,
+	}
+}
+
+// UploadAvatar accepts the image as a multipart upload in the "avatar" field.
+// The declared content type and filename are ignored, the image type is
+// sniffed from its bytes.
+func (h *AvatarHandler) UploadAvatar(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.maxBytes+multipartOverhead)
+
+	file, _, err := c.Request.FormFile("avatar")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if stderrors.As(err, &maxBytesErr) {
+			response.HandleError(c, errors.ErrFileTooLarge)
+			return
+		}
+		response.Error(c, http.StatusBadRequest, "Avatar file is required")
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, h.maxBytes+1))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "Failed to read avatar file")
+		return
+	}
+
+	user, err := h.avatarService.UploadAvatar(c.Request.Context(), userID.(string), data)
+	if err != nil {
+		response.HandleError(c, err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, user)
+}
+
+func (h *AvatarHandler) DeleteAvatar(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	user, err := h.avatarService.DeleteAvatar(c.Request.Context(), userID.(string))
+	if err != nil {
+		response.HandleError(c, err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, user)
+}
@@ -0,0 +1,292 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-contrib/requestid"
+	"github.com/gin-gonic/gin"
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/entities"
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/repositories"
+	"github.com/kaa-dan/clean-architecture-go/pkg/codec"
+	"github.com/kaa-dan/clean-architecture-go/pkg/errors"
+	"github.com/kaa-dan/clean-architecture-go/pkg/response"
+	"github.com/kaa-dan/clean-architecture-go/pkg/validator"
+)
+
+// batchKey marks the context of requests run by a batch, which may not
+// start batches of their own
+type batchKey struct{}
+
+// errBatchItemFailed rolls back an atomic batch
+var errBatchItemFailed = stderrors.New("batch item failed")
+
+// Request headers the batch sets itself. Idempotency keys apply to the batch
+// as a whole.
+var batchRequestHeaders = map[string]bool{
+	"Authorization":   true,
+	"Cookie":          true,
+	"Host":            true,
+	"Content-Length":  true,
+	"Accept-Encoding": true,
+	"Idempotency-Key": true,
+}
+
+// Response headers that describe the transfer rather than the response
+var batchResponseHeaders = map[string]bool{
+	"Content-Length":   true,
+	"Content-Encoding": true,
+	"Vary":             true,
+}
+
+// BatchHandler runs the requests of a batch through the router, as the
+// caller and with the caller's credentials
+type BatchHandler struct {
+	router      http.Handler
+	txManager   repositories.TxManager
+	validator   *validator.Validator
+	maxRequests int
+	concurrency int
+}
+
+func NewBatchHandler(router http.Handler, txManager repositories.TxManager, maxRequests, concurrency int) *BatchHandler {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	return &BatchHandler{
+		router:      router,
+		txManager:   txManager,
+		validator:   validator.New(),
+		maxRequests: maxRequests,
+		concurrency: concurrency,
+	}
+}
+
+// Execute answers with the response of every request of the batch. Requests
+// run concurrently unless the batch is atomic, in which case they run one
+// after the other in a transaction that the first failure rolls back.
+func (h *BatchHandler) Execute(c *gin.Context) {
+	if c.Request.Context().Value(batchKey{}) != nil {
+		response.HandleError(c, errors.ErrNestedBatch)
+		return
+	}
+
+	var req entities.BatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := h.validator.Validate(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+	if err := h.validator.ValidateField("requests", req.Requests, "max="+strconv.Itoa(h.maxRequests)); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+	if req.Atomic && !h.txManager.Supported() {
+		response.HandleError(c, errors.ErrAtomicBatchUnsupported)
+		return
+	}
+
+	parent := newBatchParent(c)
+	result := &entities.BatchResponse{Committed: true}
+	if !req.Atomic {
+		result.Responses = h.runConcurrently(c.Request.Context(), parent, req.Requests)
+		response.Success(c, http.StatusOK, result)
+		return
+	}
+
+	err := h.txManager.WithinTransaction(c.Request.Context(), func(ctx context.Context) error {
+		// A retried transaction runs every request again
+		result.Responses = make([]entities.BatchItemResponse, 0, len(req.Requests))
+		for i, item := range req.Requests {
+			itemResponse := h.run(ctx, parent, i, item)
+			result.Responses = append(result.Responses, itemResponse)
+			if itemResponse.Status >= http.StatusBadRequest {
+				return errBatchItemFailed
+			}
+		}
+		return nil
+	})
+	switch {
+	case stderrors.Is(err, errBatchItemFailed):
+		// The requests after the failed one did not run
+		result.Committed = false
+		for i := len(result.Responses); i < len(req.Requests); i++ {
+			result.Responses = append(result.Responses, entities.BatchItemResponse{
+				ID:     batchItemID(i, req.Requests[i]),
+				Status: http.StatusFailedDependency,
+			})
+		}
+	case err != nil:
+		response.HandleError(c, err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, result)
+}
+
+func (h *BatchHandler) runConcurrently(ctx context.Context, parent batchParent, items []entities.BatchItemRequest) []entities.BatchItemResponse {
+	responses := make([]entities.BatchItemResponse, len(items))
+	slots := make(chan struct{}, h.concurrency)
+	var wg sync.WaitGroup
+	for i, item := range items {
+		slots <- struct{}{}
+		wg.Add(1)
+		go func(i int, item entities.BatchItemRequest) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			responses[i] = h.run(ctx, parent, i, item)
+		}(i, item)
+	}
+	wg.Wait()
+	return responses
+}
+
+// run sends one request of the batch through the router
+func (h *BatchHandler) run(ctx context.Context, parent batchParent, index int, item entities.BatchItemRequest) entities.BatchItemResponse {
+	id := batchItemID(index, item)
+	ctx, cancel := context.WithCancel(context.WithValue(ctx, batchKey{}, true))
+	defer cancel()
+
+	var body io.Reader = http.NoBody
+	if len(item.Body) > 0 {
+		body = bytes.NewReader(item.Body)
+	}
+	req, err := http.NewRequestWithContext(ctx, item.Method, item.Path, body)
+	if err != nil {
+		return failedBatchItem(id, http.StatusBadRequest, "Invalid path")
+	}
+	req.Host = parent.host
+	req.RemoteAddr = parent.remoteAddr
+
+	for name, value := range item.Headers {
+		name = http.CanonicalHeaderKey(name)
+		if !batchRequestHeaders[name] {
+			req.Header.Set(name, value)
+		}
+	}
+	if parent.authorization != "" {
+		req.Header.Set("Authorization", parent.authorization)
+	}
+	if req.Header.Get("Accept") == "" {
+		req.Header.Set("Accept", codec.MediaTypeJSON)
+	}
+	if req.Header.Get("Accept-Language") == "" && parent.language != "" {
+		req.Header.Set("Accept-Language", parent.language)
+	}
+	if len(item.Body) > 0 && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", codec.MediaTypeJSON)
+	}
+	if parent.requestID != "" {
+		req.Header.Set("X-Request-ID", parent.requestID+"-"+strconv.Itoa(index))
+	}
+
+	recorder := &batchRecorder{header: http.Header{}, cancel: cancel}
+	h.router.ServeHTTP(recorder, req)
+	if recorder.streamed {
+		return failedBatchItem(id, http.StatusBadRequest, "Streaming responses cannot be batched")
+	}
+	return recorder.response(id)
+}
+
+// batchParent is what the requests of a batch take over from the batch
+// request, read before they run concurrently
+type batchParent struct {
+	authorization string
+	language      string
+	requestID     string
+	host          string
+	remoteAddr    string
+}
+
+func newBatchParent(c *gin.Context) batchParent {
+	return batchParent{
+		authorization: c.GetHeader("Authorization"),
+		language:      c.GetHeader("Accept-Language"),
+		requestID:     requestid.Get(c),
+		host:          c.Request.Host,
+		remoteAddr:    c.Request.RemoteAddr,
+	}
+}
+
+func batchItemID(index int, item entities.BatchItemRequest) string {
+	if item.ID != "" {
+		return item.ID
+	}
+	return strconv.Itoa(index)
+}
+
+func failedBatchItem(id string, status int, message string) entities.BatchItemResponse {
+	body, _ := json.Marshal(gin.H{"message": message})
+	return entities.BatchItemResponse{ID: id, Status: status, Body: body}
+}
+
+// batchRecorder keeps the response to one request of a batch
+type batchRecorder struct {
+	header   http.Header
+	status   int
+	body     bytes.Buffer
+	streamed bool
+	cancel   context.CancelFunc
+}
+
+func (r *batchRecorder) Header() http.Header {
+	return r.header
+}
+
+func (r *batchRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+}
+
+func (r *batchRecorder) Write(data []byte) (int, error) {
+	r.WriteHeader(http.StatusOK)
+	return r.body.Write(data)
+}
+
+// Flush is how handlers stream, such a response would never end. The request
+// is cancelled so that the handler returns.
+func (r *batchRecorder) Flush() {
+	r.streamed = true
+	r.cancel()
+}
+
+func (r *batchRecorder) response(id string) entities.BatchItemResponse {
+	itemResponse := entities.BatchItemResponse{ID: id, Status: r.status}
+	if itemResponse.Status == 0 {
+		itemResponse.Status = http.StatusOK
+	}
+
+	for name, values := range r.header {
+		if batchResponseHeaders[name] || strings.HasPrefix(name, "Access-Control-") {
+			continue
+		}
+		if itemResponse.Headers == nil {
+			itemResponse.Headers = make(map[string]string)
+		}
+		itemResponse.Headers[name] = strings.Join(values, ", ")
+	}
+
+	if body := r.body.Bytes(); len(body) > 0 {
+		if json.Valid(body) {
+			itemResponse.Body = body
+		} else {
+			// Bodies that are not JSON are returned as strings
+			itemResponse.Body, _ = json.Marshal(string(body))
+		}
+	}
+	return itemResponse
+}
@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kaa-dan/clean-architecture-go/pkg/cache"
+	"github.com/kaa-dan/clean-architecture-go/pkg/response"
+)
+
+type CacheStatsProvider interface {
+	CacheStats() cache.Stats
+}
+
+type CacheHandler struct {
+	userCache CacheStatsProvider
+}
+
+// NewCacheHandler accepts a nil provider when caching is disabled.
+func NewCacheHandler(userCache CacheStatsProvider) *CacheHandler {
+	return &CacheHandler{
+		userCache: userCache,
+	}
+}
+
+func (h *CacheHandler) Stats(c *gin.Context) {
+	if h.userCache == nil {
+		response.Success(c, http.StatusOK, gin.H{
+			"users": gin.H{"enabled": false},
+		})
+		return
+	}
+
+	response.Success(c, http.StatusOK, gin.H{
+		"users": gin.H{
+			"enabled": true,
+			"stats":   h.userCache.CacheStats(),
+		},
+	})
+}
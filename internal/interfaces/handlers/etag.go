@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/entities"
+	"github.com/kaa-dan/clean-architecture-go/internal/interfaces/versioning"
+	"github.com/kaa-dan/clean-architecture-go/pkg/codec"
+	"github.com/kaa-dan/clean-architecture-go/pkg/response"
+)
+
+// setUserETag tags the user with its version, which changes whenever
+// UpdatedAt does, followed by a hash of the representation: the API version
+// and the encoding the Accept header picked, as the unversioned routes serve
+// whichever it asks for, and the selection of a sparse fieldset,
+// "3-1a2b3c4d".
+func setUserETag(c *gin.Context, user *entities.UserResponse) {
+	representation := "v" + strconv.Itoa(versioning.Current(c)) + " " + codec.Negotiate(c.GetHeader("Accept")).MediaType
+	if fields := response.SelectedFields(c); fields != nil {
+		representation += " " + fields.String()
+	}
+	sum := sha256.Sum256([]byte(representation))
+	tag := strconv.FormatInt(user.Version, 10) + "-" + hex.EncodeToString(sum[:4])
+	response.SetValidators(c, strconv.Quote(tag), user.UpdatedAt)
+}
+
+// parseIfMatch returns the version a client expects to update. A missing
+// header or "*" places no constraint on the version. Weak or malformed
+// entity tags can never match, so they are reported as a stale version. The
+// tags name the version they were read at before the representation hash.
+func parseIfMatch(header string) (version *int64, ok bool) {
+	header = strings.TrimSpace(header)
+	if header == "" || header == "*" {
+		return nil, true
+	}
+
+	tag := strings.TrimSpace(strings.Split(header, ",")[0])
+	unquoted, err := strconv.Unquote(tag)
+	if err != nil || !strings.HasPrefix(tag, `"`) {
+		return nil, false
+	}
+
+	unquoted, _, _ = strings.Cut(unquoted, "-")
+	parsed, err := strconv.ParseInt(unquoted, 10, 64)
+	if err != nil {
+		return nil, false
+	}
+
+	return &parsed, true
+}
@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kaa-dan/clean-architecture-go/pkg/response"
+)
+
+// userFieldAliases lets clients name the avatar the same way in every API
+// version, version 1 splits it into two members
+var userFieldAliases = map[string][]string{
+	"avatar": {"avatar_url", "avatar_variants"},
+}
+
+// selectUserFields applies the ?fields= selection to the users found at paths
+// of the response. A malformed selection is answered with 400 and false.
+func selectUserFields(c *gin.Context, paths ...string) bool {
+	fields, err := response.ParseFields(c.Query("fields"))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return false
+	}
+	for alias, names := range userFieldAliases {
+		if fields.Has(alias) {
+			for _, name := range names {
+				fields.Add(name)
+			}
+		}
+	}
+	response.SelectFields(c, fields, paths...)
+	return true
+}
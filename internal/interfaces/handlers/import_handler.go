@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/entities"
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/services"
+	"github.com/kaa-dan/clean-architecture-go/internal/infrastructure/importer"
+	"github.com/kaa-dan/clean-architecture-go/pkg/response"
+)
+
+const maxImportBodyBytes = 50 << 20
+
+type ImportHandler struct {
+	importService services.UserImportService
+}
+
+func NewImportHandler(importService services.UserImportService) *ImportHandler {
+	return &ImportHandler{
+		importService: importService,
+	}
+}
+
+// ImportUsers accepts either a multipart upload in the "file" field or the
+// raw file as the request body.
+func (h *ImportHandler) ImportUsers(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportBodyBytes)
+
+	dryRun, _ := strconv.ParseBool(c.DefaultQuery("dry_run", "false"))
+	invite, _ := strconv.ParseBool(c.DefaultQuery("invite", "false"))
+	onConflict := entities.ImportConflictMode(c.DefaultQuery("on_conflict", string(entities.ImportConflictSkip)))
+	if onConflict != entities.ImportConflictSkip && onConflict != entities.ImportConflictUpsert {
+		response.Error(c, http.StatusBadRequest, "on_conflict must be skip or upsert")
+		return
+	}
+
+	var (
+		body        io.Reader = c.Request.Body
+		filename    string
+		contentType = c.GetHeader("Content-Type")
+	)
+	if strings.HasPrefix(contentType, "multipart/form-data") {
+		file, header, err := c.Request.FormFile("file")
+		if err != nil {
+			response.Error(c, http.StatusBadRequest, "File is required")
+			return
+		}
+		defer file.Close()
+
+		body = file
+		filename = header.Filename
+		contentType = header.Header.Get("Content-Type")
+	}
+
+	format := importFormat(c.Query("format"), filename, contentType)
+	source, err := importer.NewSource(format, body)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	report, err := h.importService.ImportUsers(c.Request.Context(), source, entities.ImportOptions{
+		DryRun:          dryRun,
+		OnConflict:      onConflict,
+		SendInvitations: invite,
+	})
+	if err != nil {
+		response.HandleError(c, err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, report)
+}
+
+// importFormat prefers the explicit format parameter, then the file
+// extension, then the content type.
+func importFormat(format, filename, contentType string) string {
+	if format != "" {
+		return strings.ToLower(format)
+	}
+
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		return importer.FormatCSV
+	case ".ndjson", ".jsonl":
+		return importer.FormatNDJSON
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "text/csv":
+		return importer.FormatCSV
+	case "application/x-ndjson", "application/ndjson", "application/jsonl":
+		return importer.FormatNDJSON
+	}
+
+	return ""
+}
@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/entities"
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/services"
+	"github.com/kaa-dan/clean-architecture-go/pkg/logger"
+	"github.com/kaa-dan/clean-architecture-go/pkg/response"
+	"github.com/kaa-dan/clean-architecture-go/pkg/validator"
+)
+
+const personalDataArchiveVersion = 1
+
+type PrivacyHandler struct {
+	privacyService services.PrivacyService
+	validator      *validator.Validator
+}
+
+func NewPrivacyHandler(privacyService services.PrivacyService) *PrivacyHandler {
+	return &PrivacyHandler{
+		privacyService: privacyService,
+		validator:      validator.New(),
+	}
+}
+
+// ExportPersonalData returns everything stored about the caller, as a zip
+// archive with one JSON file per section or, with format=json, as a single
+// JSON document.
+func (h *PrivacyHandler) ExportPersonalData(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	format := c.DefaultQuery("format", "zip")
+	if format != "zip" && format != "json" {
+		response.Error(c, http.StatusBadRequest, "format must be one of: zip, json")
+		return
+	}
+
+	data, err := h.privacyService.ExportPersonalData(c.Request.Context(), userID.(string))
+	if err != nil {
+		response.HandleError(c, err)
+		return
+	}
+
+	filename := "personal-data-" + data.GeneratedAt.Format("20060102-150405")
+	c.Header("Cache-Control", "no-store")
+
+	if format == "json" {
+		c.Header("Content-Disposition", `attachment; filename="`+filename+`.json"`)
+		c.JSON(http.StatusOK, data)
+		return
+	}
+
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`.zip"`)
+	c.Status(http.StatusOK)
+	if err := writePersonalDataArchive(c.Writer, data); err != nil {
+		logger.Errorf("personal data export failed: %v", err)
+	}
+}
+
+func writePersonalDataArchive(w http.ResponseWriter, data *entities.PersonalDataExport) error {
+	archive := zip.NewWriter(w)
+
+	names := make([]string, 0, len(data.Sections))
+	for name := range data.Sections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	files := make([]string, len(names))
+	for i, name := range names {
+		files[i] = name + ".json"
+	}
+
+	manifest := gin.H{
+		"version":      personalDataArchiveVersion,
+		"generated_at": data.GeneratedAt,
+		"user_id":      data.UserID,
+		"files":        files,
+	}
+	if err := writeArchiveJSON(archive, "manifest.json", data.GeneratedAt, manifest); err != nil {
+		return err
+	}
+
+	for i, name := range names {
+		if err := writeArchiveJSON(archive, files[i], data.GeneratedAt, data.Sections[name]); err != nil {
+			return err
+		}
+	}
+
+	return archive.Close()
+}
+
+func writeArchiveJSON(archive *zip.Writer, name string, modified time.Time, value interface{}) error {
+	file, err := archive.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: modified,
+	})
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
+
+func (h *PrivacyHandler) RequestErasure(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	result, err := h.privacyService.RequestErasure(c.Request.Context(), userID.(string))
+	if err != nil {
+		response.HandleError(c, err)
+		return
+	}
+
+	response.Success(c, http.StatusAccepted, result)
+}
+
+func (h *PrivacyHandler) ConfirmErasure(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req entities.ConfirmErasureRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := h.validator.Validate(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+
+	result, err := h.privacyService.ConfirmErasure(c.Request.Context(), userID.(string), &req)
+	if err != nil {
+		response.HandleError(c, err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, result)
+}
+
+func (h *PrivacyHandler) CancelErasure(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	if err := h.privacyService.CancelErasure(c.Request.Context(), userID.(string)); err != nil {
+		response.HandleError(c, err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, gin.H{"message": "Erasure request cancelled"})
+}
+
+func (h *PrivacyHandler) GetErasureStatus(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	result, err := h.privacyService.GetErasureStatus(c.Request.Context(), userID.(string))
+	if err != nil {
+		response.HandleError(c, err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, result)
+}
+
+func (h *PrivacyHandler) ListErasureCertificates(c *gin.Context) {
+	limit, offset := parsePagination(c)
+
+	certificates, err := h.privacyService.ListErasureCertificates(c.Request.Context(), limit, offset)
+	if err != nil {
+		response.HandleError(c, err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, gin.H{
+		"certificates": certificates,
+		"limit":        limit,
+		"offset":       offset,
+	})
+}
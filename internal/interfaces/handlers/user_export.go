@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/entities"
+	"github.com/kaa-dan/clean-architecture-go/internal/interfaces/export"
+	"github.com/kaa-dan/clean-architecture-go/pkg/logger"
+	"github.com/kaa-dan/clean-architecture-go/pkg/response"
+)
+
+const exportFlushEvery = 500
+
+// ExportUsers streams every user matching the list filters straight from the
+// database cursor. The response uses chunked transfer encoding, so an error
+// after the first row can only be reported by truncating the download.
+func (h *UserHandler) ExportUsers(c *gin.Context) {
+	format := strings.ToLower(c.DefaultQuery("format", export.FormatCSV))
+
+	filter, err := parseUserFilter(c)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	columns, err := export.UserColumns(c.Query("fields"))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	writer, err := export.NewWriter(format, c.Writer)
+	if err != nil {
+		response.HandleError(c, err)
+		return
+	}
+
+	// Exports outlive the server write timeout
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil {
+		logger.Warnf("failed to clear write deadline for export: %v", err)
+	}
+
+	filename := "users-" + time.Now().UTC().Format("20060102-150405") + "." + format
+	c.Header("Content-Type", export.ContentType(format))
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Header("Cache-Control", "no-store")
+	c.Status(http.StatusOK)
+
+	names := make([]string, len(columns))
+	for i, column := range columns {
+		names[i] = column.Name
+	}
+	if err := writer.WriteHeader(names); err != nil {
+		logger.Errorf("user export failed: %v", err)
+		return
+	}
+
+	rows := 0
+	err = h.userService.ExportUsers(c.Request.Context(), filter, func(user *entities.UserResponse) error {
+		values := make([]interface{}, len(columns))
+		for i, column := range columns {
+			values[i] = column.Value(user)
+		}
+		if err := writer.WriteRow(values); err != nil {
+			return err
+		}
+
+		rows++
+		if rows%exportFlushEvery == 0 {
+			if err := writer.Flush(); err != nil {
+				return err
+			}
+			c.Writer.Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		logger.Errorf("user export failed after %d rows: %v", rows, err)
+		return
+	}
+
+	if err := writer.Close(); err != nil {
+		logger.Errorf("user export failed: %v", err)
+	}
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/entities"
+	"github.com/kaa-dan/clean-architecture-go/pkg/validator"
+)
+
+// parseUserFilter reads the list filters shared by the admin list and export
+// endpoints: role, is_active, created_after, created_before (RFC 3339), and
+// attr.<name> for custom attributes.
+func parseUserFilter(c *gin.Context) (entities.UserFilter, error) {
+	var filter entities.UserFilter
+
+	if role := c.Query("role"); role != "" {
+		if role != string(entities.RoleUser) && role != string(entities.RoleAdmin) {
+			return filter, fmt.Errorf("role must be user or admin")
+		}
+		filter.Role = role
+	}
+
+	if value := c.Query("is_active"); value != "" {
+		isActive, err := strconv.ParseBool(value)
+		if err != nil {
+			return filter, fmt.Errorf("is_active must be true or false")
+		}
+		filter.IsActive = &isActive
+	}
+
+	for param, target := range map[string]**time.Time{
+		"created_after":  &filter.CreatedAfter,
+		"created_before": &filter.CreatedBefore,
+	} {
+		value := c.Query(param)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return filter, fmt.Errorf("%s must be an RFC 3339 timestamp", param)
+		}
+		*target = &parsed
+	}
+
+	for param, values := range c.Request.URL.Query() {
+		name, ok := strings.CutPrefix(param, "attr.")
+		if !ok {
+			continue
+		}
+		if !validator.IsIdentifier(name) {
+			return filter, fmt.Errorf("%s is not a valid attribute name", name)
+		}
+		if filter.Attributes == nil {
+			filter.Attributes = make(map[string]string)
+		}
+		filter.Attributes[name] = values[0]
+	}
+
+	return filter, nil
+}
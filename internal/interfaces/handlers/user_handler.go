@@ -1,16 +1,22 @@
 package handlers
 
 import (
+	"io"
+	"mime"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/kaa-dan/clean-architecture-go/internal/domain/entities"
 	"github.com/kaa-dan/clean-architecture-go/internal/domain/services"
+	"github.com/kaa-dan/clean-architecture-go/pkg/errors"
 	"github.com/kaa-dan/clean-architecture-go/pkg/response"
 	"github.com/kaa-dan/clean-architecture-go/pkg/validator"
 )
 
+const maxPatchBodyBytes = 1 << 20
+
 type UserHandler struct {
 	userService services.UserService
 	validator   *validator.Validator
@@ -72,12 +78,20 @@ func (h *UserHandler) GetUser(c *gin.Context) {
 		return
 	}
 
-	user, err := h.userService.GetUserByID(c.Request.Context(), userID)
+	if !selectUserFields(c, "") {
+		return
+	}
+
+	user, err := h.userService.GetUserByID(c.Request.Context(), userID, currentViewer(c))
 	if err != nil {
 		response.HandleError(c, err)
 		return
 	}
 
+	setUserETag(c, user)
+	if response.NotModified(c) {
+		return
+	}
 	response.Success(c, http.StatusOK, user)
 }
 
@@ -95,19 +109,61 @@ func (h *UserHandler) GetAllUsers(c *gin.Context) {
 		offset = 0
 	}
 
-	users, err := h.userService.GetAllUsers(c.Request.Context(), limit, offset)
+	filter, err := parseUserFilter(c)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if !selectUserFields(c, "users") {
+		return
+	}
+
+	users, err := h.userService.GetAllUsers(c.Request.Context(), filter, limit, offset)
 	if err != nil {
 		response.HandleError(c, err)
 		return
 	}
 
-	response.Success(c, http.StatusOK, gin.H{
+	response.SuccessIfModified(c, http.StatusOK, gin.H{
 		"users":  users,
 		"limit":  limit,
 		"offset": offset,
 	})
 }
 
+func (h *UserHandler) SearchUsers(c *gin.Context) {
+	query := strings.TrimSpace(c.Query("q"))
+	if query == "" {
+		response.Error(c, http.StatusBadRequest, "Search query is required")
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if limit > 100 {
+		limit = 100
+	}
+	if limit <= 0 {
+		limit = 10
+	}
+
+	if !selectUserFields(c, "results.user") {
+		return
+	}
+
+	results, err := h.userService.SearchUsers(c.Request.Context(), query, limit)
+	if err != nil {
+		response.HandleError(c, err)
+		return
+	}
+
+	response.SuccessIfModified(c, http.StatusOK, gin.H{
+		"query":   query,
+		"results": results,
+		"limit":   limit,
+	})
+}
+
 func (h *UserHandler) UpdateUser(c *gin.Context) {
 	userID := c.Param("id")
 	if userID == "" {
@@ -135,12 +191,74 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 		return
 	}
 
+	expectedVersion, ok := parseIfMatch(c.GetHeader("If-Match"))
+	if !ok {
+		response.HandleError(c, errors.ErrVersionConflict)
+		return
+	}
+	req.ExpectedVersion = expectedVersion
+	req.Viewer = currentViewer(c)
+
 	user, err := h.userService.UpdateUser(c.Request.Context(), userID, &req)
 	if err != nil {
 		response.HandleError(c, err)
 		return
 	}
 
+	setUserETag(c, user)
+	response.Success(c, http.StatusOK, user)
+}
+
+func (h *UserHandler) PatchUser(c *gin.Context) {
+	userID := c.Param("id")
+	if userID == "" {
+		response.Error(c, http.StatusBadRequest, "User ID is required")
+		return
+	}
+
+	// Check if user can update this profile (self or admin)
+	currentUserID, _ := c.Get("user_id")
+	currentUserRole, _ := c.Get("user_role")
+
+	if currentUserID != userID && currentUserRole != string(entities.RoleAdmin) {
+		response.Error(c, http.StatusForbidden, "You can only update your own profile")
+		return
+	}
+
+	contentType, _, err := mime.ParseMediaType(c.GetHeader("Content-Type"))
+	if err != nil {
+		response.HandleError(c, errors.ErrUnsupportedMediaType)
+		return
+	}
+
+	document, err := io.ReadAll(io.LimitReader(c.Request.Body, maxPatchBodyBytes))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	expectedVersion, ok := parseIfMatch(c.GetHeader("If-Match"))
+	if !ok {
+		response.HandleError(c, errors.ErrVersionConflict)
+		return
+	}
+
+	user, err := h.userService.PatchUser(c.Request.Context(), userID, &entities.PatchUserRequest{
+		ContentType:     contentType,
+		Document:        document,
+		ExpectedVersion: expectedVersion,
+		Viewer:          currentViewer(c),
+	})
+	if err != nil {
+		if validator.IsValidationError(err) {
+			response.ValidationError(c, err)
+			return
+		}
+		response.HandleError(c, err)
+		return
+	}
+
+	setUserETag(c, user)
 	response.Success(c, http.StatusOK, user)
 }
 
@@ -171,11 +289,27 @@ func (h *UserHandler) DeleteUser(c *gin.Context) {
 func (h *UserHandler) GetProfile(c *gin.Context) {
 	userID, _ := c.Get("user_id")
 
-	user, err := h.userService.GetUserByID(c.Request.Context(), userID.(string))
+	if !selectUserFields(c, "") {
+		return
+	}
+
+	user, err := h.userService.GetUserByID(c.Request.Context(), userID.(string), currentViewer(c))
 	if err != nil {
 		response.HandleError(c, err)
 		return
 	}
 
+	setUserETag(c, user)
+	if response.NotModified(c) {
+		return
+	}
 	response.Success(c, http.StatusOK, user)
 }
+
+// currentViewer identifies the authenticated caller
+func currentViewer(c *gin.Context) entities.Viewer {
+	return entities.Viewer{
+		UserID: c.GetString("user_id"),
+		Role:   c.GetString("user_role"),
+	}
+}
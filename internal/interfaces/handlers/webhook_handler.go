@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/entities"
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/services"
+	"github.com/kaa-dan/clean-architecture-go/pkg/response"
+	"github.com/kaa-dan/clean-architecture-go/pkg/validator"
+)
+
+type WebhookHandler struct {
+	webhookService services.WebhookService
+	validator      *validator.Validator
+}
+
+func NewWebhookHandler(webhookService services.WebhookService) *WebhookHandler {
+	return &WebhookHandler{
+		webhookService: webhookService,
+		validator:      validator.New(),
+	}
+}
+
+func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
+	var req entities.CreateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := h.validator.Validate(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+
+	webhook, err := h.webhookService.CreateWebhook(c.Request.Context(), &req)
+	if err != nil {
+		response.HandleError(c, err)
+		return
+	}
+
+	response.Success(c, http.StatusCreated, webhook)
+}
+
+func (h *WebhookHandler) GetWebhook(c *gin.Context) {
+	webhook, err := h.webhookService.GetWebhook(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		response.HandleError(c, err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, webhook)
+}
+
+func (h *WebhookHandler) GetAllWebhooks(c *gin.Context) {
+	limit, offset := parsePagination(c)
+
+	webhooks, err := h.webhookService.GetAllWebhooks(c.Request.Context(), limit, offset)
+	if err != nil {
+		response.HandleError(c, err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, gin.H{
+		"webhooks": webhooks,
+		"limit":    limit,
+		"offset":   offset,
+	})
+}
+
+func (h *WebhookHandler) UpdateWebhook(c *gin.Context) {
+	var req entities.UpdateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := h.validator.Validate(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+
+	webhook, err := h.webhookService.UpdateWebhook(c.Request.Context(), c.Param("id"), &req)
+	if err != nil {
+		response.HandleError(c, err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, webhook)
+}
+
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	if err := h.webhookService.DeleteWebhook(c.Request.Context(), c.Param("id")); err != nil {
+		response.HandleError(c, err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, gin.H{"message": "Webhook deleted successfully"})
+}
+
+func (h *WebhookHandler) ListDeliveries(c *gin.Context) {
+	limit, offset := parsePagination(c)
+
+	status := entities.WebhookDeliveryStatus(c.Query("status"))
+	switch status {
+	case "", entities.WebhookDeliveryPending, entities.WebhookDeliverySucceeded, entities.WebhookDeliveryDead:
+	default:
+		response.Error(c, http.StatusBadRequest, "status must be one of: pending, succeeded, dead")
+		return
+	}
+
+	deliveries, err := h.webhookService.ListDeliveries(c.Request.Context(), c.Param("id"), status, limit, offset)
+	if err != nil {
+		response.HandleError(c, err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, gin.H{
+		"deliveries": deliveries,
+		"limit":      limit,
+		"offset":     offset,
+	})
+}
+
+func (h *WebhookHandler) Redeliver(c *gin.Context) {
+	delivery, err := h.webhookService.Redeliver(c.Request.Context(), c.Param("id"), c.Param("delivery_id"))
+	if err != nil {
+		response.HandleError(c, err)
+		return
+	}
+
+	response.Success(c, http.StatusAccepted, delivery)
+}
+
+// parsePagination reads limit and offset with the defaults and bounds used by
+// every list endpoint.
+func parsePagination(c *gin.Context) (int, int) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+
+	if limit > 100 {
+		limit = 100
+	}
+	if limit <= 0 {
+		limit = 10
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return limit, offset
+}
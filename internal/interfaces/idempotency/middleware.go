@@ -0,0 +1,262 @@
+// Package idempotency lets clients retry mutating requests safely. A request
+// sent with an Idempotency-Key header is executed once; retries with the same
+// key get the stored response instead of running again.
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/entities"
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/repositories"
+	"github.com/kaa-dan/clean-architecture-go/pkg/errors"
+	"github.com/kaa-dan/clean-architecture-go/pkg/logger"
+	"github.com/kaa-dan/clean-architecture-go/pkg/response"
+)
+
+const (
+	// HeaderKey is the request header that carries the key
+	HeaderKey = "Idempotency-Key"
+
+	// ReplayedHeader marks a response that was stored for an earlier request
+	ReplayedHeader = "Idempotent-Replayed"
+
+	maxKeyLength = 255
+)
+
+// Headers of a stored response that belong to the request that is answered,
+// besides the CORS headers. The body is stored before compression, which the
+// answer gets anew.
+var replayExcludedHeaders = map[string]bool{
+	"X-Request-Id":     true,
+	"Date":             true,
+	"Content-Encoding": true,
+	"Content-Length":   true,
+}
+
+type Middleware struct {
+	repo           repositories.IdempotencyRepository
+	fingerprintKey []byte
+	responseSecret []byte
+	maxBodyBytes   int64
+	window         time.Duration
+	lockTimeout    time.Duration
+}
+
+// NewMiddleware keeps responses for window. A request that has not completed
+// within lockTimeout is assumed lost and may be retried. Requests are
+// fingerprinted and responses encrypted with keys derived from secret, each
+// for its own purpose, so that a secret shared with other uses is never used
+// as it is. Bodies larger than maxBodyBytes are refused, since they are held
+// in memory.
+func NewMiddleware(repo repositories.IdempotencyRepository, secret string, maxBodyBytes int64, window, lockTimeout time.Duration) *Middleware {
+	return &Middleware{
+		repo:           repo,
+		fingerprintKey: deriveKey(secret, "fingerprint"),
+		responseSecret: deriveKey(secret, "response"),
+		maxBodyBytes:   maxBodyBytes,
+		window:         window,
+		lockTimeout:    lockTimeout,
+	}
+}
+
+// Handle applies to POST, PUT, PATCH and DELETE requests that send a key.
+// Keys are scoped to the authenticated user, so it must run after the auth
+// middleware on protected routes.
+func (m *Middleware) Handle() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(HeaderKey)
+		if key == "" || !mutating(c.Request.Method) {
+			c.Next()
+			return
+		}
+		if !validKey(key) {
+			response.HandleError(c, errors.ErrInvalidIdempotencyKey)
+			c.Abort()
+			return
+		}
+
+		body, err := m.readBody(c)
+		if err != nil {
+			response.HandleError(c, err)
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		lockToken, err := newLockToken()
+		if err != nil {
+			response.HandleError(c, err)
+			c.Abort()
+			return
+		}
+
+		now := time.Now()
+		record := &entities.IdempotencyRecord{
+			Key:         scopedKey(c, key),
+			UserID:      c.GetString("user_id"),
+			Fingerprint: m.fingerprint(c.Request, body),
+			LockToken:   lockToken,
+			LockedUntil: now.Add(m.lockTimeout),
+			CreatedAt:   now,
+			ExpiresAt:   now.Add(m.window),
+		}
+		existing, reserved, err := m.repo.Reserve(c.Request.Context(), record)
+		if err != nil {
+			response.HandleError(c, err)
+			c.Abort()
+			return
+		}
+		if !reserved {
+			m.answer(c, key, record, existing)
+			c.Abort()
+			return
+		}
+
+		m.execute(c, key, record)
+	}
+}
+
+// readBody reads the whole request body, which must fit in maxBodyBytes
+func (m *Middleware) readBody(c *gin.Context) ([]byte, error) {
+	if c.Request.ContentLength > m.maxBodyBytes {
+		return nil, errors.ErrIdempotentBodyTooLarge
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, m.maxBodyBytes))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if stderrors.As(err, &maxBytesErr) {
+			return nil, errors.ErrIdempotentBodyTooLarge
+		}
+		return nil, errors.ErrInvalidRequestBody
+	}
+	return body, nil
+}
+
+// execute runs the request and stores its response. Server errors are not
+// stored and neither are panics, the client may retry those. A request that
+// outlived its lock leaves the key to the request that took it over.
+func (m *Middleware) execute(c *gin.Context, headerKey string, record *entities.IdempotencyRecord) {
+	recorder := &recordingWriter{ResponseWriter: c.Writer}
+	c.Writer = recorder
+
+	completed := false
+	defer func() {
+		c.Writer = recorder.ResponseWriter
+		if completed {
+			return
+		}
+		// The request context may be cancelled already
+		if err := m.repo.Release(context.Background(), record.Key, record.LockToken); err != nil {
+			logger.Errorf("releasing idempotency key failed: %v", err)
+		}
+	}()
+
+	c.Next()
+
+	status := recorder.Status()
+	if status >= http.StatusInternalServerError {
+		return
+	}
+	body, err := seal(m.responseKey(c, headerKey), recorder.body.Bytes())
+	if err != nil {
+		logger.Errorf("encrypting idempotent response failed: %v", err)
+		return
+	}
+	stored := &entities.IdempotentResponse{
+		Status: status,
+		Header: recorder.Header().Clone(),
+		Body:   body,
+	}
+	owned, err := m.repo.Complete(context.Background(), record.Key, record.LockToken, stored)
+	if err != nil {
+		logger.Errorf("storing idempotent response failed: %v", err)
+		return
+	}
+	if !owned {
+		logger.Warnf("idempotency key was taken over after %s, the response was not stored", m.lockTimeout)
+	}
+	completed = true
+}
+
+// answer responds to a retry: with the stored response when the request
+// matches the one that used the key and has completed, with an error
+// otherwise
+func (m *Middleware) answer(c *gin.Context, headerKey string, record, existing *entities.IdempotencyRecord) {
+	switch {
+	case existing.Fingerprint != record.Fingerprint:
+		response.HandleError(c, errors.ErrIdempotencyKeyReused)
+	case existing.Response == nil:
+		c.Header("Retry-After", "1")
+		response.HandleError(c, errors.ErrIdempotencyRequestInProgress)
+	default:
+		body, err := open(m.responseKey(c, headerKey), existing.Response.Body)
+		if err != nil {
+			response.HandleError(c, fmt.Errorf("decrypting idempotent response: %w", err))
+			return
+		}
+		header := c.Writer.Header()
+		for name, values := range existing.Response.Header {
+			if !replayExcludedHeaders[name] && !strings.HasPrefix(name, "Access-Control-") {
+				header[name] = values
+			}
+		}
+		header.Set(ReplayedHeader, "true")
+		c.Writer.WriteHeader(existing.Response.Status)
+		c.Writer.Write(body)
+	}
+}
+
+func mutating(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// validKey allows up to 255 printable ASCII characters, such as a UUID
+func validKey(key string) bool {
+	if len(key) > maxKeyLength {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] < 0x20 || key[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// scopedKey keeps the keys of different users apart. Anonymous requests,
+// such as sign ups, share one scope.
+func scopedKey(c *gin.Context, key string) string {
+	sum := sha256.Sum256([]byte(c.GetString("user_id") + "\n" + key))
+	return hex.EncodeToString(sum[:])
+}
+
+// recordingWriter keeps a copy of the response body
+type recordingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *recordingWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *recordingWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
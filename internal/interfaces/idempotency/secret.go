@@ -0,0 +1,93 @@
+package idempotency
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fingerprint is a keyed hash of the method, URL, body and the headers that
+// decide the representation of the response. Bodies may hold passwords, the
+// key keeps stored fingerprints from being guessed offline.
+func (m *Middleware) fingerprint(r *http.Request, body []byte) string {
+	mac := hmac.New(sha256.New, m.fingerprintKey)
+	for _, part := range []string{
+		r.Method,
+		r.URL.RequestURI(),
+		r.Header.Get("Content-Type"),
+		r.Header.Get("Accept"),
+		r.Header.Get("If-Match"),
+	} {
+		io.WriteString(mac, part)
+		mac.Write([]byte{0})
+	}
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// responseKey is the key that stored responses are encrypted with. It is
+// derived from the header value, which is only stored hashed, so that the
+// records alone do not reveal the responses.
+func (m *Middleware) responseKey(c *gin.Context, headerKey string) []byte {
+	mac := hmac.New(sha256.New, m.responseSecret)
+	io.WriteString(mac, c.GetString("user_id")+"\n"+headerKey)
+	return mac.Sum(nil)
+}
+
+// deriveKey is the key for purpose, labelled so that keys derived from the
+// same secret for other purposes differ
+func deriveKey(secret, purpose string) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	io.WriteString(mac, "idempotency "+purpose)
+	return mac.Sum(nil)
+}
+
+// newLockToken tells the request holding a key from one that took it over
+func newLockToken() (string, error) {
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(token), nil
+}
+
+// seal encrypts data with AES-GCM, prefixed with the nonce
+func seal(key, data []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(data)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, data, nil), nil
+}
+
+func open(key, sealed []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("sealed response is too short")
+	}
+	nonce, data := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	return aead.Open(nil, nonce, data, nil)
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
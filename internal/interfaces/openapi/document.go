@@ -0,0 +1,355 @@
+// Package openapi builds an OpenAPI 3.1 document from route declarations
+// and the request and response types of the handlers, serves it with a docs
+// UI, and validates requests and responses against it.
+package openapi
+
+import (
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kaa-dan/clean-architecture-go/pkg/codec"
+	"github.com/kaa-dan/clean-architecture-go/pkg/response"
+)
+
+// Version is the OpenAPI version of the generated document
+const Version = "3.1.0"
+
+type Document struct {
+	OpenAPI    string                           `json:"openapi"`
+	Info       Info                             `json:"info"`
+	Paths      map[string]map[string]*Operation `json:"paths"`
+	Components Components                       `json:"components"`
+
+	// operations indexes the operations by method and gin route pattern
+	operations map[string]*Operation
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+}
+
+type Operation struct {
+	OperationID string                `json:"operationId"`
+	Summary     string                `json:"summary,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []*Parameter          `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+
+	// jsonResponse is false for operations that only send other content,
+	// such as streams and downloads, whose responses are not validated
+	jsonResponse bool
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                  `json:"required,omitempty"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string                `json:"description"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema,omitempty"`
+}
+
+// Content maps content types to body samples, for bodies that are not JSON
+// or come in several types
+type Content map[string]interface{}
+
+// Access is who may call a route
+type Access int
+
+const (
+	Public Access = iota
+	Authenticated
+	Admin
+)
+
+// Param declares a query or header parameter. Type is a JSON Schema type,
+// "string" when empty.
+type Param struct {
+	Name        string
+	In          string
+	Type        string
+	Format      string
+	Enum        []string
+	Required    bool
+	Description string
+}
+
+// Route declares an operation. Body and Response are sample values whose
+// types are turned into schemas, or *Schema values. Body is JSON unless it
+// is a Content. JSON responses are wrapped in the response envelope of
+// pkg/response; Produces lists the content types of a response that is sent
+// as is instead.
+type Route struct {
+	Method      string
+	Path        string
+	Summary     string
+	Description string
+	Tag         string
+	Access      Access
+	Params      []Param
+
+	Body interface{}
+
+	Status   int
+	Response interface{}
+	Produces []string
+	// Conditional routes answer a request whose If-None-Match or
+	// If-Modified-Since header matches with 304 Not Modified
+	Conditional bool
+
+	// Errors lists error statuses beyond those implied by Access and Body
+	Errors []int
+	// ErrorResponse replaces the error envelope of pkg/response as the body
+	// of error responses
+	ErrorResponse interface{}
+}
+
+var ginParam = regexp.MustCompile(`[:*]([A-Za-z0-9_]+)`)
+
+// Build generates the document for routes
+func Build(info Info, routes []Route) *Document {
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    info,
+		Paths:   make(map[string]map[string]*Operation),
+		Components: Components{
+			Schemas: make(map[string]*Schema),
+			SecuritySchemes: map[string]*SecurityScheme{
+				"bearerAuth": {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+			},
+		},
+		operations: make(map[string]*Operation),
+	}
+	gen := newGenerator(doc.Components.Schemas)
+
+	doc.Components.Schemas["ErrorResponse"] = &Schema{
+		Type:     Types("object"),
+		Required: []string{"success", "error", "code"},
+		Properties: map[string]*Schema{
+			"success": {Type: Types("boolean"), Const: false},
+			"error":   {Type: Types("string")},
+			"code":    {Type: Types("string"), Description: "Stable error code, described at /problems/{code}"},
+			"data":    {Description: "Details, such as one message per invalid field"},
+		},
+	}
+	gen.schema(response.Problem{})
+	doc.Components.Schemas["Problem"].Description = "RFC 9457 problem details, sent when the request accepts application/problem+json"
+
+	for _, route := range routes {
+		op := gen.operation(route)
+		path := ginParam.ReplaceAllString(route.Path, "{$1}")
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = make(map[string]*Operation)
+		}
+		doc.Paths[path][strings.ToLower(route.Method)] = op
+		doc.operations[route.Method+" "+route.Path] = op
+	}
+
+	return doc
+}
+
+// Operation returns the operation for a method and gin route pattern
+func (d *Document) Operation(method, route string) *Operation {
+	return d.operations[method+" "+route]
+}
+
+// Undocumented returns the registered routes that the document does not
+// describe, as "METHOD path"
+func (d *Document) Undocumented(routes gin.RoutesInfo) []string {
+	var missing []string
+	for _, route := range routes {
+		if d.Operation(route.Method, route.Path) == nil {
+			missing = append(missing, route.Method+" "+route.Path)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+func (g *generator) operation(route Route) *Operation {
+	op := &Operation{
+		OperationID: operationID(route.Method, route.Path),
+		Summary:     route.Summary,
+		Description: route.Description,
+		Responses:   make(map[string]*Response),
+	}
+	if route.Tag != "" {
+		op.Tags = []string{route.Tag}
+	}
+	if route.Access != Public {
+		op.Security = []map[string][]string{{"bearerAuth": {}}}
+	}
+
+	for _, match := range ginParam.FindAllStringSubmatch(route.Path, -1) {
+		op.Parameters = append(op.Parameters, &Parameter{
+			Name:     match[1],
+			In:       "path",
+			Required: true,
+			Schema:   &Schema{Type: Types("string")},
+		})
+	}
+	for _, param := range route.Params {
+		in := param.In
+		if in == "" {
+			in = "query"
+		}
+		paramType := param.Type
+		if paramType == "" {
+			paramType = "string"
+		}
+		schema := &Schema{Type: Types(paramType), Format: param.Format}
+		for _, value := range param.Enum {
+			schema.Enum = append(schema.Enum, value)
+		}
+		op.Parameters = append(op.Parameters, &Parameter{
+			Name:        param.Name,
+			In:          in,
+			Description: param.Description,
+			Required:    param.Required,
+			Schema:      schema,
+		})
+	}
+
+	errorStatuses := append([]int(nil), route.Errors...)
+	if route.Body != nil || len(route.Params) > 0 {
+		errorStatuses = append(errorStatuses, http.StatusBadRequest)
+	}
+	if route.Access != Public {
+		errorStatuses = append(errorStatuses, http.StatusUnauthorized)
+	}
+	if route.Access == Admin {
+		errorStatuses = append(errorStatuses, http.StatusForbidden)
+	}
+
+	if route.Body != nil {
+		content, ok := route.Body.(Content)
+		if !ok {
+			content = Content{
+				"application/json":      route.Body,
+				codec.MediaTypeMsgPack:  route.Body,
+				codec.MediaTypeProtobuf: protobufBody(),
+			}
+		}
+		op.RequestBody = &RequestBody{Required: true, Content: make(map[string]*MediaType)}
+		for contentType, sample := range content {
+			op.RequestBody.Content[contentType] = &MediaType{Schema: g.schema(sample)}
+		}
+	}
+
+	status := route.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	success := &Response{Description: http.StatusText(status), Content: make(map[string]*MediaType)}
+	if len(route.Produces) > 0 {
+		for _, contentType := range route.Produces {
+			success.Content[contentType] = &MediaType{Schema: g.schema(route.Response)}
+			if isJSON(contentType) {
+				op.jsonResponse = true
+			}
+		}
+	} else {
+		op.jsonResponse = true
+		envelope := g.envelope(route.Response)
+		success.Content["application/json"] = &MediaType{Schema: envelope}
+		success.Content[codec.MediaTypeMsgPack] = &MediaType{Schema: envelope}
+		success.Content[codec.MediaTypeProtobuf] = &MediaType{Schema: protobufBody()}
+	}
+	op.Responses[itoa(status)] = success
+	if route.Conditional {
+		op.Responses[itoa(http.StatusNotModified)] = &Response{Description: http.StatusText(http.StatusNotModified)}
+	}
+
+	errorContent := func() map[string]*MediaType {
+		if route.ErrorResponse != nil {
+			return map[string]*MediaType{"application/json": {Schema: g.schema(route.ErrorResponse)}}
+		}
+		return map[string]*MediaType{
+			"application/json":        {Schema: &Schema{Ref: schemaRef("ErrorResponse")}},
+			response.ProblemMediaType: {Schema: &Schema{Ref: schemaRef("Problem")}},
+		}
+	}
+	for _, status := range append(errorStatuses, http.StatusInternalServerError) {
+		op.Responses[itoa(status)] = &Response{
+			Description: http.StatusText(status),
+			Content:     errorContent(),
+		}
+	}
+
+	return op
+}
+
+// protobufBody describes a body in the Protobuf encoding of pkg/codec
+func protobufBody() *Schema {
+	return &Schema{
+		Type:        Types("string"),
+		Format:      "binary",
+		Description: "google.protobuf.Struct holding the JSON form of the body",
+	}
+}
+
+// envelope wraps the schema of data in the success envelope
+func (g *generator) envelope(data interface{}) *Schema {
+	schema := &Schema{
+		Type:     Types("object"),
+		Required: []string{"success"},
+		Properties: map[string]*Schema{
+			"success": {Type: Types("boolean"), Const: true},
+			"message": {Type: Types("string")},
+		},
+	}
+	if data != nil {
+		schema.Required = append(schema.Required, "data")
+		schema.Properties["data"] = g.schema(data)
+	}
+	return schema
+}
+
+// operationID turns "GET /api/v1/users/:id" into "getApiV1UsersId"
+func operationID(method, path string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, part := range strings.FieldsFunc(path, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9')
+	}) {
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return b.String()
+}
+
+func itoa(status int) string {
+	return strconv.Itoa(status)
+}
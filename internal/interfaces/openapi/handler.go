@@ -0,0 +1,35 @@
+package openapi
+
+import (
+	_ "embed"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	swaggerFiles "github.com/swaggo/files/v2"
+)
+
+const (
+	// SpecPath serves the document, the docs UI loads it from there
+	SpecPath = "/openapi.json"
+	// DocsPath serves the docs UI
+	DocsPath = "/docs"
+)
+
+//go:embed ui/swagger-initializer.js
+var swaggerInitializer []byte
+
+// ServeSpec responds with the document
+func (d *Document) ServeSpec(c *gin.Context) {
+	c.JSON(http.StatusOK, d)
+}
+
+// ServeDocs serves Swagger UI, bundled into the binary, for a route with a
+// *filepath parameter
+func ServeDocs(c *gin.Context) {
+	switch path := c.Param("filepath"); path {
+	case "/swagger-initializer.js":
+		c.Data(http.StatusOK, "application/javascript; charset=utf-8", swaggerInitializer)
+	default:
+		c.FileFromFS(path, http.FS(swaggerFiles.FS))
+	}
+}
@@ -0,0 +1,222 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kaa-dan/clean-architecture-go/pkg/logger"
+	"github.com/kaa-dan/clean-architecture-go/pkg/response"
+	"github.com/kaa-dan/clean-architecture-go/pkg/validator"
+)
+
+// maxValidatedBody is the largest body that is checked against the spec.
+// Larger bodies are left to the handler, which enforces its own limits.
+const maxValidatedBody = 1 << 20
+
+// ValidateRequests rejects requests whose query parameters or JSON body do
+// not match the operation in the document, with the same response as a
+// failed struct validation. Routes the document does not describe pass.
+func (d *Document) ValidateRequests() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		op := d.Operation(c.Request.Method, c.FullPath())
+		if op == nil {
+			c.Next()
+			return
+		}
+
+		problems := d.validateParams(c, op)
+
+		if op.RequestBody != nil {
+			contentType, _, _ := mime.ParseMediaType(c.GetHeader("Content-Type"))
+			if media, ok := op.RequestBody.Content[contentType]; ok && isJSON(contentType) {
+				problems = append(problems, d.validateBody(c, media.Schema)...)
+			}
+		}
+
+		if len(problems) > 0 {
+			response.ValidationError(c, problems)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+func (d *Document) validateParams(c *gin.Context, op *Operation) validator.FieldErrors {
+	var problems validator.FieldErrors
+	for _, param := range op.Parameters {
+		var (
+			raw     string
+			present bool
+		)
+		switch param.In {
+		case "query":
+			raw, present = c.GetQuery(param.Name)
+		case "header":
+			raw = c.GetHeader(param.Name)
+			present = raw != ""
+		default:
+			continue
+		}
+
+		name := param.In + " parameter " + param.Name
+		if !present {
+			if param.Required {
+				problems = append(problems, validator.FieldError{Field: param.Name, Code: "required", Message: name + " is required"})
+			}
+			continue
+		}
+		for _, problem := range d.Validate(param.Schema, paramValue(param.Schema, raw), name) {
+			problem.Field = param.Name
+			problems = append(problems, problem)
+		}
+	}
+	return problems
+}
+
+// paramValue converts a raw parameter to the JSON value its schema expects,
+// leaving it a string when it does not parse so the type check reports it
+func paramValue(schema *Schema, raw string) interface{} {
+	switch {
+	case schema.Type.has("integer"):
+		if _, err := strconv.ParseInt(raw, 10, 64); err == nil {
+			return json.Number(raw)
+		}
+	case schema.Type.has("number"):
+		if _, err := strconv.ParseFloat(raw, 64); err == nil {
+			return json.Number(raw)
+		}
+	case schema.Type.has("boolean"):
+		if b, err := strconv.ParseBool(raw); err == nil {
+			return b
+		}
+	}
+	return raw
+}
+
+func (d *Document) validateBody(c *gin.Context, schema *Schema) validator.FieldErrors {
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxValidatedBody+1))
+	// The handler reads the body again, including anything past the limit
+	c.Request.Body = readCloser{io.MultiReader(bytes.NewReader(body), c.Request.Body), c.Request.Body}
+	if err != nil || len(body) > maxValidatedBody {
+		return nil
+	}
+
+	value, err := decodeJSON(body)
+	if err != nil {
+		return validator.FieldErrors{validator.NewLocalizedFieldError("body", "json", "validation.json")}
+	}
+	return d.Validate(schema, value, "body")
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// ValidateResponses logs a warning for every JSON response that does not
+// match the document. It is meant for development, where it catches drift
+// between the handlers and the spec; responses are sent unchanged. Streams
+// and downloads are not recorded.
+func (d *Document) ValidateResponses() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		op := d.Operation(c.Request.Method, c.FullPath())
+		if op == nil || !op.jsonResponse {
+			c.Next()
+			return
+		}
+
+		recorder := &recordingWriter{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+		c.Writer = recorder.ResponseWriter
+
+		status := c.Writer.Status()
+		route := c.Request.Method + " " + c.FullPath()
+		resp, ok := op.Responses[strconv.Itoa(status)]
+		if !ok {
+			logger.Warnf("openapi: %s responded with undocumented status %d", route, status)
+			return
+		}
+
+		contentType, _, _ := mime.ParseMediaType(c.Writer.Header().Get("Content-Type"))
+		media, ok := resp.Content[contentType]
+		if !ok {
+			if len(resp.Content) > 0 {
+				logger.Warnf("openapi: %s responded %d with undocumented content type %q", route, status, contentType)
+			}
+			return
+		}
+		if !isJSON(contentType) || recorder.overflow {
+			return
+		}
+
+		value, err := decodeJSON(recorder.body.Bytes())
+		if err != nil {
+			logger.Warnf("openapi: %s responded %d with invalid JSON", route, status)
+			return
+		}
+		for _, problem := range d.Validate(media.Schema, value, "response") {
+			logger.Warnf("openapi: %s responded %d not matching the spec: %s", route, status, problem.Message)
+		}
+	}
+}
+
+// recordingWriter keeps a copy of the first maxValidatedBody bytes written
+type recordingWriter struct {
+	gin.ResponseWriter
+	body     bytes.Buffer
+	overflow bool
+}
+
+func (w *recordingWriter) Write(data []byte) (int, error) {
+	w.record(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *recordingWriter) WriteString(s string) (int, error) {
+	w.record([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Unwrap lets http.ResponseController reach the connection, so that streams
+// can lift the write deadline
+func (w *recordingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *recordingWriter) record(data []byte) {
+	if w.overflow {
+		return
+	}
+	if w.body.Len()+len(data) > maxValidatedBody {
+		w.overflow = true
+		w.body.Reset()
+		return
+	}
+	w.body.Write(data)
+}
+
+func decodeJSON(data []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	if decoder.More() {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return value, nil
+}
+
+func isJSON(contentType string) bool {
+	return contentType == "application/json" || strings.HasSuffix(contentType, "+json")
+}
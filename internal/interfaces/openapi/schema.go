@@ -0,0 +1,328 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Schema is the subset of JSON Schema 2020-12 that the generator emits and
+// the validator understands
+type Schema struct {
+	Ref         string             `json:"$ref,omitempty"`
+	Type        SchemaType         `json:"type,omitempty"`
+	Format      string             `json:"format,omitempty"`
+	Description string             `json:"description,omitempty"`
+	Enum        []interface{}      `json:"enum,omitempty"`
+	Const       interface{}        `json:"const,omitempty"`
+	Pattern     string             `json:"pattern,omitempty"`
+	MinLength   *int               `json:"minLength,omitempty"`
+	MaxLength   *int               `json:"maxLength,omitempty"`
+	Minimum     *float64           `json:"minimum,omitempty"`
+	Maximum     *float64           `json:"maximum,omitempty"`
+	MinItems    *int               `json:"minItems,omitempty"`
+	MaxItems    *int               `json:"maxItems,omitempty"`
+	Items       *Schema            `json:"items,omitempty"`
+	Properties  map[string]*Schema `json:"properties,omitempty"`
+	Required    []string           `json:"required,omitempty"`
+
+	// AdditionalProperties describes the values of a map
+	AdditionalProperties *Schema   `json:"additionalProperties,omitempty"`
+	AnyOf                []*Schema `json:"anyOf,omitempty"`
+
+	// objectSamples holds the property samples of an Object until the
+	// generator turns them into schemas
+	objectSamples map[string]interface{}
+}
+
+// SchemaType is one or more JSON types. Nullable values list "null" as a
+// second type, as OpenAPI 3.1 has no nullable keyword.
+type SchemaType []string
+
+func Types(types ...string) SchemaType {
+	return types
+}
+
+func (t SchemaType) MarshalJSON() ([]byte, error) {
+	if len(t) == 1 {
+		return json.Marshal(t[0])
+	}
+	return json.Marshal([]string(t))
+}
+
+func (t SchemaType) has(name string) bool {
+	for _, candidate := range t {
+		if candidate == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Object describes an object with the given properties, for responses that
+// are not a named type. Values are samples or *Schema, as for Route. The
+// properties are optional, and slices and maps may be null.
+func Object(properties map[string]interface{}) *Schema {
+	return &Schema{Type: Types("object"), objectSamples: properties}
+}
+
+// File describes an uploaded file in a multipart form field
+func File(field string) *Schema {
+	return &Schema{
+		Type:       Types("object"),
+		Required:   []string{field},
+		Properties: map[string]*Schema{field: {Type: Types("string"), Format: "binary"}},
+	}
+}
+
+// Binary describes a body that is not JSON, such as a file download
+func Binary() *Schema {
+	return &Schema{Type: Types("string"), Format: "binary"}
+}
+
+func schemaRef(name string) string {
+	return "#/components/schemas/" + name
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	objectIDType   = reflect.TypeOf(primitive.ObjectID{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+// generator turns Go types into schemas following encoding/json, adding the
+// constraints of their validate tags. Named structs become components.
+type generator struct {
+	schemas map[string]*Schema
+}
+
+func newGenerator(schemas map[string]*Schema) *generator {
+	return &generator{schemas: schemas}
+}
+
+func (g *generator) schema(sample interface{}) *Schema {
+	switch s := sample.(type) {
+	case nil:
+		return &Schema{}
+	case *Schema:
+		if s.objectSamples != nil {
+			return g.object(s)
+		}
+		return s
+	}
+	return g.typeSchema(reflect.TypeOf(sample))
+}
+
+func (g *generator) object(s *Schema) *Schema {
+	object := &Schema{Type: s.Type, Properties: make(map[string]*Schema)}
+	for name, sample := range s.objectSamples {
+		property := g.schema(sample)
+		switch reflect.ValueOf(sample).Kind() {
+		case reflect.Slice, reflect.Map:
+			property = nullable(property)
+		}
+		object.Properties[name] = property
+	}
+	return object
+}
+
+func (g *generator) typeSchema(t reflect.Type) *Schema {
+	switch t {
+	case timeType:
+		return &Schema{Type: Types("string"), Format: "date-time"}
+	case objectIDType:
+		return &Schema{Type: Types("string"), Pattern: "^[0-9a-f]{24}$"}
+	case rawMessageType:
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return g.typeSchema(t.Elem())
+	case reflect.String:
+		return &Schema{Type: Types("string")}
+	case reflect.Bool:
+		return &Schema{Type: Types("boolean")}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: Types("integer")}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: Types("number")}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: Types("array"), Items: g.typeSchema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: Types("object"), AdditionalProperties: g.typeSchema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		if _, ok := g.schemas[t.Name()]; !ok {
+			// Register first, so recursive types end in a reference
+			g.schemas[t.Name()] = &Schema{}
+			*g.schemas[t.Name()] = *g.structSchema(t)
+		}
+		return &Schema{Ref: schemaRef(t.Name())}
+	}
+	// interface{} and anything else accept any value
+	return &Schema{}
+}
+
+func (g *generator) structSchema(t reflect.Type) *Schema {
+	schema := &Schema{Type: Types("object"), Properties: make(map[string]*Schema)}
+	g.addFields(schema, t)
+	sort.Strings(schema.Required)
+	return schema
+}
+
+func (g *generator) addFields(schema *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, omitEmpty, skip := jsonName(field)
+		if skip {
+			continue
+		}
+
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				g.addFields(schema, embedded)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		property := g.typeSchema(field.Type)
+		required := applyValidateTag(property, field.Type, field.Tag.Get("validate"))
+
+		// Nil pointers, slices and maps are encoded as null unless omitted
+		if !omitEmpty {
+			switch field.Type.Kind() {
+			case reflect.Pointer, reflect.Slice, reflect.Map:
+				property = nullable(property)
+			}
+		}
+
+		schema.Properties[name] = property
+		if required {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+}
+
+func jsonName(field reflect.StructField) (name string, omitEmpty, skip bool) {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false, true
+	}
+	parts := strings.Split(tag, ",")
+	for _, option := range parts[1:] {
+		if option == "omitempty" {
+			omitEmpty = true
+		}
+	}
+	return parts[0], omitEmpty, false
+}
+
+// nullable allows null next to the types of schema. References cannot carry
+// a type, so they are wrapped.
+func nullable(schema *Schema) *Schema {
+	if schema.Ref != "" {
+		return &Schema{AnyOf: []*Schema{schema, {Type: Types("null")}}}
+	}
+	if len(schema.Type) > 0 && !schema.Type.has("null") {
+		schema.Type = append(schema.Type, "null")
+	}
+	return schema
+}
+
+// applyValidateTag adds the constraints of a go-playground validate tag to
+// schema and reports whether the field is required. Tags after "dive"
+// apply to the items of a slice.
+func applyValidateTag(schema *Schema, t reflect.Type, tag string) (required bool) {
+	if tag == "" {
+		return false
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	rules := strings.Split(tag, ",")
+	for i, rule := range rules {
+		name, param, _ := strings.Cut(rule, "=")
+		switch name {
+		case "required":
+			required = true
+		case "dive":
+			if schema.Items != nil && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+				applyValidateTag(schema.Items, t.Elem(), strings.Join(rules[i+1:], ","))
+			}
+			return required
+		case "min", "gte":
+			setBound(schema, t, param, true)
+		case "max", "lte":
+			setBound(schema, t, param, false)
+		case "len":
+			setBound(schema, t, param, true)
+			setBound(schema, t, param, false)
+		case "oneof":
+			for _, value := range strings.Fields(param) {
+				schema.Enum = append(schema.Enum, value)
+			}
+		case "email":
+			schema.Format = "email"
+		case "http_url", "url":
+			schema.Format = "uri"
+		case "alphanum":
+			schema.Pattern = "^[a-zA-Z0-9]*$"
+		case "identifier":
+			schema.Pattern = "^[a-z][a-z0-9_]*$"
+		case "locale":
+			schema.Format = "bcp47"
+		}
+	}
+	return required
+}
+
+func setBound(schema *Schema, t reflect.Type, param string, lower bool) {
+	n, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return
+	}
+	switch t.Kind() {
+	case reflect.String:
+		length := int(n)
+		if lower {
+			schema.MinLength = &length
+		} else {
+			schema.MaxLength = &length
+		}
+	case reflect.Map:
+		return
+	case reflect.Slice, reflect.Array:
+		count := int(n)
+		if lower {
+			schema.MinItems = &count
+		} else {
+			schema.MaxItems = &count
+		}
+	default:
+		if lower {
+			schema.Minimum = &n
+		} else {
+			schema.Maximum = &n
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/kaa-dan/clean-architecture-go/pkg/validator"
+)
+
+var patterns sync.Map
+
+// Validate checks a value decoded with json.Decoder.UseNumber against
+// schema and returns one problem per failed keyword. name is how the value
+// is called in the messages.
+func (d *Document) Validate(schema *Schema, value interface{}, name string) validator.FieldErrors {
+	var problems validator.FieldErrors
+	d.validate(schema, value, name, &problems)
+	return problems
+}
+
+func (d *Document) validate(schema *Schema, value interface{}, name string, problems *validator.FieldErrors) {
+	if schema == nil {
+		return
+	}
+	if schema.Ref != "" {
+		d.validate(d.Components.Schemas[strings.TrimPrefix(schema.Ref, schemaRef(""))], value, name, problems)
+		return
+	}
+	if len(schema.AnyOf) > 0 {
+		for _, candidate := range schema.AnyOf {
+			if len(d.Validate(candidate, value, name)) == 0 {
+				return
+			}
+		}
+		// Report against the first alternative, which is the non-null one
+		d.validate(schema.AnyOf[0], value, name, problems)
+		return
+	}
+
+	report := func(keyword, format string, args ...interface{}) {
+		*problems = append(*problems, validator.NewFieldError(name, keyword, fmt.Sprintf(format, args...)))
+	}
+
+	if len(schema.Type) > 0 && !matchesType(schema.Type, value) {
+		report("type", "must be of type %s", strings.Join(schema.Type, " or "))
+		return
+	}
+	if schema.Const != nil && value != schema.Const {
+		report("const", "must be %v", schema.Const)
+	}
+	if len(schema.Enum) > 0 && !inEnum(schema.Enum, value) {
+		var values []string
+		for _, allowed := range schema.Enum {
+			values = append(values, fmt.Sprint(allowed))
+		}
+		report("enum", "must be one of: %s", strings.Join(values, ", "))
+	}
+
+	switch v := value.(type) {
+	case string:
+		length := utf8.RuneCountInString(v)
+		if schema.MinLength != nil && length < *schema.MinLength {
+			report("minLength", "must be at least %d characters long", *schema.MinLength)
+		}
+		if schema.MaxLength != nil && length > *schema.MaxLength {
+			report("maxLength", "must be at most %d characters long", *schema.MaxLength)
+		}
+		if schema.Pattern != "" && !compiled(schema.Pattern).MatchString(v) {
+			report("pattern", "must match %s", schema.Pattern)
+		}
+		if schema.Format != "" && !matchesFormat(schema.Format, v) {
+			report("format", "must be a valid %s", schema.Format)
+		}
+	case json.Number:
+		n, _ := v.Float64()
+		if schema.Minimum != nil && n < *schema.Minimum {
+			report("minimum", "must be at least %v", *schema.Minimum)
+		}
+		if schema.Maximum != nil && n > *schema.Maximum {
+			report("maximum", "must be at most %v", *schema.Maximum)
+		}
+	case []interface{}:
+		if schema.MinItems != nil && len(v) < *schema.MinItems {
+			report("minItems", "must contain at least %d items", *schema.MinItems)
+		}
+		if schema.MaxItems != nil && len(v) > *schema.MaxItems {
+			report("maxItems", "must contain at most %d items", *schema.MaxItems)
+		}
+		for i, item := range v {
+			d.validate(schema.Items, item, fmt.Sprintf("%s[%d]", name, i), problems)
+		}
+	case map[string]interface{}:
+		for _, property := range schema.Required {
+			if _, ok := v[property]; !ok {
+				*problems = append(*problems, validator.NewFieldError(name+"."+property, "required", "is required"))
+			}
+		}
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if property, ok := schema.Properties[key]; ok {
+				d.validate(property, v[key], name+"."+key, problems)
+			} else if schema.AdditionalProperties != nil {
+				d.validate(schema.AdditionalProperties, v[key], name+"."+key, problems)
+			}
+		}
+	}
+}
+
+func matchesType(types SchemaType, value interface{}) bool {
+	for _, t := range types {
+		switch v := value.(type) {
+		case nil:
+			if t == "null" {
+				return true
+			}
+		case bool:
+			if t == "boolean" {
+				return true
+			}
+		case string:
+			if t == "string" {
+				return true
+			}
+		case json.Number:
+			if t == "number" {
+				return true
+			}
+			if t == "integer" {
+				n, err := v.Float64()
+				if err == nil && n == math.Trunc(n) {
+					return true
+				}
+			}
+		case []interface{}:
+			if t == "array" {
+				return true
+			}
+		case map[string]interface{}:
+			if t == "object" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func inEnum(enum []interface{}, value interface{}) bool {
+	for _, allowed := range enum {
+		if fmt.Sprint(allowed) == fmt.Sprint(value) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesFormat(format, value string) bool {
+	switch format {
+	case "date-time":
+		_, err := time.Parse(time.RFC3339, value)
+		return err == nil
+	case "email":
+		address, err := mail.ParseAddress(value)
+		return err == nil && address.Address == value
+	case "uri":
+		parsed, err := url.Parse(value)
+		return err == nil && parsed.Scheme != "" && parsed.Host != ""
+	}
+	return true
+}
+
+func compiled(pattern string) *regexp.Regexp {
+	if re, ok := patterns.Load(pattern); ok {
+		return re.(*regexp.Regexp)
+	}
+	re := regexp.MustCompile(pattern)
+	patterns.Store(pattern, re)
+	return re
+}
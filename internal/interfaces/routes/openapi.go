@@ -0,0 +1,414 @@
+package routes
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/entities"
+	"github.com/kaa-dan/clean-architecture-go/internal/interfaces/idempotency"
+	"github.com/kaa-dan/clean-architecture-go/internal/interfaces/openapi"
+	"github.com/kaa-dan/clean-architecture-go/pkg/cache"
+	"github.com/kaa-dan/clean-architecture-go/pkg/jsonpatch"
+)
+
+var (
+	paginationParams = []openapi.Param{
+		{Name: "limit", Type: "integer", Description: "Page size, at most 100 (default: 10)"},
+		{Name: "offset", Type: "integer", Description: "Number of results to skip"},
+	}
+
+	userFilterParams = []openapi.Param{
+		{Name: "role", Enum: []string{string(entities.RoleUser), string(entities.RoleAdmin)}},
+		{Name: "is_active", Type: "boolean"},
+		{Name: "created_after", Format: "date-time"},
+		{Name: "created_before", Format: "date-time"},
+	}
+
+	ifMatchParam = openapi.Param{
+		Name:        "If-Match",
+		In:          "header",
+		Description: "ETag of the version being changed, the request fails with 412 when it is outdated",
+	}
+
+	userFieldsParam = openapi.Param{
+		Name:        "fields",
+		Description: "Comma separated members of the user objects to return, such as id,username,avatar. Custom attributes are selected as attributes.<name>.",
+	}
+
+	conditionalParams = []openapi.Param{
+		{Name: "If-None-Match", In: "header", Description: "ETag of the copy the client has, answered with 304 when it is current"},
+		{Name: "If-Modified-Since", In: "header", Description: "Last-Modified of the copy the client has, ignored when If-None-Match is sent"},
+	}
+
+	idempotencyKeyParam = openapi.Param{
+		Name:        idempotency.HeaderKey,
+		In:          "header",
+		Description: "Unique key of the request. Retries with the same key and request get the first response instead of running again.",
+	}
+
+	messageResponse = openapi.Object(map[string]interface{}{"message": ""})
+
+	graphQLResponse = openapi.Object(map[string]interface{}{"data": nil, "errors": []interface{}{}})
+)
+
+const apiDescription = "User management REST API, version 1. Successful JSON responses are wrapped in {\"success\": true, \"data\": ...}. " +
+	"Errors carry a stable code, and requests that accept application/problem+json get RFC 9457 problem details instead. " +
+	"Version 2 serves the same routes under /api/v2 with a {\"data\": ...} envelope, camelCase user objects and problem details for every error. " +
+	"Its sign up and user update bodies nest the name as {\"name\": {\"first\": ..., \"last\": ...}}, as the user objects do."
+
+// OpenAPI describes every route of SetupRoutes. Add a route here when you
+// add one there, SetupRoutes warns about routes that are missing.
+func OpenAPI() *openapi.Document {
+	return openapi.Build(openapi.Info{
+		Title:       "Clean Architecture User API",
+		Version:     "1.0.0",
+		Description: apiDescription,
+	}, withIdempotencyKeys([]openapi.Route{
+		{
+			Method: http.MethodGet, Path: "/health", Tag: "Health",
+			Summary:  "Health check",
+			Response: openapi.Object(map[string]interface{}{"status": "", "timestamp": int64(0)}),
+		},
+		{
+			Method: http.MethodGet, Path: "/problems/:code", Tag: "Errors",
+			Summary:     "Describe an error code",
+			Description: "The type of every problem response points here.",
+			Response:    openapi.Object(map[string]interface{}{"code": "", "title": "", "status": 0}),
+			Errors:      []int{http.StatusNotFound},
+		},
+		{
+			Method: http.MethodPost, Path: "/graphql", Tag: "GraphQL",
+			Summary:     "Execute a GraphQL query or mutation",
+			Description: "The bearer token is optional, each field applies the same access rules as the REST routes.",
+			Access:      openapi.Public,
+			Body: openapi.Object(map[string]interface{}{
+				"query": "", "operationName": "", "variables": map[string]interface{}{},
+			}),
+			Produces:      []string{"application/json"},
+			Response:      graphQLResponse,
+			ErrorResponse: graphQLResponse,
+		},
+		{
+			Method: http.MethodGet, Path: "/graphql", Tag: "GraphQL",
+			Summary: "Execute a GraphQL query",
+			Params: []openapi.Param{
+				{Name: "query", Required: true},
+				{Name: "operationName"},
+				{Name: "variables", Description: "Variables as a JSON object"},
+			},
+			Produces:      []string{"application/json"},
+			Response:      graphQLResponse,
+			Errors:        []int{http.StatusMethodNotAllowed},
+			ErrorResponse: graphQLResponse,
+		},
+
+		// Authentication
+		{
+			Method: http.MethodPost, Path: "/api/v1/auth/signup", Tag: "Authentication",
+			Summary:  "Register a user",
+			Body:     entities.SignUpRequest{},
+			Status:   http.StatusCreated,
+			Response: entities.AuthResponse{},
+			Errors:   []int{http.StatusConflict},
+		},
+		{
+			Method: http.MethodPost, Path: "/api/v1/auth/signin", Tag: "Authentication",
+			Summary:  "Sign in and receive a token",
+			Body:     entities.SignInRequest{},
+			Response: entities.AuthResponse{},
+			Errors:   []int{http.StatusUnauthorized, http.StatusForbidden},
+		},
+
+		// Profile
+		{
+			Method: http.MethodGet, Path: "/api/v1/profile", Tag: "Profile", Access: openapi.Authenticated,
+			Summary:     "Get the caller's profile",
+			Params:      append([]openapi.Param{userFieldsParam}, conditionalParams...),
+			Conditional: true,
+			Response:    entities.UserResponse{},
+			Errors:      []int{http.StatusNotFound},
+		},
+		{
+			Method: http.MethodPut, Path: "/api/v1/profile/avatar", Tag: "Profile", Access: openapi.Authenticated,
+			Summary:     "Upload an avatar",
+			Description: "JPEG, PNG or GIF. The image is cropped to a square and stored in several sizes.",
+			Body:        openapi.Content{"multipart/form-data": openapi.File("avatar")},
+			Response:    entities.UserResponse{},
+			Errors:      []int{http.StatusRequestEntityTooLarge, http.StatusUnprocessableEntity},
+		},
+		{
+			Method: http.MethodDelete, Path: "/api/v1/profile/avatar", Tag: "Profile", Access: openapi.Authenticated,
+			Summary:  "Remove the avatar",
+			Response: entities.UserResponse{},
+			Errors:   []int{http.StatusNotFound},
+		},
+		{
+			Method: http.MethodGet, Path: "/api/v1/profile/export", Tag: "Personal Data", Access: openapi.Authenticated,
+			Summary:  "Download everything stored about the caller",
+			Params:   []openapi.Param{{Name: "format", Enum: []string{"zip", "json"}}},
+			Produces: []string{"application/zip", "application/json"},
+		},
+		{
+			Method: http.MethodGet, Path: "/api/v1/profile/erasure", Tag: "Personal Data", Access: openapi.Authenticated,
+			Summary:  "Get the status of the caller's erasure request",
+			Response: entities.ErasureRequestResponse{},
+			Errors:   []int{http.StatusNotFound},
+		},
+		{
+			Method: http.MethodPost, Path: "/api/v1/profile/erasure", Tag: "Personal Data", Access: openapi.Authenticated,
+			Summary:  "Request erasure of the caller's account",
+			Status:   http.StatusAccepted,
+			Response: entities.ErasureRequestResponse{},
+			Errors:   []int{http.StatusConflict},
+		},
+		{
+			Method: http.MethodPost, Path: "/api/v1/profile/erasure/confirm", Tag: "Personal Data", Access: openapi.Authenticated,
+			Summary:  "Confirm an erasure request",
+			Body:     entities.ConfirmErasureRequest{},
+			Response: entities.ErasureRequestResponse{},
+			Errors:   []int{http.StatusForbidden, http.StatusNotFound, http.StatusConflict},
+		},
+		{
+			Method: http.MethodDelete, Path: "/api/v1/profile/erasure", Tag: "Personal Data", Access: openapi.Authenticated,
+			Summary:  "Cancel a pending erasure request",
+			Response: messageResponse,
+			Errors:   []int{http.StatusNotFound, http.StatusConflict},
+		},
+		{
+			Method: http.MethodGet, Path: "/api/v1/attributes", Tag: "Custom Attributes", Access: openapi.Authenticated,
+			Summary:  "List the custom attributes the caller may see",
+			Response: openapi.Object(map[string]interface{}{"attributes": []*entities.AttributeSchema{}}),
+		},
+
+		// Batch
+		{
+			Method: http.MethodPost, Path: "/api/v1/batch", Tag: "Batch", Access: openapi.Authenticated,
+			Summary: "Run several API requests at once",
+			Description: "Each request runs through the API as the caller and gets its own status. " +
+				"Atomic batches run their requests in order in one transaction: the first status of 400 or above rolls back the whole batch, and the requests after it get 424.",
+			Body:     entities.BatchRequest{},
+			Response: entities.BatchResponse{},
+			Errors:   []int{http.StatusNotImplemented},
+		},
+
+		// Users
+		{
+			Method: http.MethodGet, Path: "/api/v1/users/:id", Tag: "Users", Access: openapi.Authenticated,
+			Summary:     "Get a user",
+			Params:      append([]openapi.Param{userFieldsParam}, conditionalParams...),
+			Conditional: true,
+			Response:    entities.UserResponse{},
+			Errors:      []int{http.StatusBadRequest, http.StatusNotFound},
+		},
+		{
+			Method: http.MethodPut, Path: "/api/v1/users/:id", Tag: "Users", Access: openapi.Authenticated,
+			Summary:  "Update a user",
+			Params:   []openapi.Param{ifMatchParam},
+			Body:     entities.UpdateUserRequest{},
+			Response: entities.UserResponse{},
+			Errors:   []int{http.StatusForbidden, http.StatusNotFound, http.StatusConflict, http.StatusPreconditionFailed},
+		},
+		{
+			Method: http.MethodPatch, Path: "/api/v1/users/:id", Tag: "Users", Access: openapi.Authenticated,
+			Summary: "Partially update a user with a JSON Merge Patch or JSON Patch",
+			Params:  []openapi.Param{ifMatchParam},
+			Body: openapi.Content{
+				"application/merge-patch+json": map[string]interface{}{},
+				"application/json-patch+json":  []jsonpatch.Operation{},
+			},
+			Response: entities.UserResponse{},
+			Errors: []int{http.StatusForbidden, http.StatusNotFound, http.StatusConflict, http.StatusPreconditionFailed,
+				http.StatusUnsupportedMediaType, http.StatusUnprocessableEntity},
+		},
+		{
+			Method: http.MethodDelete, Path: "/api/v1/users/:id", Tag: "Users", Access: openapi.Authenticated,
+			Summary:  "Delete a user",
+			Response: messageResponse,
+			Errors:   []int{http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound},
+		},
+
+		// Admin
+		{
+			Method: http.MethodGet, Path: "/api/v1/admin/users", Tag: "Admin", Access: openapi.Admin,
+			Summary: "List users, newest first",
+			Params: append(append(append([]openapi.Param{userFieldsParam}, paginationParams...), userFilterParams...),
+				conditionalParams...),
+			Conditional: true,
+			Response: openapi.Object(map[string]interface{}{
+				"users": []*entities.UserResponse{}, "limit": 0, "offset": 0,
+			}),
+		},
+		{
+			Method: http.MethodGet, Path: "/api/v1/admin/users/search", Tag: "Admin", Access: openapi.Admin,
+			Summary: "Full-text search over users",
+			Params: append([]openapi.Param{
+				{Name: "q", Required: true},
+				{Name: "limit", Type: "integer"},
+				userFieldsParam,
+			}, conditionalParams...),
+			Conditional: true,
+			Response: openapi.Object(map[string]interface{}{
+				"query": "", "results": []*entities.UserSearchResult{}, "limit": 0,
+			}),
+		},
+		{
+			Method: http.MethodGet, Path: "/api/v1/admin/users/export", Tag: "Admin", Access: openapi.Admin,
+			Summary: "Export users as CSV, NDJSON or XLSX",
+			Params: append([]openapi.Param{
+				{Name: "format", Enum: []string{"csv", "ndjson", "xlsx"}},
+				{Name: "fields", Description: "Comma separated columns"},
+			}, userFilterParams...),
+			Produces: []string{
+				"text/csv",
+				"application/x-ndjson",
+				"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+			},
+			Response: openapi.Binary(),
+		},
+		{
+			Method: http.MethodPost, Path: "/api/v1/admin/users/import", Tag: "Admin", Access: openapi.Admin,
+			Summary: "Import users from CSV or NDJSON",
+			Params: []openapi.Param{
+				{Name: "format", Enum: []string{"csv", "ndjson"}},
+				{Name: "dry_run", Type: "boolean"},
+				{Name: "invite", Type: "boolean"},
+				{Name: "on_conflict", Enum: []string{string(entities.ImportConflictSkip), string(entities.ImportConflictUpsert)}},
+			},
+			Body: openapi.Content{
+				"multipart/form-data":  openapi.File("file"),
+				"text/csv":             openapi.Binary(),
+				"application/x-ndjson": openapi.Binary(),
+			},
+			Response: entities.ImportReport{},
+		},
+		{
+			Method: http.MethodGet, Path: "/api/v1/admin/cache/stats", Tag: "Admin", Access: openapi.Admin,
+			Summary: "User cache statistics",
+			Response: openapi.Object(map[string]interface{}{
+				"users": openapi.Object(map[string]interface{}{"enabled": true, "stats": cache.Stats{}}),
+			}),
+		},
+		{
+			Method: http.MethodGet, Path: "/api/v1/admin/stream", Tag: "Admin", Access: openapi.Admin,
+			Summary: "Live activity feed as Server-Sent Events",
+			Params: []openapi.Param{
+				{Name: "types", Description: "Comma separated event types"},
+				{Name: "last_event_id", Description: "Resume after this event, like the Last-Event-ID header"},
+				{Name: "Last-Event-ID", In: "header"},
+			},
+			Produces: []string{"text/event-stream"},
+			Response: openapi.Binary(),
+		},
+		{
+			Method: http.MethodGet, Path: "/api/v1/admin/erasure/certificates", Tag: "Admin", Access: openapi.Admin,
+			Summary: "List erasure certificates",
+			Params:  paginationParams,
+			Response: openapi.Object(map[string]interface{}{
+				"certificates": []*entities.ErasureCertificate{}, "limit": 0, "offset": 0,
+			}),
+		},
+
+		// Custom attributes
+		{
+			Method: http.MethodPost, Path: "/api/v1/admin/attributes", Tag: "Custom Attributes", Access: openapi.Admin,
+			Summary:  "Define a custom attribute",
+			Body:     entities.CreateAttributeSchemaRequest{},
+			Status:   http.StatusCreated,
+			Response: entities.AttributeSchema{},
+			Errors:   []int{http.StatusConflict},
+		},
+		{
+			Method: http.MethodGet, Path: "/api/v1/admin/attributes/:name", Tag: "Custom Attributes", Access: openapi.Admin,
+			Summary:  "Get a custom attribute",
+			Response: entities.AttributeSchema{},
+			Errors:   []int{http.StatusNotFound},
+		},
+		{
+			Method: http.MethodPut, Path: "/api/v1/admin/attributes/:name", Tag: "Custom Attributes", Access: openapi.Admin,
+			Summary:  "Update a custom attribute",
+			Body:     entities.UpdateAttributeSchemaRequest{},
+			Response: entities.AttributeSchema{},
+			Errors:   []int{http.StatusNotFound},
+		},
+		{
+			Method: http.MethodDelete, Path: "/api/v1/admin/attributes/:name", Tag: "Custom Attributes", Access: openapi.Admin,
+			Summary:  "Delete a custom attribute",
+			Response: messageResponse,
+			Errors:   []int{http.StatusNotFound},
+		},
+
+		// Webhooks
+		{
+			Method: http.MethodPost, Path: "/api/v1/admin/webhooks", Tag: "Webhooks", Access: openapi.Admin,
+			Summary:     "Create a webhook",
+			Description: "The signing secret is only returned in this response.",
+			Body:        entities.CreateWebhookRequest{},
+			Status:      http.StatusCreated,
+			Response:    entities.WebhookResponse{},
+		},
+		{
+			Method: http.MethodGet, Path: "/api/v1/admin/webhooks", Tag: "Webhooks", Access: openapi.Admin,
+			Summary: "List webhooks",
+			Params:  paginationParams,
+			Response: openapi.Object(map[string]interface{}{
+				"webhooks": []*entities.WebhookResponse{}, "limit": 0, "offset": 0,
+			}),
+		},
+		{
+			Method: http.MethodGet, Path: "/api/v1/admin/webhooks/:id", Tag: "Webhooks", Access: openapi.Admin,
+			Summary:  "Get a webhook",
+			Response: entities.WebhookResponse{},
+			Errors:   []int{http.StatusBadRequest, http.StatusNotFound},
+		},
+		{
+			Method: http.MethodPut, Path: "/api/v1/admin/webhooks/:id", Tag: "Webhooks", Access: openapi.Admin,
+			Summary:  "Update a webhook",
+			Body:     entities.UpdateWebhookRequest{},
+			Response: entities.WebhookResponse{},
+			Errors:   []int{http.StatusNotFound},
+		},
+		{
+			Method: http.MethodDelete, Path: "/api/v1/admin/webhooks/:id", Tag: "Webhooks", Access: openapi.Admin,
+			Summary:  "Delete a webhook and its delivery log",
+			Response: messageResponse,
+			Errors:   []int{http.StatusBadRequest, http.StatusNotFound},
+		},
+		{
+			Method: http.MethodGet, Path: "/api/v1/admin/webhooks/:id/deliveries", Tag: "Webhooks", Access: openapi.Admin,
+			Summary: "Delivery log of a webhook",
+			Params: append([]openapi.Param{{
+				Name: "status",
+				Enum: []string{
+					string(entities.WebhookDeliveryPending),
+					string(entities.WebhookDeliverySucceeded),
+					string(entities.WebhookDeliveryDead),
+				},
+			}}, paginationParams...),
+			Response: openapi.Object(map[string]interface{}{
+				"deliveries": []*entities.WebhookDelivery{}, "limit": 0, "offset": 0,
+			}),
+			Errors: []int{http.StatusNotFound},
+		},
+		{
+			Method: http.MethodPost, Path: "/api/v1/admin/webhooks/:id/deliveries/:delivery_id/redeliver", Tag: "Webhooks", Access: openapi.Admin,
+			Summary:  "Queue a delivery again",
+			Status:   http.StatusAccepted,
+			Response: entities.WebhookDelivery{},
+			Errors:   []int{http.StatusBadRequest, http.StatusNotFound},
+		},
+	}))
+}
+
+// withIdempotencyKeys documents the Idempotency-Key header on the mutating
+// API routes, which all run the idempotency middleware except sign in
+func withIdempotencyKeys(routes []openapi.Route) []openapi.Route {
+	for i, route := range routes {
+		if !strings.HasPrefix(route.Path, "/api/") || route.Method == http.MethodGet || route.Path == "/api/v1/auth/signin" {
+			continue
+		}
+		routes[i].Params = append(route.Params[:len(route.Params):len(route.Params)], idempotencyKeyParam)
+		routes[i].Errors = append(route.Errors[:len(route.Errors):len(route.Errors)],
+			http.StatusConflict, http.StatusRequestEntityTooLarge, http.StatusUnprocessableEntity)
+	}
+	return routes
+}
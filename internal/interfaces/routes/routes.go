@@ -1,36 +1,96 @@
 package routes
 
 import (
+	"fmt"
+	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-contrib/requestid"
 	"github.com/gin-gonic/gin"
+	"github.com/kaa-dan/clean-architecture-go/internal/infrastructure/blobstore"
 	"github.com/kaa-dan/clean-architecture-go/internal/infrastructure/security"
+	"github.com/kaa-dan/clean-architecture-go/internal/interfaces/encoding"
+	"github.com/kaa-dan/clean-architecture-go/internal/interfaces/graphql"
 	"github.com/kaa-dan/clean-architecture-go/internal/interfaces/handlers"
+	"github.com/kaa-dan/clean-architecture-go/internal/interfaces/idempotency"
+	"github.com/kaa-dan/clean-architecture-go/internal/interfaces/openapi"
+	"github.com/kaa-dan/clean-architecture-go/internal/interfaces/versioning"
+	"github.com/kaa-dan/clean-architecture-go/pkg/errors"
+	"github.com/kaa-dan/clean-architecture-go/pkg/logger"
 	"github.com/kaa-dan/clean-architecture-go/pkg/response"
 )
 
+// OpenAPIOptions decides how requests and responses are checked against the
+// OpenAPI document
+type OpenAPIOptions struct {
+	Document          *openapi.Document
+	ValidateRequests  bool
+	ValidateResponses bool
+}
+
 func SetupRoutes(
 	router *gin.Engine,
 	userHandler *handlers.UserHandler,
+	cacheHandler *handlers.CacheHandler,
+	importHandler *handlers.ImportHandler,
+	privacyHandler *handlers.PrivacyHandler,
+	webhookHandler *handlers.WebhookHandler,
+	activityHandler *handlers.ActivityHandler,
+	avatarHandler *handlers.AvatarHandler,
+	attributeHandler *handlers.AttributeHandler,
+	batchHandler *handlers.BatchHandler,
+	graphqlHandler *graphql.Handler,
+	openAPI OpenAPIOptions,
+	apiVersions *versioning.Registry,
+	mediaDir string,
 	authMiddleware *security.AuthMiddleware,
+	idempotencyMiddleware *idempotency.Middleware,
+	compressor *encoding.Compressor,
 ) {
 	// Middleware
-	router.Use(gin.Logger())
-	router.Use(gin.Recovery())
 	router.Use(requestid.New())
+	router.Use(gin.Logger())
+	// Responses are compressed when a compressor is given, including the
+	// errors of recovered panics
+	if compressor != nil {
+		router.Use(compressor.Handle())
+	}
+	router.Use(gin.CustomRecovery(func(c *gin.Context, recovered interface{}) {
+		response.HandleError(c, fmt.Errorf("panic: %v", recovered))
+		c.Abort()
+	}))
+
+	// Unknown routes get the same error bodies as the API
+	router.HandleMethodNotAllowed = true
+	router.NoRoute(func(c *gin.Context) {
+		response.Error(c, http.StatusNotFound, "Route not found")
+	})
+	router.NoMethod(func(c *gin.Context) {
+		response.Error(c, http.StatusMethodNotAllowed, "Method not allowed")
+	})
 
 	// CORS configuration
 	router.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"*"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Length", "Content-Type", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowHeaders:     []string{"Origin", "Content-Length", "Content-Type", "Authorization", "Accept", "If-Match", "If-None-Match", "If-Modified-Since", "Last-Event-ID", idempotency.HeaderKey},
+		ExposeHeaders:    []string{"Content-Length", "ETag", "Last-Modified", "API-Version", "Deprecation", "Sunset", "Link", "Retry-After", idempotency.ReplayedHeader},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}))
 
+	// Responses are checked first, so rejected requests are covered too
+	if openAPI.ValidateResponses {
+		router.Use(openAPI.Document.ValidateResponses())
+	}
+	// MessagePack and Protobuf bodies are read as JSON from here on
+	router.Use(encoding.DecodeBodies())
+	if openAPI.ValidateRequests {
+		router.Use(openAPI.Document.ValidateRequests())
+	}
+
 	// Rate limiting middleware (simple implementation)
 	router.Use(func(c *gin.Context) {
 		// Add rate limiting logic here if needed
@@ -45,36 +105,120 @@ func SetupRoutes(
 		})
 	})
 
-	// API routes
-	api := router.Group("/api/v1")
+	// What each error code means, the type URIs of problem responses
+	router.GET(response.ProblemTypePath+":code", func(c *gin.Context) {
+		description, ok := errors.DescribeCode(errors.Code(c.Param("code")))
+		if !ok {
+			response.Error(c, http.StatusNotFound, "Unknown error code")
+			return
+		}
+		problem := response.NewProblem(c, description)
+		response.Success(c, http.StatusOK, gin.H{
+			"code":   problem.Code,
+			"title":  problem.Title,
+			"status": problem.Status,
+		})
+	})
+
+	// API description and docs UI
+	router.GET(openapi.SpecPath, openAPI.Document.ServeSpec)
+	router.GET(openapi.DocsPath+"/*filepath", openapi.ServeDocs)
 
-	// Public routes
-	auth := api.Group("/auth")
-	{
-		auth.POST("/signup", userHandler.SignUp)
-		auth.POST("/signin", userHandler.SignIn)
+	// Uploaded files, when blobs are kept on the local filesystem. Blob keys
+	// are never reused, so the files can be cached for good.
+	if mediaDir != "" {
+		media := router.Group(blobstore.LocalMediaRoute)
+		media.Use(func(c *gin.Context) {
+			c.Header("X-Content-Type-Options", "nosniff")
+			c.Header("Cache-Control", "public, max-age=31536000, immutable")
+			c.Next()
+		})
+		media.Static("/", mediaDir)
 	}
 
-	// Protected routes
-	protected := api.Group("/")
-	protected.Use(authMiddleware.RequireAuth())
-	{
-		// User profile routes
-		protected.GET("/profile", userHandler.GetProfile)
+	// GraphQL, open to anonymous callers so that resolvers can apply the
+	// same per-field rules as the REST routes
+	router.GET("/graphql", authMiddleware.OptionalAuth(), graphqlHandler.Query)
+	router.POST("/graphql", authMiddleware.OptionalAuth(), graphqlHandler.Query)
 
-		// User management routes
-		users := protected.Group("/users")
+	// API routes. Every version shares the handlers, the version middleware
+	// installs the renderer that maps results to that version's DTOs.
+	registerAPI := func(api *gin.RouterGroup) {
+		// Public routes. Retries of sign ups that send an Idempotency-Key
+		// are answered from the first response. Signing in twice does no
+		// harm, and its body is a password worth keeping out of storage.
+		auth := api.Group("/auth")
 		{
-			users.GET("/:id", userHandler.GetUser)
-			users.PUT("/:id", userHandler.UpdateUser)
-			users.DELETE("/:id", userHandler.DeleteUser)
+			auth.POST("/signup", idempotencyMiddleware.Handle(), userHandler.SignUp)
+			auth.POST("/signin", userHandler.SignIn)
 		}
 
-		// Admin only routes
-		admin := protected.Group("/admin")
-		admin.Use(authMiddleware.RequireAdmin())
+		// Protected routes
+		protected := api.Group("/")
+		protected.Use(authMiddleware.RequireAuth(), idempotencyMiddleware.Handle())
 		{
-			admin.GET("/users", userHandler.GetAllUsers)
+			// User profile routes
+			protected.GET("/profile", userHandler.GetProfile)
+			protected.PUT("/profile/avatar", avatarHandler.UploadAvatar)
+			protected.DELETE("/profile/avatar", avatarHandler.DeleteAvatar)
+			protected.GET("/profile/export", privacyHandler.ExportPersonalData)
+			protected.GET("/profile/erasure", privacyHandler.GetErasureStatus)
+			protected.POST("/profile/erasure", privacyHandler.RequestErasure)
+			protected.POST("/profile/erasure/confirm", privacyHandler.ConfirmErasure)
+			protected.DELETE("/profile/erasure", privacyHandler.CancelErasure)
+
+			protected.GET("/attributes", attributeHandler.ListSchemas)
+
+			// Several requests in one, run as the caller
+			protected.POST("/batch", batchHandler.Execute)
+
+			// User management routes
+			users := protected.Group("/users")
+			{
+				users.GET("/:id", userHandler.GetUser)
+				users.PUT("/:id", userHandler.UpdateUser)
+				users.PATCH("/:id", userHandler.PatchUser)
+				users.DELETE("/:id", userHandler.DeleteUser)
+			}
+
+			// Admin only routes
+			admin := protected.Group("/admin")
+			admin.Use(authMiddleware.RequireAdmin())
+			{
+				admin.GET("/users", userHandler.GetAllUsers)
+				admin.GET("/users/search", userHandler.SearchUsers)
+				admin.GET("/users/export", userHandler.ExportUsers)
+				admin.POST("/users/import", importHandler.ImportUsers)
+				admin.GET("/cache/stats", cacheHandler.Stats)
+				admin.GET("/stream", activityHandler.Stream)
+				admin.GET("/erasure/certificates", privacyHandler.ListErasureCertificates)
+
+				admin.POST("/attributes", attributeHandler.CreateSchema)
+				admin.GET("/attributes/:name", attributeHandler.GetSchema)
+				admin.PUT("/attributes/:name", attributeHandler.UpdateSchema)
+				admin.DELETE("/attributes/:name", attributeHandler.DeleteSchema)
+
+				admin.POST("/webhooks", webhookHandler.CreateWebhook)
+				admin.GET("/webhooks", webhookHandler.GetAllWebhooks)
+				admin.GET("/webhooks/:id", webhookHandler.GetWebhook)
+				admin.PUT("/webhooks/:id", webhookHandler.UpdateWebhook)
+				admin.DELETE("/webhooks/:id", webhookHandler.DeleteWebhook)
+				admin.GET("/webhooks/:id/deliveries", webhookHandler.ListDeliveries)
+				admin.POST("/webhooks/:id/deliveries/:delivery_id/redeliver", webhookHandler.Redeliver)
+			}
+		}
+	}
+	for _, number := range apiVersions.Numbers() {
+		registerAPI(router.Group(versioning.Prefix(number), apiVersions.Path(number)))
+	}
+	// Unversioned paths take the version from the Accept header
+	registerAPI(router.Group("/api", apiVersions.Negotiate("/api")))
+
+	// Keep the document in step with the API routes. It describes version 1,
+	// the other prefixes serve the same routes.
+	for _, route := range openAPI.Document.Undocumented(router.Routes()) {
+		if strings.Contains(route, " /api/v1/") {
+			logger.Warnf("route %s is missing from the OpenAPI document", route)
 		}
 	}
 }
@@ -0,0 +1,144 @@
+// Package v2 holds the DTOs and response envelope of version 2 of the REST
+// API. Handlers and use cases are shared with version 1, the renderer maps
+// their results on the way out.
+package v2
+
+import (
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/entities"
+)
+
+// User replaces entities.UserResponse. Keys are camelCase, the name and
+// avatar are nested objects and is_active became status.
+type User struct {
+	ID         string                 `json:"id"`
+	Email      string                 `json:"email"`
+	Username   string                 `json:"username"`
+	Name       Name                   `json:"name"`
+	Status     string                 `json:"status"`
+	Role       string                 `json:"role"`
+	Locale     *string                `json:"locale"`
+	Version    int64                  `json:"version"`
+	Avatar     *Avatar                `json:"avatar"`
+	Attributes map[string]interface{} `json:"attributes"`
+	CreatedAt  time.Time              `json:"createdAt"`
+	UpdatedAt  time.Time              `json:"updatedAt"`
+}
+
+type Name struct {
+	First string `json:"first"`
+	Last  string `json:"last"`
+}
+
+// Avatar lists the variants from smallest to largest, URL is the largest
+type Avatar struct {
+	URL      string          `json:"url"`
+	Variants []AvatarVariant `json:"variants"`
+}
+
+type AvatarVariant struct {
+	Size int    `json:"size"`
+	URL  string `json:"url"`
+}
+
+const (
+	StatusActive   = "active"
+	StatusInactive = "inactive"
+)
+
+type AuthResponse struct {
+	Token string `json:"token"`
+	User  User   `json:"user"`
+}
+
+type SearchResult struct {
+	User       User              `json:"user"`
+	Score      float64           `json:"score"`
+	Highlights map[string]string `json:"highlights"`
+}
+
+// FromUser maps a shared user response to its version 2 shape
+func FromUser(user entities.UserResponse) User {
+	dto := User{
+		ID:         user.ID,
+		Email:      user.Email,
+		Username:   user.Username,
+		Name:       Name{First: user.FirstName, Last: user.LastName},
+		Status:     StatusInactive,
+		Role:       user.Role,
+		Version:    user.Version,
+		Attributes: user.Attributes,
+		CreatedAt:  user.CreatedAt,
+		UpdatedAt:  user.UpdatedAt,
+	}
+	if user.IsActive {
+		dto.Status = StatusActive
+	}
+	if user.Locale != "" {
+		dto.Locale = &user.Locale
+	}
+	if dto.Attributes == nil {
+		dto.Attributes = map[string]interface{}{}
+	}
+	if user.AvatarURL != "" {
+		dto.Avatar = &Avatar{URL: user.AvatarURL, Variants: []AvatarVariant{}}
+		for size, url := range user.AvatarVariants {
+			n, err := strconv.Atoi(size)
+			if err != nil {
+				continue
+			}
+			dto.Avatar.Variants = append(dto.Avatar.Variants, AvatarVariant{Size: n, URL: url})
+		}
+		sort.Slice(dto.Avatar.Variants, func(i, j int) bool {
+			return dto.Avatar.Variants[i].Size < dto.Avatar.Variants[j].Size
+		})
+	}
+	return dto
+}
+
+// Map converts the results handlers respond with to their version 2 DTOs,
+// including inside gin.H. Types that did not change are returned as they are.
+func Map(data interface{}) interface{} {
+	switch v := data.(type) {
+	case *entities.UserResponse:
+		if v == nil {
+			return nil
+		}
+		return FromUser(*v)
+	case entities.UserResponse:
+		return FromUser(v)
+	case []*entities.UserResponse:
+		users := make([]User, 0, len(v))
+		for _, user := range v {
+			users = append(users, FromUser(*user))
+		}
+		return users
+	case *entities.AuthResponse:
+		if v == nil {
+			return nil
+		}
+		return AuthResponse{Token: v.Token, User: FromUser(v.User.ToResponse())}
+	case []*entities.UserSearchResult:
+		results := make([]SearchResult, 0, len(v))
+		for _, result := range v {
+			results = append(results, SearchResult{
+				User:       FromUser(result.User),
+				Score:      result.Score,
+				Highlights: result.Highlights,
+			})
+		}
+		return results
+	case gin.H:
+		mapped := make(gin.H, len(v))
+		for key, value := range v {
+			mapped[key] = Map(value)
+		}
+		return mapped
+	default:
+		return data
+	}
+}
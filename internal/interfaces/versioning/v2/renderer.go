@@ -0,0 +1,23 @@
+package v2
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/kaa-dan/clean-architecture-go/pkg/response"
+)
+
+// Body is the version 2 envelope of successful responses, in the encoding the
+// client accepts. Errors are always application/problem+json.
+type Body struct {
+	Data interface{} `json:"data"`
+}
+
+// Renderer writes version 2 responses
+type Renderer struct{}
+
+func (Renderer) Success(c *gin.Context, statusCode int, data interface{}) {
+	response.Write(c, statusCode, Body{Data: response.Sparse(c, Map(data))})
+}
+
+func (Renderer) Problem(c *gin.Context, problem *response.Problem) {
+	response.WriteProblem(c, problem)
+}
@@ -0,0 +1,212 @@
+package v2
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/entities"
+	"github.com/kaa-dan/clean-architecture-go/pkg/errors"
+	"github.com/kaa-dan/clean-architecture-go/pkg/jsonpatch"
+)
+
+// SignUpRequest replaces entities.SignUpRequest, with the name nested as in
+// User
+type SignUpRequest struct {
+	Email      string                 `json:"email"`
+	Username   string                 `json:"username"`
+	Password   string                 `json:"password"`
+	Name       Name                   `json:"name"`
+	Locale     string                 `json:"locale,omitempty"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+}
+
+func (r SignUpRequest) toShared() entities.SignUpRequest {
+	return entities.SignUpRequest{
+		Email:      r.Email,
+		Username:   r.Username,
+		Password:   r.Password,
+		FirstName:  r.Name.First,
+		LastName:   r.Name.Last,
+		Locale:     r.Locale,
+		Attributes: r.Attributes,
+	}
+}
+
+// UpdateUserRequest replaces entities.UpdateUserRequest. Parts of the name
+// that are left out are kept.
+type UpdateUserRequest struct {
+	Name       *NameUpdate            `json:"name,omitempty"`
+	Username   *string                `json:"username,omitempty"`
+	Locale     *string                `json:"locale,omitempty"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+}
+
+type NameUpdate struct {
+	First *string `json:"first,omitempty"`
+	Last  *string `json:"last,omitempty"`
+}
+
+func (r UpdateUserRequest) toShared() entities.UpdateUserRequest {
+	shared := entities.UpdateUserRequest{
+		Username:   r.Username,
+		Locale:     r.Locale,
+		Attributes: r.Attributes,
+	}
+	if r.Name != nil {
+		shared.FirstName = r.Name.First
+		shared.LastName = r.Name.Last
+	}
+	return shared
+}
+
+// nameFields are the members of Name and the shared fields they stand for
+var nameFields = map[string]string{
+	"first": "first_name",
+	"last":  "last_name",
+}
+
+// RequestMapper converts version 2 request bodies to the shared request
+// DTOs, so that a client can send back what it received. Routes whose
+// bodies did not change pass unchanged.
+type RequestMapper struct{}
+
+func (RequestMapper) MapRequest(c *gin.Context, route string) error {
+	var mapBody func(body []byte) (interface{}, error)
+	switch c.Request.Method + " " + route {
+	case "POST /auth/signup":
+		mapBody = mapSignUp
+	case "PUT /users/:id":
+		mapBody = mapUpdateUser
+	case "PATCH /users/:id":
+		switch c.ContentType() {
+		case jsonpatch.MergePatchContentType:
+			mapBody = mapMergePatch
+		case jsonpatch.JSONPatchContentType:
+			mapBody = mapJSONPatch
+		}
+	}
+	if mapBody == nil || c.Request.Body == nil {
+		return nil
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return errors.ErrInvalidRequestBody
+	}
+	mapped, err := mapBody(body)
+	if err != nil {
+		return errors.ErrInvalidRequestBody
+	}
+	if body, err = json.Marshal(mapped); err != nil {
+		return err
+	}
+
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	c.Request.ContentLength = int64(len(body))
+	c.Request.Header.Set("Content-Length", strconv.Itoa(len(body)))
+	return nil
+}
+
+func mapSignUp(body []byte) (interface{}, error) {
+	var req SignUpRequest
+	if err := decodeStrict(body, &req); err != nil {
+		return nil, err
+	}
+	return req.toShared(), nil
+}
+
+func mapUpdateUser(body []byte) (interface{}, error) {
+	var req UpdateUserRequest
+	if err := decodeStrict(body, &req); err != nil {
+		return nil, err
+	}
+	return req.toShared(), nil
+}
+
+// mapMergePatch moves the members of name to the shared fields. A null name
+// is passed on as the shared fields, which are not nullable.
+func mapMergePatch(body []byte) (interface{}, error) {
+	var patch map[string]interface{}
+	if err := json.Unmarshal(body, &patch); err != nil {
+		return nil, err
+	}
+	name, ok := patch["name"]
+	if !ok {
+		return patch, nil
+	}
+	delete(patch, "name")
+
+	members, ok := name.(map[string]interface{})
+	if !ok && name != nil {
+		return nil, errors.ErrInvalidPatch
+	}
+	for member, field := range nameFields {
+		if name == nil {
+			patch[field] = nil
+		} else if value, ok := members[member]; ok {
+			patch[field] = value
+		}
+	}
+	return patch, nil
+}
+
+// mapJSONPatch rewrites the paths under /name to the shared fields. An
+// operation on the whole name becomes one per member.
+func mapJSONPatch(body []byte) (interface{}, error) {
+	var operations []jsonpatch.Operation
+	if err := json.Unmarshal(body, &operations); err != nil {
+		return nil, err
+	}
+
+	mapped := make([]jsonpatch.Operation, 0, len(operations))
+	for _, operation := range operations {
+		if operation.Path != "/name" {
+			operation.Path = sharedPointer(operation.Path)
+			operation.From = sharedPointer(operation.From)
+			mapped = append(mapped, operation)
+			continue
+		}
+
+		switch operation.Op {
+		case "add", "replace", "test":
+			var name map[string]json.RawMessage
+			if err := json.Unmarshal(operation.Value, &name); err != nil {
+				return nil, err
+			}
+			for _, member := range []string{"first", "last"} {
+				value, ok := name[member]
+				if !ok {
+					continue
+				}
+				mapped = append(mapped, jsonpatch.Operation{Op: operation.Op, Path: "/" + nameFields[member], Value: value})
+			}
+		default:
+			// Names cannot be removed or moved, the shared fields refuse it
+			for _, member := range []string{"first", "last"} {
+				mapped = append(mapped, jsonpatch.Operation{Op: operation.Op, Path: "/" + nameFields[member], From: sharedPointer(operation.From)})
+			}
+		}
+	}
+	return mapped, nil
+}
+
+// sharedPointer maps /name/first and /name/last to the shared fields
+func sharedPointer(pointer string) string {
+	member, ok := strings.CutPrefix(pointer, "/name/")
+	if field, known := nameFields[member]; ok && known {
+		return "/" + field
+	}
+	return pointer
+}
+
+// decodeStrict refuses members that the DTO does not have, such as the
+// version 1 first_name
+func decodeStrict(body []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
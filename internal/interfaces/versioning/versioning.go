@@ -0,0 +1,221 @@
+package versioning
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kaa-dan/clean-architecture-go/pkg/codec"
+	"github.com/kaa-dan/clean-architecture-go/pkg/response"
+)
+
+// MediaTypePrefix and MediaTypeSuffix surround the version number in the
+// vendor media type, e.g. application/vnd.userapi.v2+json
+const (
+	MediaTypePrefix = "application/vnd.userapi.v"
+	MediaTypeSuffix = "+json"
+)
+
+// versionKey holds the number of the version serving the request
+const versionKey = "api_version"
+
+// Version is one major version of the REST API. Every version shares the
+// routes and use cases, Renderer maps their results to the version's DTOs and
+// Requests, when set, maps the version's request bodies to the shared ones.
+type Version struct {
+	Number   int
+	Renderer response.Renderer
+	Requests RequestMapper
+
+	// Deprecated and Sunset are zero unless the version is being retired.
+	// Once Sunset has passed the version answers 410 Gone.
+	Deprecated time.Time
+	Sunset     time.Time
+}
+
+// RequestMapper rewrites the body of a request to a route, the path of the
+// route below the version prefix such as /users/:id, before handlers read it
+type RequestMapper interface {
+	MapRequest(c *gin.Context, route string) error
+}
+
+// Registry holds the versions that are served and picks one per request
+type Registry struct {
+	versions map[int]Version
+	numbers  []int
+	fallback int
+}
+
+// NewRegistry serves versions, using fallback for requests that do not ask
+// for a version
+func NewRegistry(fallback int, versions ...Version) (*Registry, error) {
+	r := &Registry{
+		versions: make(map[int]Version, len(versions)),
+		fallback: fallback,
+	}
+	for _, v := range versions {
+		if _, ok := r.versions[v.Number]; ok {
+			return nil, fmt.Errorf("API version %d is registered twice", v.Number)
+		}
+		r.versions[v.Number] = v
+		r.numbers = append(r.numbers, v.Number)
+	}
+	sort.Ints(r.numbers)
+
+	if _, ok := r.versions[fallback]; !ok {
+		return nil, fmt.Errorf("default API version %d is not registered", fallback)
+	}
+	return r, nil
+}
+
+// Numbers lists the served versions in ascending order
+func (r *Registry) Numbers() []int {
+	return r.numbers
+}
+
+// Current is the number of the version serving the request, zero outside
+// the versioned routes
+func Current(c *gin.Context) int {
+	return c.GetInt(versionKey)
+}
+
+// Prefix is the path under which version number is served
+func Prefix(number int) string {
+	return "/api/v" + strconv.Itoa(number)
+}
+
+// Path serves version number under its own prefix. An Accept header asking
+// for another version is refused with 406.
+func (r *Registry) Path(number int) gin.HandlerFunc {
+	v := r.versions[number]
+	prefix := Prefix(number)
+
+	return func(c *gin.Context) {
+		if requested, ok := acceptedVersion(c.GetHeader("Accept")); ok && requested != number {
+			response.Error(c, http.StatusNotAcceptable, fmt.Sprintf(
+				"The Accept header asks for another API version than the path, which is for version %d", number))
+			c.Abort()
+			return
+		}
+		r.serve(c, v, prefix)
+	}
+}
+
+// Negotiate serves the version named in the Accept header of the routes
+// under prefix, or the default version when it names none
+func (r *Registry) Negotiate(prefix string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Vary", "Accept")
+
+		number := r.fallback
+		if requested, ok := acceptedVersion(c.GetHeader("Accept")); ok {
+			number = requested
+		}
+
+		v, ok := r.versions[number]
+		if !ok {
+			response.Error(c, http.StatusNotAcceptable, fmt.Sprintf(
+				"The requested API version is not supported, use one of %s", r.list()))
+			c.Abort()
+			return
+		}
+		r.serve(c, v, prefix)
+	}
+}
+
+func (r *Registry) serve(c *gin.Context, v Version, prefix string) {
+	response.UseRenderer(c, v.Renderer)
+	c.Set(versionKey, v.Number)
+	c.Header("API-Version", strconv.Itoa(v.Number))
+
+	if !v.Deprecated.IsZero() {
+		// RFC 9745 structured field date
+		c.Header("Deprecation", "@"+strconv.FormatInt(v.Deprecated.Unix(), 10))
+	}
+	if !v.Sunset.IsZero() {
+		c.Header("Sunset", v.Sunset.UTC().Format(http.TimeFormat))
+	}
+
+	successor, hasSuccessor := r.successor(v.Number)
+	if hasSuccessor && (!v.Deprecated.IsZero() || !v.Sunset.IsZero()) {
+		path := Prefix(successor) + strings.TrimPrefix(c.Request.URL.Path, prefix)
+		c.Header("Link", "<"+path+`>; rel="successor-version"`)
+	}
+
+	if !v.Sunset.IsZero() && !time.Now().Before(v.Sunset) {
+		message := fmt.Sprintf("API version %d was retired on %s", v.Number, v.Sunset.UTC().Format("2006-01-02"))
+		if hasSuccessor {
+			message += fmt.Sprintf(", use version %d", successor)
+		}
+		response.Error(c, http.StatusGone, message)
+		c.Abort()
+		return
+	}
+
+	if v.Requests != nil {
+		if err := v.Requests.MapRequest(c, strings.TrimPrefix(c.FullPath(), prefix)); err != nil {
+			response.HandleError(c, err)
+			c.Abort()
+			return
+		}
+	}
+
+	c.Next()
+}
+
+// successor is the next version up, if any
+func (r *Registry) successor(number int) (int, bool) {
+	for _, n := range r.numbers {
+		if n > number {
+			return n, true
+		}
+	}
+	return 0, false
+}
+
+func (r *Registry) list() string {
+	names := make([]string, len(r.numbers))
+	for i, n := range r.numbers {
+		names[i] = strconv.Itoa(n)
+	}
+	return strings.Join(names, ", ")
+}
+
+// acceptedVersion finds the version asked for in an Accept header, either as
+// application/vnd.userapi.v2+json or as application/json; version=2, where
+// application/msgpack and application/x-protobuf take the parameter too
+func acceptedVersion(accept string) (int, bool) {
+	if accept == "" {
+		return 0, false
+	}
+
+	for _, mediaRange := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(mediaRange))
+		if err != nil {
+			continue
+		}
+
+		raw := ""
+		switch {
+		case strings.HasPrefix(mediaType, MediaTypePrefix) && strings.HasSuffix(mediaType, MediaTypeSuffix):
+			raw = strings.TrimSuffix(strings.TrimPrefix(mediaType, MediaTypePrefix), MediaTypeSuffix)
+		case (mediaType == "application/json" || codec.IsBinary(mediaType)) && params["version"] != "":
+			raw = params["version"]
+		default:
+			continue
+		}
+
+		number, err := strconv.Atoi(raw)
+		if err != nil || number <= 0 {
+			// Unknown versions are refused rather than silently served
+			return -1, true
+		}
+		return number, true
+	}
+	return 0, false
+}
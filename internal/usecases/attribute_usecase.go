@@ -0,0 +1,114 @@
+package usecases
+
+import (
+	"context"
+
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/entities"
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/repositories"
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/services"
+	"github.com/kaa-dan/clean-architecture-go/pkg/validator"
+)
+
+type attributeUseCase struct {
+	schemaRepo repositories.AttributeSchemaRepository
+	validator  *validator.Validator
+}
+
+func NewAttributeUseCase(schemaRepo repositories.AttributeSchemaRepository) services.AttributeService {
+	return &attributeUseCase{
+		schemaRepo: schemaRepo,
+		validator:  validator.New(),
+	}
+}
+
+func (u *attributeUseCase) CreateSchema(ctx context.Context, req *entities.CreateAttributeSchemaRequest) (*entities.AttributeSchema, error) {
+	schema := &entities.AttributeSchema{
+		Name:       req.Name,
+		Label:      req.Label,
+		Type:       req.Type,
+		Rules:      req.Rules,
+		Visibility: req.Visibility,
+		Required:   req.Required,
+	}
+	if err := u.checkSchema(schema); err != nil {
+		return nil, err
+	}
+
+	if err := u.schemaRepo.Create(ctx, schema); err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+func (u *attributeUseCase) GetSchema(ctx context.Context, name string) (*entities.AttributeSchema, error) {
+	return u.schemaRepo.GetByName(ctx, name)
+}
+
+func (u *attributeUseCase) ListSchemas(ctx context.Context, viewer entities.Viewer) ([]*entities.AttributeSchema, error) {
+	schemas, err := u.schemaRepo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	visible := make([]*entities.AttributeSchema, 0, len(schemas))
+	for _, schema := range schemas {
+		if viewer.CanSee(schema.Visibility, viewer.UserID) {
+			visible = append(visible, schema)
+		}
+	}
+	return visible, nil
+}
+
+// UpdateSchema changes how values are shown and validated from now on.
+// Values already stored are not checked against new rules.
+func (u *attributeUseCase) UpdateSchema(ctx context.Context, name string, req *entities.UpdateAttributeSchemaRequest) (*entities.AttributeSchema, error) {
+	schema, err := u.schemaRepo.GetByName(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Label != nil {
+		schema.Label = *req.Label
+	}
+	if req.Rules != nil {
+		schema.Rules = *req.Rules
+	}
+	if req.Visibility != nil {
+		schema.Visibility = *req.Visibility
+	}
+	if req.Required != nil {
+		schema.Required = *req.Required
+	}
+	if err := u.checkSchema(schema); err != nil {
+		return nil, err
+	}
+
+	if err := u.schemaRepo.Update(ctx, schema); err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+// DeleteSchema hides the attribute from every user straight away. The stored
+// values are dropped the next time each user's attributes change.
+func (u *attributeUseCase) DeleteSchema(ctx context.Context, name string) error {
+	return u.schemaRepo.Delete(ctx, name)
+}
+
+func (u *attributeUseCase) checkSchema(schema *entities.AttributeSchema) error {
+	var problems validator.FieldErrors
+	if schema.Rules != "" {
+		if err := u.validator.CheckTag(schema.Rules, attributeSample(schema.Type)); err != nil {
+			problems = append(problems, validator.FieldError{Field: "rules", Code: "invalid", Message: err.Error()})
+		}
+	}
+	// Users cannot set admin attributes, so they could never sign up
+	if schema.Required && schema.Visibility == entities.VisibilityAdmin {
+		problems = append(problems, validator.NewLocalizedFieldError("required", "admin_required", "validation.admin_required"))
+	}
+
+	if len(problems) > 0 {
+		return problems
+	}
+	return nil
+}
@@ -0,0 +1,207 @@
+package usecases
+
+import (
+	"context"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/entities"
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/repositories"
+	"github.com/kaa-dan/clean-architecture-go/pkg/errors"
+	"github.com/kaa-dan/clean-architecture-go/pkg/validator"
+)
+
+const attributeDateLayout = "2006-01-02"
+
+// adminViewer sees every attribute, for endpoints that only admins can reach
+var adminViewer = entities.Viewer{Role: string(entities.RoleAdmin)}
+
+// attributeSet is the current set of attribute schemas, keyed by name.
+// Stored values whose schema has been deleted are never shown and are
+// dropped the next time the user's attributes change.
+type attributeSet map[string]*entities.AttributeSchema
+
+func loadAttributeSet(ctx context.Context, repo repositories.AttributeSchemaRepository) (attributeSet, error) {
+	schemas, err := repo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	set := make(attributeSet, len(schemas))
+	for _, schema := range schemas {
+		set[schema.Name] = schema
+	}
+	return set, nil
+}
+
+// visible returns the attributes of user that viewer may see
+func (s attributeSet) visible(user *entities.User, viewer entities.Viewer) map[string]interface{} {
+	var attributes map[string]interface{}
+	for name, value := range user.Attributes {
+		schema, ok := s[name]
+		if !ok || !viewer.CanSee(schema.Visibility, user.ID.Hex()) {
+			continue
+		}
+		if attributes == nil {
+			attributes = make(map[string]interface{})
+		}
+		attributes[name] = value
+	}
+	return attributes
+}
+
+// response is user.ToResponse with only the attributes viewer may see
+func (s attributeSet) response(user *entities.User, viewer entities.Viewer) entities.UserResponse {
+	response := user.ToResponse()
+	response.Attributes = s.visible(user, viewer)
+	return response
+}
+
+// apply validates the changes viewer makes to the attributes of user and
+// returns the resulting attributes. A nil value removes an attribute. When
+// creating, every required attribute must be present.
+func (s attributeSet) apply(v *validator.Validator, user *entities.User, changes map[string]interface{}, viewer entities.Viewer, creating bool) (map[string]interface{}, error) {
+	attributes := make(map[string]interface{}, len(user.Attributes)+len(changes))
+	for name, value := range user.Attributes {
+		if _, ok := s[name]; ok {
+			attributes[name] = value
+		}
+	}
+
+	names := make([]string, 0, len(changes))
+	for name := range changes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var problems validator.FieldErrors
+	reported := make(map[string]bool)
+	for _, name := range names {
+		field := "attributes." + name
+		reported[name] = true
+		schema, ok := s[name]
+		if !ok {
+			problems = append(problems, validator.NewLocalizedFieldError(field, "undefined", "validation.undefined"))
+			continue
+		}
+		if !viewer.CanChange(schema.Visibility, user.ID.Hex()) {
+			return nil, errors.ErrAttributeNotWritable
+		}
+
+		value := changes[name]
+		if value == nil {
+			if schema.Required {
+				problems = append(problems, validator.NewLocalizedFieldError(field, "required", "validation.required"))
+				continue
+			}
+			delete(attributes, name)
+			continue
+		}
+
+		normalized, ok := normalizeAttribute(schema.Type, value)
+		if !ok {
+			problems = append(problems, validator.NewLocalizedFieldError(field, "type", "validation.type."+string(schema.Type)))
+			continue
+		}
+		if schema.Rules != "" {
+			if err := v.ValidateField(field, normalized, schema.Rules); err != nil {
+				if fieldErrs, ok := err.(validator.FieldErrors); ok {
+					problems = append(problems, fieldErrs...)
+					continue
+				}
+				return nil, err
+			}
+		}
+		attributes[name] = normalized
+	}
+
+	if creating {
+		for _, name := range s.names() {
+			if _, ok := attributes[name]; !ok && s[name].Required && !reported[name] {
+				problems = append(problems, validator.NewLocalizedFieldError("attributes."+name, "required", "validation.required"))
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return nil, problems
+	}
+	if len(attributes) == 0 {
+		return nil, nil
+	}
+	return attributes, nil
+}
+
+func (s attributeSet) names() []string {
+	names := make([]string, 0, len(s))
+	for name := range s {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// normalizeAttribute converts a decoded JSON value to the Go type stored for
+// an attribute type.
+func normalizeAttribute(attributeType entities.AttributeType, value interface{}) (interface{}, bool) {
+	switch attributeType {
+	case entities.AttributeString:
+		s, ok := value.(string)
+		return s, ok
+	case entities.AttributeInteger:
+		switch n := value.(type) {
+		case float64:
+			if n != math.Trunc(n) || math.Abs(n) > 1<<53 {
+				return nil, false
+			}
+			return int64(n), true
+		case int64:
+			return n, true
+		case int32:
+			return int64(n), true
+		case int:
+			return int64(n), true
+		}
+	case entities.AttributeNumber:
+		switch n := value.(type) {
+		case float64:
+			return n, true
+		case int64:
+			return float64(n), true
+		case int32:
+			return float64(n), true
+		case int:
+			return float64(n), true
+		}
+	case entities.AttributeBoolean:
+		b, ok := value.(bool)
+		return b, ok
+	case entities.AttributeDate:
+		s, ok := value.(string)
+		if !ok {
+			return nil, false
+		}
+		if _, err := time.Parse(attributeDateLayout, s); err != nil {
+			return nil, false
+		}
+		return s, true
+	}
+	return nil, false
+}
+
+// attributeSample is a value of the attribute type, used to check rules
+func attributeSample(attributeType entities.AttributeType) interface{} {
+	switch attributeType {
+	case entities.AttributeInteger:
+		return int64(0)
+	case entities.AttributeNumber:
+		return float64(0)
+	case entities.AttributeBoolean:
+		return false
+	case entities.AttributeDate:
+		return "1970-01-01"
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,250 @@
+package usecases
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/entities"
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/events"
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/repositories"
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/services"
+	"github.com/kaa-dan/clean-architecture-go/pkg/errors"
+	"github.com/kaa-dan/clean-architecture-go/pkg/imaging"
+	"github.com/kaa-dan/clean-architecture-go/pkg/logger"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// avatarSizes are the square variants generated for every upload, in pixels
+var avatarSizes = []int{512, 256, 64}
+
+type avatarUseCase struct {
+	userRepo    repositories.UserRepository
+	schemaRepo  repositories.AttributeSchemaRepository
+	searchIndex repositories.UserSearchIndex
+	txManager   repositories.TxManager
+	outbox      repositories.OutboxRepository
+	activity    services.ActivityPublisher
+	blobStore   services.BlobStore
+	maxBytes    int64
+	maxPixels   int
+}
+
+func NewAvatarUseCase(
+	userRepo repositories.UserRepository,
+	schemaRepo repositories.AttributeSchemaRepository,
+	searchIndex repositories.UserSearchIndex,
+	txManager repositories.TxManager,
+	outbox repositories.OutboxRepository,
+	activity services.ActivityPublisher,
+	blobStore services.BlobStore,
+	maxBytes int64,
+	maxPixels int,
+) services.AvatarService {
+	return &avatarUseCase{
+		userRepo:    userRepo,
+		schemaRepo:  schemaRepo,
+		searchIndex: searchIndex,
+		txManager:   txManager,
+		outbox:      outbox,
+		activity:    activity,
+		blobStore:   blobStore,
+		maxBytes:    maxBytes,
+		maxPixels:   maxPixels,
+	}
+}
+
+// UploadAvatar replaces the user's avatar. The image is decoded and
+// re-encoded, which strips EXIF and any other metadata, then stored as a
+// square variant for each of avatarSizes.
+func (u *avatarUseCase) UploadAvatar(ctx context.Context, userID string, data []byte) (*entities.UserResponse, error) {
+	objectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, errors.ErrInvalidUserID
+	}
+	if int64(len(data)) > u.maxBytes {
+		return nil, errors.ErrFileTooLarge
+	}
+
+	img, contentType, err := imaging.Decode(data, u.maxPixels)
+	if err != nil {
+		return nil, imageError(err)
+	}
+
+	avatar := &entities.Avatar{
+		ID:          primitive.NewObjectID().Hex(),
+		ContentType: contentType,
+		UpdatedAt:   time.Now().UTC(),
+	}
+	for _, size := range avatarSizes {
+		var buf bytes.Buffer
+		if err := imaging.Encode(&buf, imaging.SquareThumbnail(img, size), contentType); err != nil {
+			u.deleteBlobs(ctx, avatar)
+			return nil, err
+		}
+
+		key := fmt.Sprintf("avatars/%s/%s/%d%s", objectID.Hex(), avatar.ID, size, imaging.Extension(contentType))
+		if err := u.blobStore.Put(ctx, key, contentType, buf.Bytes()); err != nil {
+			u.deleteBlobs(ctx, avatar)
+			return nil, err
+		}
+		avatar.Variants = append(avatar.Variants, entities.AvatarVariant{
+			Size: size,
+			Key:  key,
+			URL:  u.blobStore.URL(key),
+		})
+	}
+
+	user, previous, err := u.setAvatar(ctx, objectID, avatar)
+	if err != nil {
+		u.deleteBlobs(ctx, avatar)
+		return nil, err
+	}
+	if previous != nil {
+		// The previous images stay until the change commits
+		u.txManager.AfterCommit(ctx, func(ctx context.Context) {
+			u.deleteBlobs(ctx, previous)
+		})
+	}
+
+	return u.response(ctx, user)
+}
+
+func (u *avatarUseCase) DeleteAvatar(ctx context.Context, userID string) (*entities.UserResponse, error) {
+	objectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, errors.ErrInvalidUserID
+	}
+
+	user, previous, err := u.setAvatar(ctx, objectID, nil)
+	if err != nil {
+		return nil, err
+	}
+	u.txManager.AfterCommit(ctx, func(ctx context.Context) {
+		u.deleteBlobs(ctx, previous)
+	})
+
+	return u.response(ctx, user)
+}
+
+func (u *avatarUseCase) Name() string {
+	return "avatars"
+}
+
+func (u *avatarUseCase) ExportPersonalData(ctx context.Context, userID primitive.ObjectID) (interface{}, error) {
+	user, err := u.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.Avatar == nil {
+		return map[string]interface{}{}, nil
+	}
+	return user.Avatar, nil
+}
+
+// ErasePersonalData deletes the stored images. The avatar field goes away
+// with the user document itself.
+func (u *avatarUseCase) ErasePersonalData(ctx context.Context, userID primitive.ObjectID) (entities.ErasureStep, error) {
+	user, err := u.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return entities.ErasureStep{}, err
+	}
+
+	var count int64
+	if user.Avatar != nil {
+		for _, variant := range user.Avatar.Variants {
+			if err := u.blobStore.Delete(ctx, variant.Key); err != nil {
+				return entities.ErasureStep{}, err
+			}
+			count++
+		}
+	}
+	return entities.ErasureStep{Name: u.Name(), Action: "deleted", Count: count}, nil
+}
+
+// setAvatar stores avatar on the user, or removes it when avatar is nil, and
+// returns the updated user together with the avatar it replaced.
+func (u *avatarUseCase) setAvatar(ctx context.Context, id primitive.ObjectID, avatar *entities.Avatar) (*entities.User, *entities.Avatar, error) {
+	var (
+		user     *entities.User
+		previous *entities.Avatar
+	)
+	err := u.txManager.WithinTransaction(ctx, func(ctx context.Context) error {
+		before, err := u.userRepo.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		if avatar == nil && before.Avatar == nil {
+			return errors.ErrAvatarNotFound
+		}
+		previous = before.Avatar
+
+		patch := &entities.UserPatch{Set: map[string]interface{}{}}
+		if avatar != nil {
+			patch.Set["avatar"] = avatar
+		} else {
+			patch.Unset = []string{"avatar"}
+		}
+		user, err = u.userRepo.Patch(ctx, id, before.Version, patch)
+		if err != nil {
+			return err
+		}
+
+		if event := events.NewUserUpdated(before, user); event != nil {
+			return u.outbox.Add(ctx, event)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	u.txManager.AfterCommit(ctx, func(ctx context.Context) {
+		if err := u.searchIndex.Index(ctx, user); err != nil {
+			logger.Warnf("failed to index user %s: %v", id.Hex(), err)
+		}
+		u.activity.Publish(&entities.ActivityEvent{
+			Type:       entities.ActivityUpdated,
+			UserID:     id.Hex(),
+			OccurredAt: time.Now().UTC(),
+			Data:       map[string]interface{}{"changes": []string{"avatar"}},
+		})
+	})
+
+	return user, previous, nil
+}
+
+// response shows the user their own profile
+func (u *avatarUseCase) response(ctx context.Context, user *entities.User) (*entities.UserResponse, error) {
+	attributes, err := loadAttributeSet(ctx, u.schemaRepo)
+	if err != nil {
+		return nil, err
+	}
+
+	response := attributes.response(user, entities.Viewer{UserID: user.ID.Hex(), Role: user.Role})
+	return &response, nil
+}
+
+// deleteBlobs removes the stored variants of an avatar that is no longer
+// referenced. Failures only leave orphaned files behind, so they are logged.
+func (u *avatarUseCase) deleteBlobs(ctx context.Context, avatar *entities.Avatar) {
+	for _, variant := range avatar.Variants {
+		if err := u.blobStore.Delete(ctx, variant.Key); err != nil {
+			logger.Warnf("failed to delete avatar blob %s: %v", variant.Key, err)
+		}
+	}
+}
+
+func imageError(err error) error {
+	switch err {
+	case imaging.ErrUnsupportedType:
+		return errors.ErrUnsupportedMediaType
+	case imaging.ErrTooManyPixels:
+		return errors.ErrImageTooLarge
+	case imaging.ErrInvalidImage:
+		return errors.ErrInvalidImage
+	default:
+		return err
+	}
+}
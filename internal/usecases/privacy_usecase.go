@@ -0,0 +1,320 @@
+package usecases
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/entities"
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/events"
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/repositories"
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/services"
+	"github.com/kaa-dan/clean-architecture-go/internal/infrastructure/security"
+	"github.com/kaa-dan/clean-architecture-go/pkg/errors"
+	"github.com/kaa-dan/clean-architecture-go/pkg/logger"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// An erasure still marked as processing after this long is assumed to
+// belong to a crashed worker and is picked up again.
+const erasureClaimTimeout = 15 * time.Minute
+
+type privacyUseCase struct {
+	userRepo        repositories.UserRepository
+	erasureRepo     repositories.ErasureRepository
+	searchIndex     repositories.UserSearchIndex
+	txManager       repositories.TxManager
+	outbox          repositories.OutboxRepository
+	activity        services.ActivityPublisher
+	passwordManager *security.PasswordManager
+	providers       []repositories.PersonalDataProvider
+	gracePeriod     time.Duration
+}
+
+// NewPrivacyUseCase covers the users collection itself. Every other store
+// that references users must be passed in providers.
+func NewPrivacyUseCase(
+	userRepo repositories.UserRepository,
+	erasureRepo repositories.ErasureRepository,
+	searchIndex repositories.UserSearchIndex,
+	txManager repositories.TxManager,
+	outbox repositories.OutboxRepository,
+	activity services.ActivityPublisher,
+	passwordManager *security.PasswordManager,
+	providers []repositories.PersonalDataProvider,
+	gracePeriod time.Duration,
+) services.PrivacyService {
+	return &privacyUseCase{
+		userRepo:        userRepo,
+		erasureRepo:     erasureRepo,
+		searchIndex:     searchIndex,
+		txManager:       txManager,
+		outbox:          outbox,
+		activity:        activity,
+		passwordManager: passwordManager,
+		providers:       providers,
+		gracePeriod:     gracePeriod,
+	}
+}
+
+func (u *privacyUseCase) ExportPersonalData(ctx context.Context, userID string) (*entities.PersonalDataExport, error) {
+	objectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, errors.ErrInvalidUserID
+	}
+
+	user, err := u.userRepo.GetByID(ctx, objectID)
+	if err != nil {
+		return nil, err
+	}
+
+	export := &entities.PersonalDataExport{
+		GeneratedAt: time.Now().UTC(),
+		UserID:      userID,
+		Sections:    map[string]interface{}{"user": user.ToPersonalRecord()},
+	}
+
+	if request, err := u.erasureRepo.GetByUserID(ctx, objectID); err == nil {
+		export.Sections["erasure_request"] = request.ToResponse()
+	} else if err != errors.ErrErasureNotFound {
+		return nil, err
+	}
+
+	for _, provider := range u.providers {
+		data, err := provider.ExportPersonalData(ctx, objectID)
+		if err != nil {
+			return nil, err
+		}
+		export.Sections[provider.Name()] = data
+	}
+
+	return export, nil
+}
+
+// RequestErasure starts an erasure that only takes effect once it has been
+// confirmed with the returned token. The token is shown exactly once.
+func (u *privacyUseCase) RequestErasure(ctx context.Context, userID string) (*entities.ErasureRequestResponse, error) {
+	objectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, errors.ErrInvalidUserID
+	}
+
+	if _, err := u.userRepo.GetByID(ctx, objectID); err != nil {
+		return nil, err
+	}
+
+	token, err := generateConfirmationToken()
+	if err != nil {
+		return nil, err
+	}
+
+	request := &entities.ErasureRequest{
+		UserID:                objectID,
+		Status:                entities.ErasurePendingConfirmation,
+		ConfirmationTokenHash: hashConfirmationToken(token),
+		RequestedAt:           time.Now(),
+	}
+	if err := u.erasureRepo.Create(ctx, request); err != nil {
+		return nil, err
+	}
+
+	response := request.ToResponse()
+	response.ConfirmationToken = token
+	return &response, nil
+}
+
+// ConfirmErasure requires the token and the current password, then schedules
+// the erasure at the end of the grace period.
+func (u *privacyUseCase) ConfirmErasure(ctx context.Context, userID string, req *entities.ConfirmErasureRequest) (*entities.ErasureRequestResponse, error) {
+	objectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, errors.ErrInvalidUserID
+	}
+
+	request, err := u.erasureRepo.GetByUserID(ctx, objectID)
+	if err != nil {
+		return nil, err
+	}
+	if request.Status != entities.ErasurePendingConfirmation {
+		return nil, errors.ErrErasureNotConfirmable
+	}
+
+	expected := []byte(request.ConfirmationTokenHash)
+	if subtle.ConstantTimeCompare(expected, []byte(hashConfirmationToken(req.Token))) != 1 {
+		return nil, errors.ErrInvalidConfirmationToken
+	}
+
+	user, err := u.userRepo.GetByID(ctx, objectID)
+	if err != nil {
+		return nil, err
+	}
+	if err := u.passwordManager.VerifyPassword(user.Password, req.Password); err != nil {
+		return nil, errors.ErrInvalidCredentials
+	}
+
+	now := time.Now()
+	scheduledFor := now.Add(u.gracePeriod)
+	request.Status = entities.ErasureScheduled
+	request.ConfirmedAt = &now
+	request.ScheduledFor = &scheduledFor
+	request.ConfirmationTokenHash = ""
+	if err := u.erasureRepo.Update(ctx, request); err != nil {
+		return nil, err
+	}
+
+	response := request.ToResponse()
+	return &response, nil
+}
+
+// CancelErasure withdraws a request at any point before processing starts.
+func (u *privacyUseCase) CancelErasure(ctx context.Context, userID string) error {
+	objectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return errors.ErrInvalidUserID
+	}
+
+	request, err := u.erasureRepo.GetByUserID(ctx, objectID)
+	if err != nil {
+		return err
+	}
+	if request.Status == entities.ErasureProcessing {
+		return errors.ErrErasureNotConfirmable
+	}
+
+	return u.erasureRepo.Delete(ctx, request.ID)
+}
+
+func (u *privacyUseCase) GetErasureStatus(ctx context.Context, userID string) (*entities.ErasureRequestResponse, error) {
+	objectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, errors.ErrInvalidUserID
+	}
+
+	request, err := u.erasureRepo.GetByUserID(ctx, objectID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := request.ToResponse()
+	return &response, nil
+}
+
+func (u *privacyUseCase) ProcessDueErasures(ctx context.Context) (int, error) {
+	completed := 0
+	for {
+		now := time.Now()
+		request, err := u.erasureRepo.ClaimDue(ctx, now, now.Add(-erasureClaimTimeout))
+		if err != nil {
+			return completed, err
+		}
+		if request == nil {
+			return completed, nil
+		}
+
+		if err := u.erase(ctx, request); err != nil {
+			return completed, err
+		}
+		completed++
+	}
+}
+
+// erase removes the user from every store and records an untraceable
+// certificate in the same transaction.
+func (u *privacyUseCase) erase(ctx context.Context, request *entities.ErasureRequest) error {
+	certificateID, err := generateCertificateID()
+	if err != nil {
+		return err
+	}
+
+	err = u.txManager.WithinTransaction(ctx, func(ctx context.Context) error {
+		certificate := &entities.ErasureCertificate{
+			ID:          certificateID,
+			RequestedOn: truncateToDay(request.RequestedAt),
+			CompletedOn: truncateToDay(time.Now()),
+		}
+
+		for _, provider := range u.providers {
+			step, err := provider.ErasePersonalData(ctx, request.UserID)
+			if err != nil {
+				return err
+			}
+			certificate.Steps = append(certificate.Steps, step)
+		}
+
+		userStep := entities.ErasureStep{Name: "users", Action: "deleted"}
+		user, err := u.userRepo.GetByID(ctx, request.UserID)
+		if err != nil && err != errors.ErrUserNotFound {
+			return err
+		}
+		if user != nil {
+			if err := u.userRepo.Delete(ctx, request.UserID); err != nil {
+				return err
+			}
+			userStep.Count = 1
+
+			// The event carries only the user ID
+			if err := u.outbox.Add(ctx, events.NewUserDeleted(user.ID, user.Version)); err != nil {
+				return err
+			}
+		}
+		certificate.Steps = append(certificate.Steps, userStep,
+			entities.ErasureStep{Name: "erasure_requests", Action: "deleted", Count: 1})
+
+		if err := u.erasureRepo.Delete(ctx, request.ID); err != nil {
+			return err
+		}
+		return u.erasureRepo.CreateCertificate(ctx, certificate)
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := u.searchIndex.Remove(ctx, request.UserID); err != nil {
+		logger.Warnf("failed to remove erased user from search index: %v", err)
+	}
+	u.activity.Forget(request.UserID.Hex())
+	u.activity.Publish(&entities.ActivityEvent{
+		Type:       entities.ActivityDeleted,
+		UserID:     request.UserID.Hex(),
+		OccurredAt: time.Now().UTC(),
+	})
+
+	logger.Infof("completed erasure, certificate %s", certificateID)
+	return nil
+}
+
+func (u *privacyUseCase) ListErasureCertificates(ctx context.Context, limit, offset int) ([]*entities.ErasureCertificate, error) {
+	return u.erasureRepo.ListCertificates(ctx, limit, offset)
+}
+
+func generateConfirmationToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func hashConfirmationToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// generateCertificateID is random rather than an ObjectID, whose embedded
+// timestamp could be matched against request logs.
+func generateCertificateID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+func truncateToDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
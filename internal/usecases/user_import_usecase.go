@@ -0,0 +1,328 @@
+package usecases
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	stderrors "errors"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/entities"
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/events"
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/repositories"
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/services"
+	"github.com/kaa-dan/clean-architecture-go/internal/infrastructure/security"
+	"github.com/kaa-dan/clean-architecture-go/pkg/errors"
+	"github.com/kaa-dan/clean-architecture-go/pkg/identity"
+	"github.com/kaa-dan/clean-architecture-go/pkg/logger"
+	"github.com/kaa-dan/clean-architecture-go/pkg/validator"
+)
+
+type userImportUseCase struct {
+	userRepo         repositories.UserRepository
+	searchIndex      repositories.UserSearchIndex
+	txManager        repositories.TxManager
+	outbox           repositories.OutboxRepository
+	canonicalizer    *identity.Canonicalizer
+	passwordManager  *security.PasswordManager
+	invitationSender services.InvitationSender
+	validator        *validator.Validator
+}
+
+func NewUserImportUseCase(
+	userRepo repositories.UserRepository,
+	searchIndex repositories.UserSearchIndex,
+	txManager repositories.TxManager,
+	outbox repositories.OutboxRepository,
+	canonicalizer *identity.Canonicalizer,
+	passwordManager *security.PasswordManager,
+	invitationSender services.InvitationSender,
+) services.UserImportService {
+	return &userImportUseCase{
+		userRepo:         userRepo,
+		searchIndex:      searchIndex,
+		txManager:        txManager,
+		outbox:           outbox,
+		canonicalizer:    canonicalizer,
+		passwordManager:  passwordManager,
+		invitationSender: invitationSender,
+		validator:        validator.New(),
+	}
+}
+
+// ImportUsers processes every row independently and reports the outcome of
+// each one instead of failing the whole batch.
+func (u *userImportUseCase) ImportUsers(ctx context.Context, source services.ImportSource, opts entities.ImportOptions) (*entities.ImportReport, error) {
+	if opts.OnConflict == "" {
+		opts.OnConflict = entities.ImportConflictSkip
+	}
+	if opts.OnConflict != entities.ImportConflictSkip && opts.OnConflict != entities.ImportConflictUpsert {
+		return nil, errors.ErrBadRequest
+	}
+
+	report := &entities.ImportReport{DryRun: opts.DryRun, Rows: []entities.ImportRowResult{}}
+
+	// Canonical keys of rows seen earlier in the same file, to catch
+	// duplicates in dry runs
+	seenEmails := make(map[string]int)
+	seenUsernames := make(map[string]int)
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		record, err := source.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil && !stderrors.Is(err, errors.ErrMalformedRow) {
+			return nil, err
+		}
+
+		var result entities.ImportRowResult
+		if err != nil {
+			result = entities.ImportRowResult{Row: report.Total + 1, Status: entities.ImportRowFailed, Errors: []string{rowError(report.Total+1, err)}}
+		} else {
+			result = u.importRecord(ctx, record, opts, seenEmails, seenUsernames)
+		}
+
+		report.Total++
+		switch result.Status {
+		case entities.ImportRowCreated:
+			report.Created++
+		case entities.ImportRowUpdated:
+			report.Updated++
+		case entities.ImportRowSkipped:
+			report.Skipped++
+		case entities.ImportRowValid:
+			report.Valid++
+		case entities.ImportRowFailed:
+			report.Failed++
+		}
+		report.Rows = append(report.Rows, result)
+	}
+
+	return report, nil
+}
+
+func (u *userImportUseCase) importRecord(
+	ctx context.Context,
+	record *entities.ImportUserRecord,
+	opts entities.ImportOptions,
+	seenEmails, seenUsernames map[string]int,
+) entities.ImportRowResult {
+	result := entities.ImportRowResult{
+		Row:      record.Row,
+		Email:    record.Email,
+		Username: record.Username,
+	}
+	fail := func(messages ...string) entities.ImportRowResult {
+		result.Status = entities.ImportRowFailed
+		result.Errors = messages
+		return result
+	}
+
+	if messages := u.validateRecord(record, opts); len(messages) > 0 {
+		return fail(messages...)
+	}
+
+	emailKey := u.canonicalizer.Email(record.Email)
+	usernameKey := u.canonicalizer.Username(record.Username)
+	if row, ok := seenEmails[emailKey]; ok {
+		return fail("email duplicates row " + strconv.Itoa(row))
+	}
+	if row, ok := seenUsernames[usernameKey]; ok {
+		return fail("username duplicates row " + strconv.Itoa(row))
+	}
+	seenEmails[emailKey] = record.Row
+	seenUsernames[usernameKey] = record.Row
+
+	// Hashing is skipped in dry runs, it is by far the slowest step
+	var (
+		passwordHash      string
+		temporaryPassword string
+		err               error
+	)
+	if !opts.DryRun {
+		passwordHash, temporaryPassword, err = u.resolvePassword(record)
+		if err != nil {
+			return fail(rowError(record.Row, err))
+		}
+	}
+
+	var (
+		user   *entities.User
+		status entities.ImportRowStatus
+	)
+	err = u.txManager.WithinTransaction(ctx, func(ctx context.Context) error {
+		existing, err := u.userRepo.GetByEmail(ctx, record.Email)
+		if err != nil && err != errors.ErrUserNotFound {
+			return err
+		}
+
+		if byUsername, err := u.userRepo.GetByUsername(ctx, record.Username); err == nil {
+			if existing == nil || byUsername.ID != existing.ID {
+				return errors.ErrUsernameAlreadyExists
+			}
+		} else if err != errors.ErrUserNotFound {
+			return err
+		}
+
+		switch {
+		case existing != nil && opts.OnConflict == entities.ImportConflictSkip:
+			user, status = existing, entities.ImportRowSkipped
+			return nil
+		case opts.DryRun:
+			user, status = existing, entities.ImportRowValid
+			return nil
+		case existing != nil:
+			// Columns a row leaves empty keep the user's value
+			before := *existing
+			existing.Username = record.Username
+			if record.FirstName != "" {
+				existing.FirstName = record.FirstName
+			}
+			if record.LastName != "" {
+				existing.LastName = record.LastName
+			}
+			if record.Role != "" {
+				existing.Role = record.Role
+			}
+			if record.Password != "" || record.PasswordHash != "" {
+				existing.Password = passwordHash
+			}
+			user, status = existing, entities.ImportRowUpdated
+			if err := u.userRepo.Update(ctx, existing.ID, existing); err != nil {
+				return err
+			}
+			if event := events.NewUserUpdated(&before, existing); event != nil {
+				return u.outbox.Add(ctx, event)
+			}
+			return nil
+		default:
+			role := record.Role
+			if role == "" {
+				role = string(entities.RoleUser)
+			}
+			user = &entities.User{
+				Email:     record.Email,
+				Username:  record.Username,
+				Password:  passwordHash,
+				FirstName: record.FirstName,
+				LastName:  record.LastName,
+				IsActive:  true,
+				Role:      role,
+				CreatedAt: time.Now(),
+				UpdatedAt: time.Now(),
+			}
+			status = entities.ImportRowCreated
+			if err := u.userRepo.Create(ctx, user); err != nil {
+				return err
+			}
+			return u.outbox.Add(ctx, events.NewUserRegistered(user))
+		}
+	})
+	if err != nil {
+		return fail(rowError(record.Row, err))
+	}
+
+	result.Status = status
+	if user != nil {
+		result.UserID = user.ID.Hex()
+	}
+
+	if status == entities.ImportRowCreated || status == entities.ImportRowUpdated {
+		if err := u.searchIndex.Index(ctx, user); err != nil {
+			logger.Warnf("failed to index user %s: %v", user.ID.Hex(), err)
+		}
+	}
+
+	if status == entities.ImportRowCreated && temporaryPassword != "" {
+		if err := u.invitationSender.SendInvitation(ctx, user, temporaryPassword); err != nil {
+			result.Errors = append(result.Errors, "user created but invitation failed: "+rowError(record.Row, err))
+		} else {
+			result.Invited = true
+		}
+	}
+
+	return result
+}
+
+// validateRecord applies the SignUpRequest rules to a row. The password rule
+// is skipped when a pre-hashed password or an invitation replaces it.
+func (u *userImportUseCase) validateRecord(record *entities.ImportUserRecord, opts entities.ImportOptions) []string {
+	req := entities.SignUpRequest{
+		Email:     record.Email,
+		Username:  record.Username,
+		Password:  record.Password,
+		FirstName: record.FirstName,
+		LastName:  record.LastName,
+	}
+
+	var messages []string
+	var err error
+	switch {
+	case record.Password != "" && record.PasswordHash != "":
+		messages = append(messages, "password and password_hash are mutually exclusive")
+		err = u.validator.ValidateExcept(&req, "Password")
+	case record.Password != "":
+		err = u.validator.Validate(&req)
+	case record.PasswordHash != "":
+		if hashErr := u.passwordManager.ValidateHash(record.PasswordHash); hashErr != nil {
+			messages = append(messages, "password_hash must be a bcrypt hash")
+		}
+		err = u.validator.ValidateExcept(&req, "Password")
+	case opts.SendInvitations:
+		err = u.validator.ValidateExcept(&req, "Password")
+	default:
+		messages = append(messages, "password or password_hash is required unless invitations are sent")
+		err = u.validator.ValidateExcept(&req, "Password")
+	}
+	if err != nil {
+		messages = append(messages, validator.ErrorMessages(err)...)
+	}
+
+	if err := u.validator.ValidateVar(record.Role, "omitempty,oneof=user admin"); err != nil {
+		messages = append(messages, "role must be one of: user, admin")
+	}
+
+	return messages
+}
+
+// resolvePassword returns the hash to store and, for invited users, the
+// generated temporary password.
+func (u *userImportUseCase) resolvePassword(record *entities.ImportUserRecord) (string, string, error) {
+	switch {
+	case record.PasswordHash != "":
+		return record.PasswordHash, "", nil
+	case record.Password != "":
+		hash, err := u.passwordManager.HashPassword(record.Password)
+		return hash, "", err
+	default:
+		temporaryPassword, err := generateTemporaryPassword()
+		if err != nil {
+			return "", "", err
+		}
+		hash, err := u.passwordManager.HashPassword(temporaryPassword)
+		return hash, temporaryPassword, err
+	}
+}
+
+// rowError is the message a failed row reports. Reports are returned to the
+// caller, so the raw error is only logged.
+func rowError(row int, err error) string {
+	logger.Warnf("import row %d failed: %v", row, err)
+	return errors.Describe(err).Message
+}
+
+func generateTemporaryPassword() (string, error) {
+	buf := make([]byte, 18)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return strings.TrimRight(base64.URLEncoding.EncodeToString(buf), "="), nil
+}
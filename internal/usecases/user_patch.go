@@ -0,0 +1,227 @@
+package usecases
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"reflect"
+
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/entities"
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/events"
+	"github.com/kaa-dan/clean-architecture-go/pkg/errors"
+	"github.com/kaa-dan/clean-architecture-go/pkg/jsonpatch"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type patchableField struct {
+	bsonName string
+	nullable bool
+	get      func(user *entities.User) interface{}
+}
+
+// patchableUserFields whitelists the JSON fields a patch may touch. Values are
+// validated with the rules on entities.UpdateUserRequest. Custom attributes
+// can also be patched under /attributes and are checked against their schemas.
+var patchableUserFields = map[string]patchableField{
+	"first_name": {bsonName: "first_name", get: func(u *entities.User) interface{} { return u.FirstName }},
+	"last_name":  {bsonName: "last_name", get: func(u *entities.User) interface{} { return u.LastName }},
+	"username":   {bsonName: "username", get: func(u *entities.User) interface{} { return u.Username }},
+	"locale":     {bsonName: "locale", nullable: true, get: func(u *entities.User) interface{} { return u.Locale }},
+}
+
+func (u *userUseCase) PatchUser(ctx context.Context, id string, req *entities.PatchUserRequest) (*entities.UserResponse, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.ErrInvalidUserID
+	}
+
+	attributes, err := loadAttributeSet(ctx, u.schemaRepo)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		updated *entities.User
+		changes []string
+	)
+	err = u.txManager.WithinTransaction(ctx, func(ctx context.Context) error {
+		user, err := u.userRepo.GetByID(ctx, objectID)
+		if err != nil {
+			return err
+		}
+
+		if req.ExpectedVersion != nil && *req.ExpectedVersion != user.Version {
+			return errors.ErrVersionConflict
+		}
+
+		// Unset nullable fields are absent, as in the JSON of the user
+		original := make(map[string]interface{}, len(patchableUserFields)+1)
+		for name, field := range patchableUserFields {
+			value := field.get(user)
+			if field.nullable && reflect.ValueOf(value).IsZero() {
+				continue
+			}
+			original[name] = value
+		}
+		// Attributes the caller cannot see are left out, and kept as they are
+		visible := attributes.visible(user, req.Viewer)
+		if visible == nil {
+			visible = map[string]interface{}{}
+		}
+		original["attributes"] = visible
+
+		patched, err := applyPatch(original, req)
+		if err != nil {
+			return err
+		}
+
+		attributeChanges, err := patchedAttributes(visible, patched)
+		if err != nil {
+			return err
+		}
+		delete(original, "attributes")
+		delete(patched, "attributes")
+
+		patch, err := u.buildUserPatch(original, patched)
+		if err != nil {
+			return err
+		}
+
+		if len(attributeChanges) > 0 {
+			values, err := attributes.apply(u.validator, user, attributeChanges, req.Viewer, false)
+			if err != nil {
+				return err
+			}
+			patch.Set["attributes"] = values
+		}
+
+		if len(patch.Set) == 0 && len(patch.Unset) == 0 {
+			updated = user
+			return nil
+		}
+
+		if username, ok := patch.Set["username"].(string); ok {
+			if existingUser, err := u.userRepo.GetByUsername(ctx, username); err == nil && existingUser.ID != objectID {
+				return errors.ErrUsernameAlreadyExists
+			}
+		}
+
+		updated, err = u.userRepo.Patch(ctx, objectID, user.Version, patch)
+		if err != nil {
+			return err
+		}
+
+		changes = events.ChangedFields(user, updated)
+		if event := events.NewUserUpdated(user, updated); event != nil {
+			return u.outbox.Add(ctx, event)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	u.indexUser(ctx, updated)
+	if len(changes) > 0 {
+		u.publishActivity(ctx, entities.ActivityUpdated, updated, map[string]interface{}{"changes": changes})
+	}
+
+	response := attributes.response(updated, req.Viewer)
+	return &response, nil
+}
+
+func applyPatch(document map[string]interface{}, req *entities.PatchUserRequest) (map[string]interface{}, error) {
+	var (
+		patched map[string]interface{}
+		err     error
+	)
+
+	switch req.ContentType {
+	case jsonpatch.MergePatchContentType:
+		patched, err = jsonpatch.MergePatch(document, req.Document)
+	case jsonpatch.JSONPatchContentType:
+		patched, err = jsonpatch.Apply(document, req.Document)
+	default:
+		return nil, errors.ErrUnsupportedMediaType
+	}
+
+	switch {
+	case err == nil:
+		return patched, nil
+	case stderrors.Is(err, jsonpatch.ErrTestFailed):
+		return nil, errors.ErrPatchTestFailed
+	default:
+		return nil, errors.ErrInvalidPatch
+	}
+}
+
+// patchedAttributes compares the attributes in a patched document with the
+// original ones and returns the changes, with nil for removed attributes.
+func patchedAttributes(original map[string]interface{}, patched map[string]interface{}) (map[string]interface{}, error) {
+	values := map[string]interface{}{}
+	if value, ok := patched["attributes"]; ok && value != nil {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, errors.ErrInvalidPatch
+		}
+		values = object
+	}
+
+	changes := make(map[string]interface{})
+	for name, value := range values {
+		if !jsonpatch.Equal(value, original[name]) {
+			changes[name] = value
+		}
+	}
+	for name := range original {
+		if _, ok := values[name]; !ok {
+			changes[name] = nil
+		}
+	}
+	return changes, nil
+}
+
+// buildUserPatch validates the patched document and turns the differences
+// from the original document into a field-level change set.
+func (u *userUseCase) buildUserPatch(original, patched map[string]interface{}) (*entities.UserPatch, error) {
+	for name := range patched {
+		if _, ok := patchableUserFields[name]; !ok {
+			return nil, errors.ErrFieldNotPatchable
+		}
+	}
+
+	// Decode into the update request so patches share the PUT validation rules
+	encoded, err := json.Marshal(patched)
+	if err != nil {
+		return nil, errors.ErrInvalidPatch
+	}
+
+	var req entities.UpdateUserRequest
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		return nil, errors.ErrInvalidPatch
+	}
+
+	if err := u.validator.Validate(&req); err != nil {
+		return nil, err
+	}
+
+	patch := &entities.UserPatch{Set: map[string]interface{}{}}
+	for name, field := range patchableUserFields {
+		value, ok := patched[name]
+		switch {
+		case !ok:
+			if !field.nullable {
+				return nil, errors.ErrFieldNotPatchable
+			}
+			if _, existed := original[name]; existed {
+				patch.Unset = append(patch.Unset, field.bsonName)
+			}
+		case !jsonpatch.Equal(value, original[name]):
+			patch.Set[field.bsonName] = value
+		}
+	}
+
+	return patch, nil
+}
@@ -5,64 +5,108 @@ import (
 	"time"
 
 	"github.com/kaa-dan/clean-architecture-go/internal/domain/entities"
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/events"
 	"github.com/kaa-dan/clean-architecture-go/internal/domain/repositories"
 	"github.com/kaa-dan/clean-architecture-go/internal/domain/services"
 
 	"github.com/kaa-dan/clean-architecture-go/internal/infrastructure/security"
 	"github.com/kaa-dan/clean-architecture-go/pkg/errors"
+	"github.com/kaa-dan/clean-architecture-go/pkg/logger"
+	"github.com/kaa-dan/clean-architecture-go/pkg/validator"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type userUseCase struct {
 	userRepo        repositories.UserRepository
+	schemaRepo      repositories.AttributeSchemaRepository
+	searchIndex     repositories.UserSearchIndex
+	txManager       repositories.TxManager
+	outbox          repositories.OutboxRepository
+	activity        services.ActivityPublisher
 	jwtManager      *security.JWTManager
 	passwordManager *security.PasswordManager
+	validator       *validator.Validator
 }
 
 func NewUserUseCase(
 	userRepo repositories.UserRepository,
+	schemaRepo repositories.AttributeSchemaRepository,
+	searchIndex repositories.UserSearchIndex,
+	txManager repositories.TxManager,
+	outbox repositories.OutboxRepository,
+	activity services.ActivityPublisher,
 	jwtManager *security.JWTManager,
 	passwordManager *security.PasswordManager,
 ) services.UserService {
 	return &userUseCase{
 		userRepo:        userRepo,
+		schemaRepo:      schemaRepo,
+		searchIndex:     searchIndex,
+		txManager:       txManager,
+		outbox:          outbox,
+		activity:        activity,
 		jwtManager:      jwtManager,
 		passwordManager: passwordManager,
+		validator:       validator.New(),
 	}
 }
 
 func (u *userUseCase) SignUp(ctx context.Context, req *entities.SignUpRequest) (*entities.AuthResponse, error) {
-	// Check if user already exists
-	if _, err := u.userRepo.GetByEmail(ctx, req.Email); err == nil {
-		return nil, errors.ErrUserAlreadyExists
-	}
-
-	if _, err := u.userRepo.GetByUsername(ctx, req.Username); err == nil {
-		return nil, errors.ErrUsernameAlreadyExists
-	}
-
 	// Hash password
 	hashedPassword, err := u.passwordManager.HashPassword(req.Password)
 	if err != nil {
 		return nil, err
 	}
 
+	attributes, err := loadAttributeSet(ctx, u.schemaRepo)
+	if err != nil {
+		return nil, err
+	}
+
 	// Create user
 	user := &entities.User{
+		ID:        primitive.NewObjectID(),
 		Email:     req.Email,
 		Username:  req.Username,
 		Password:  hashedPassword,
 		FirstName: req.FirstName,
 		LastName:  req.LastName,
+		Locale:    req.Locale,
 		IsActive:  true,
 		Role:      string(entities.RoleUser),
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}
+	viewer := entities.Viewer{UserID: user.ID.Hex(), Role: user.Role}
+	user.Attributes, err = attributes.apply(u.validator, user, req.Attributes, viewer, true)
+	if err != nil {
+		return nil, err
+	}
+
+	err = u.txManager.WithinTransaction(ctx, func(ctx context.Context) error {
+		// Check if user already exists
+		if _, err := u.userRepo.GetByEmail(ctx, req.Email); err == nil {
+			return errors.ErrUserAlreadyExists
+		}
 
-	if err := u.userRepo.Create(ctx, user); err != nil {
+		if _, err := u.userRepo.GetByUsername(ctx, req.Username); err == nil {
+			return errors.ErrUsernameAlreadyExists
+		}
+
+		if err := u.userRepo.Create(ctx, user); err != nil {
+			return err
+		}
+
+		return u.outbox.Add(ctx, events.NewUserRegistered(user))
+	})
+	if err != nil {
 		return nil, err
 	}
+	u.indexUser(ctx, user)
+	u.publishActivity(ctx, entities.ActivitySignedUp, user, map[string]interface{}{
+		"email":    user.Email,
+		"username": user.Username,
+	})
 
 	// Generate token
 	token, err := u.jwtManager.GenerateToken(user)
@@ -70,10 +114,7 @@ func (u *userUseCase) SignUp(ctx context.Context, req *entities.SignUpRequest) (
 		return nil, err
 	}
 
-	return &entities.AuthResponse{
-		Token: token,
-		User:  *user,
-	}, nil
+	return u.authResponse(token, user, attributes), nil
 }
 
 func (u *userUseCase) SignIn(ctx context.Context, req *entities.SignInRequest) (*entities.AuthResponse, error) {
@@ -98,14 +139,18 @@ func (u *userUseCase) SignIn(ctx context.Context, req *entities.SignInRequest) (
 	if err != nil {
 		return nil, err
 	}
+	u.publishActivity(ctx, entities.ActivitySignedIn, user, map[string]interface{}{
+		"username": user.Username,
+	})
 
-	return &entities.AuthResponse{
-		Token: token,
-		User:  *user,
-	}, nil
+	attributes, err := loadAttributeSet(ctx, u.schemaRepo)
+	if err != nil {
+		return nil, err
+	}
+	return u.authResponse(token, user, attributes), nil
 }
 
-func (u *userUseCase) GetUserByID(ctx context.Context, id string) (*entities.UserResponse, error) {
+func (u *userUseCase) GetUserByID(ctx context.Context, id string, viewer entities.Viewer) (*entities.UserResponse, error) {
 	objectID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return nil, errors.ErrInvalidUserID
@@ -116,59 +161,150 @@ func (u *userUseCase) GetUserByID(ctx context.Context, id string) (*entities.Use
 		return nil, err
 	}
 
-	response := user.ToResponse()
+	attributes, err := loadAttributeSet(ctx, u.schemaRepo)
+	if err != nil {
+		return nil, err
+	}
+
+	response := attributes.response(user, viewer)
 	return &response, nil
 }
 
-func (u *userUseCase) GetAllUsers(ctx context.Context, limit, offset int) ([]*entities.UserResponse, error) {
-	users, err := u.userRepo.GetAll(ctx, limit, offset)
+func (u *userUseCase) GetUsersByIDs(ctx context.Context, ids []string, viewer entities.Viewer) (map[string]*entities.UserResponse, error) {
+	objectIDs := make([]primitive.ObjectID, 0, len(ids))
+	for _, id := range ids {
+		objectID, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			return nil, errors.ErrInvalidUserID
+		}
+		objectIDs = append(objectIDs, objectID)
+	}
+
+	users, err := u.userRepo.GetByIDs(ctx, objectIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	attributes, err := loadAttributeSet(ctx, u.schemaRepo)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make(map[string]*entities.UserResponse, len(users))
+	for _, user := range users {
+		response := attributes.response(user, viewer)
+		responses[response.ID] = &response
+	}
+	return responses, nil
+}
+
+func (u *userUseCase) GetAllUsers(ctx context.Context, filter entities.UserFilter, limit, offset int) ([]*entities.UserResponse, error) {
+	attributes, err := loadAttributeSet(ctx, u.schemaRepo)
+	if err != nil {
+		return nil, err
+	}
+
+	users, err := u.userRepo.GetAll(ctx, filter, limit, offset)
 	if err != nil {
 		return nil, err
 	}
 
 	var responses []*entities.UserResponse
 	for _, user := range users {
-		response := user.ToResponse()
+		response := attributes.response(user, adminViewer)
 		responses = append(responses, &response)
 	}
 
 	return responses, nil
 }
 
+// ExportUsers streams every matching user as a response DTO, which never
+// contains the password hash.
+func (u *userUseCase) ExportUsers(ctx context.Context, filter entities.UserFilter, fn func(user *entities.UserResponse) error) error {
+	attributes, err := loadAttributeSet(ctx, u.schemaRepo)
+	if err != nil {
+		return err
+	}
+
+	return u.userRepo.Stream(ctx, filter, func(user *entities.User) error {
+		response := attributes.response(user, adminViewer)
+		return fn(&response)
+	})
+}
+
 func (u *userUseCase) UpdateUser(ctx context.Context, id string, req *entities.UpdateUserRequest) (*entities.UserResponse, error) {
 	objectID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return nil, errors.ErrInvalidUserID
 	}
 
-	// Get existing user
-	user, err := u.userRepo.GetByID(ctx, objectID)
+	attributes, err := loadAttributeSet(ctx, u.schemaRepo)
 	if err != nil {
 		return nil, err
 	}
 
-	// Update fields if provided
-	if req.FirstName != nil {
-		user.FirstName = *req.FirstName
-	}
-	if req.LastName != nil {
-		user.LastName = *req.LastName
-	}
-	if req.Username != nil {
-		// Check if username is already taken by another user
-		if existingUser, err := u.userRepo.GetByUsername(ctx, *req.Username); err == nil && existingUser.ID != objectID {
-			return nil, errors.ErrUsernameAlreadyExists
+	var (
+		user    *entities.User
+		changes []string
+	)
+	err = u.txManager.WithinTransaction(ctx, func(ctx context.Context) error {
+		// Get existing user
+		var err error
+		user, err = u.userRepo.GetByID(ctx, objectID)
+		if err != nil {
+			return err
+		}
+
+		if req.ExpectedVersion != nil && *req.ExpectedVersion != user.Version {
+			return errors.ErrVersionConflict
+		}
+		before := user.Clone()
+
+		// Update fields if provided
+		if req.FirstName != nil {
+			user.FirstName = *req.FirstName
+		}
+		if req.LastName != nil {
+			user.LastName = *req.LastName
+		}
+		if req.Locale != nil {
+			user.Locale = *req.Locale
+		}
+		if req.Username != nil {
+			// Check if username is already taken by another user
+			if existingUser, err := u.userRepo.GetByUsername(ctx, *req.Username); err == nil && existingUser.ID != objectID {
+				return errors.ErrUsernameAlreadyExists
+			}
+			user.Username = *req.Username
+		}
+		if req.Attributes != nil {
+			user.Attributes, err = attributes.apply(u.validator, user, req.Attributes, req.Viewer, false)
+			if err != nil {
+				return err
+			}
 		}
-		user.Username = *req.Username
-	}
 
-	user.UpdatedAt = time.Now()
+		user.UpdatedAt = time.Now()
+
+		if err := u.userRepo.Update(ctx, objectID, user); err != nil {
+			return err
+		}
 
-	if err := u.userRepo.Update(ctx, objectID, user); err != nil {
+		changes = events.ChangedFields(before, user)
+		if event := events.NewUserUpdated(before, user); event != nil {
+			return u.outbox.Add(ctx, event)
+		}
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
+	u.indexUser(ctx, user)
+	if len(changes) > 0 {
+		u.publishActivity(ctx, entities.ActivityUpdated, user, map[string]interface{}{"changes": changes})
+	}
 
-	response := user.ToResponse()
+	response := attributes.response(user, req.Viewer)
 	return &response, nil
 }
 
@@ -178,5 +314,87 @@ func (u *userUseCase) DeleteUser(ctx context.Context, id string) error {
 		return errors.ErrInvalidUserID
 	}
 
-	return u.userRepo.Delete(ctx, objectID)
+	err = u.txManager.WithinTransaction(ctx, func(ctx context.Context) error {
+		user, err := u.userRepo.GetByID(ctx, objectID)
+		if err != nil {
+			return err
+		}
+
+		if err := u.userRepo.Delete(ctx, objectID); err != nil {
+			return err
+		}
+
+		return u.outbox.Add(ctx, events.NewUserDeleted(objectID, user.Version))
+	})
+	if err != nil {
+		return err
+	}
+
+	u.txManager.AfterCommit(ctx, func(ctx context.Context) {
+		if err := u.searchIndex.Remove(ctx, objectID); err != nil {
+			logger.Warnf("failed to remove user %s from search index: %v", id, err)
+		}
+		u.activity.Publish(&entities.ActivityEvent{
+			Type:       entities.ActivityDeleted,
+			UserID:     id,
+			OccurredAt: time.Now().UTC(),
+		})
+	})
+
+	return nil
+}
+
+func (u *userUseCase) SearchUsers(ctx context.Context, query string, limit int) ([]*entities.UserSearchResult, error) {
+	attributes, err := loadAttributeSet(ctx, u.schemaRepo)
+	if err != nil {
+		return nil, err
+	}
+
+	hits, err := u.searchIndex.Search(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*entities.UserSearchResult, 0, len(hits))
+	for _, hit := range hits {
+		results = append(results, &entities.UserSearchResult{
+			User:       attributes.response(hit.User, adminViewer),
+			Score:      hit.Score,
+			Highlights: hit.Highlights,
+		})
+	}
+
+	return results, nil
+}
+
+// authResponse shows the signed in user only the attributes they may see
+func (u *userUseCase) authResponse(token string, user *entities.User, attributes attributeSet) *entities.AuthResponse {
+	shown := user.Clone()
+	shown.Attributes = attributes.visible(user, entities.Viewer{UserID: user.ID.Hex(), Role: user.Role})
+	return &entities.AuthResponse{
+		Token: token,
+		User:  *shown,
+	}
+}
+
+// indexUser keeps the search index in sync. Indexing failures are logged
+// rather than returned because the user has already been persisted. Inside
+// an enclosing transaction the user is indexed once it commits.
+func (u *userUseCase) indexUser(ctx context.Context, user *entities.User) {
+	u.txManager.AfterCommit(ctx, func(ctx context.Context) {
+		if err := u.searchIndex.Index(ctx, user); err != nil {
+			logger.Warnf("failed to index user %s: %v", user.ID.Hex(), err)
+		}
+	})
+}
+
+func (u *userUseCase) publishActivity(ctx context.Context, activityType entities.ActivityType, user *entities.User, data map[string]interface{}) {
+	u.txManager.AfterCommit(ctx, func(ctx context.Context) {
+		u.activity.Publish(&entities.ActivityEvent{
+			Type:       activityType,
+			UserID:     user.ID.Hex(),
+			OccurredAt: time.Now().UTC(),
+			Data:       data,
+		})
+	})
 }
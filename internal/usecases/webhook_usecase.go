@@ -0,0 +1,312 @@
+package usecases
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/entities"
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/events"
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/repositories"
+	"github.com/kaa-dan/clean-architecture-go/internal/domain/services"
+	"github.com/kaa-dan/clean-architecture-go/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	webhookMaxAttempts   = 8
+	webhookBaseBackoff   = 30 * time.Second
+	webhookMaxBackoff    = 6 * time.Hour
+	webhookDeliveryLock  = time.Minute
+	webhookLogSize       = 20
+	webhookDeliveryBatch = 100
+)
+
+type webhookUseCase struct {
+	webhookRepo  repositories.WebhookRepository
+	deliveryRepo repositories.WebhookDeliveryRepository
+	txManager    repositories.TxManager
+	sender       services.WebhookSender
+}
+
+func NewWebhookUseCase(
+	webhookRepo repositories.WebhookRepository,
+	deliveryRepo repositories.WebhookDeliveryRepository,
+	txManager repositories.TxManager,
+	sender services.WebhookSender,
+) services.WebhookService {
+	return &webhookUseCase{
+		webhookRepo:  webhookRepo,
+		deliveryRepo: deliveryRepo,
+		txManager:    txManager,
+		sender:       sender,
+	}
+}
+
+// CreateWebhook generates the signing secret, which is only returned here.
+func (u *webhookUseCase) CreateWebhook(ctx context.Context, req *entities.CreateWebhookRequest) (*entities.WebhookResponse, error) {
+	secret, err := generateWebhookSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	webhook := &entities.Webhook{
+		URL:         req.URL,
+		Events:      req.Events,
+		Description: req.Description,
+		Secret:      secret,
+		IsActive:    true,
+	}
+	if err := u.webhookRepo.Create(ctx, webhook); err != nil {
+		return nil, err
+	}
+
+	response := webhook.ToResponse()
+	response.Secret = secret
+	return &response, nil
+}
+
+func (u *webhookUseCase) GetWebhook(ctx context.Context, id string) (*entities.WebhookResponse, error) {
+	webhook, err := u.getWebhook(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	response := webhook.ToResponse()
+	return &response, nil
+}
+
+func (u *webhookUseCase) GetAllWebhooks(ctx context.Context, limit, offset int) ([]*entities.WebhookResponse, error) {
+	webhooks, err := u.webhookRepo.GetAll(ctx, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]*entities.WebhookResponse, 0, len(webhooks))
+	for _, webhook := range webhooks {
+		response := webhook.ToResponse()
+		responses = append(responses, &response)
+	}
+
+	return responses, nil
+}
+
+func (u *webhookUseCase) UpdateWebhook(ctx context.Context, id string, req *entities.UpdateWebhookRequest) (*entities.WebhookResponse, error) {
+	webhook, err := u.getWebhook(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Update fields if provided
+	if req.URL != nil {
+		webhook.URL = *req.URL
+	}
+	if req.Events != nil {
+		webhook.Events = *req.Events
+	}
+	if req.Description != nil {
+		webhook.Description = *req.Description
+	}
+	if req.IsActive != nil {
+		webhook.IsActive = *req.IsActive
+	}
+
+	if err := u.webhookRepo.Update(ctx, webhook); err != nil {
+		return nil, err
+	}
+
+	response := webhook.ToResponse()
+	return &response, nil
+}
+
+func (u *webhookUseCase) DeleteWebhook(ctx context.Context, id string) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return errors.ErrWebhookNotFound
+	}
+
+	return u.txManager.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := u.webhookRepo.Delete(ctx, objectID); err != nil {
+			return err
+		}
+		return u.deliveryRepo.DeleteByWebhook(ctx, objectID)
+	})
+}
+
+func (u *webhookUseCase) ListDeliveries(ctx context.Context, webhookID string, status entities.WebhookDeliveryStatus, limit, offset int) ([]*entities.WebhookDelivery, error) {
+	webhook, err := u.getWebhook(ctx, webhookID)
+	if err != nil {
+		return nil, err
+	}
+
+	return u.deliveryRepo.ListByWebhook(ctx, webhook.ID, status, limit, offset)
+}
+
+// Redeliver queues a delivery again with a fresh retry budget, whatever its
+// current state. The delivery log is kept.
+func (u *webhookUseCase) Redeliver(ctx context.Context, webhookID, deliveryID string) (*entities.WebhookDelivery, error) {
+	webhook, err := u.getWebhook(ctx, webhookID)
+	if err != nil {
+		return nil, err
+	}
+
+	objectID, err := primitive.ObjectIDFromHex(deliveryID)
+	if err != nil {
+		return nil, errors.ErrDeliveryNotFound
+	}
+
+	delivery, err := u.deliveryRepo.GetByID(ctx, objectID)
+	if err != nil {
+		return nil, err
+	}
+	if delivery.WebhookID != webhook.ID {
+		return nil, errors.ErrDeliveryNotFound
+	}
+
+	now := time.Now()
+	delivery.Status = entities.WebhookDeliveryPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = &now
+	delivery.LockedUntil = nil
+	if err := u.deliveryRepo.Update(ctx, delivery); err != nil {
+		return nil, err
+	}
+
+	return delivery, nil
+}
+
+// Publish queues the event for every active webhook subscribed to it.
+func (u *webhookUseCase) Publish(ctx context.Context, event *events.Event) error {
+	webhooks, err := u.webhookRepo.ListSubscribed(ctx, string(event.Type))
+	if err != nil {
+		return err
+	}
+	if len(webhooks) == 0 {
+		return nil
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, webhook := range webhooks {
+		err := u.deliveryRepo.Create(ctx, &entities.WebhookDelivery{
+			WebhookID:     webhook.ID,
+			EventID:       event.ID,
+			EventType:     string(event.Type),
+			AggregateID:   event.AggregateID,
+			Payload:       string(payload),
+			Status:        entities.WebhookDeliveryPending,
+			NextAttemptAt: &now,
+			Log:           []entities.WebhookDeliveryAttempt{},
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (u *webhookUseCase) ProcessDueDeliveries(ctx context.Context) (int, error) {
+	processed := 0
+	for processed < webhookDeliveryBatch {
+		now := time.Now()
+		delivery, err := u.deliveryRepo.ClaimDue(ctx, now, now.Add(webhookDeliveryLock))
+		if err != nil {
+			return processed, err
+		}
+		if delivery == nil {
+			break
+		}
+
+		if err := u.attempt(ctx, delivery); err != nil {
+			return processed, err
+		}
+		processed++
+	}
+
+	return processed, nil
+}
+
+func (u *webhookUseCase) attempt(ctx context.Context, delivery *entities.WebhookDelivery) error {
+	webhook, err := u.webhookRepo.GetByID(ctx, delivery.WebhookID)
+	if err != nil && err != errors.ErrWebhookNotFound {
+		return err
+	}
+
+	started := time.Now()
+	entry := entities.WebhookDeliveryAttempt{At: started}
+
+	switch {
+	case webhook == nil || !webhook.IsActive:
+		// Dead-lettered so it can be redelivered once the webhook is enabled
+		entry.Error = "webhook is disabled"
+		delivery.Attempts = webhookMaxAttempts
+	default:
+		statusCode, err := u.sender.Send(ctx, webhook, delivery)
+		entry.StatusCode = statusCode
+		entry.DurationMS = time.Since(started).Milliseconds()
+		if err != nil {
+			entry.Error = err.Error()
+		} else if statusCode < 200 || statusCode > 299 {
+			entry.Error = "unexpected status " + strconv.Itoa(statusCode)
+		}
+		delivery.Attempts++
+	}
+
+	delivery.Log = append(delivery.Log, entry)
+	if len(delivery.Log) > webhookLogSize {
+		delivery.Log = delivery.Log[len(delivery.Log)-webhookLogSize:]
+	}
+	delivery.LockedUntil = nil
+
+	switch {
+	case entry.Error == "":
+		delivery.Status = entities.WebhookDeliverySucceeded
+		delivery.DeliveredAt = &entry.At
+		delivery.NextAttemptAt = nil
+	case delivery.Attempts >= webhookMaxAttempts:
+		delivery.Status = entities.WebhookDeliveryDead
+		delivery.NextAttemptAt = nil
+	default:
+		next := time.Now().Add(webhookBackoff(delivery.Attempts))
+		delivery.NextAttemptAt = &next
+	}
+
+	return u.deliveryRepo.Update(ctx, delivery)
+}
+
+func (u *webhookUseCase) getWebhook(ctx context.Context, id string) (*entities.Webhook, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.ErrWebhookNotFound
+	}
+	return u.webhookRepo.GetByID(ctx, objectID)
+}
+
+// webhookBackoff doubles the delay after every failed attempt: 30s, 1m, 2m,
+// and so on, up to webhookMaxBackoff.
+func webhookBackoff(attempts int) time.Duration {
+	delay := webhookBaseBackoff
+	for i := 1; i < attempts && delay < webhookMaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > webhookMaxBackoff {
+		delay = webhookMaxBackoff
+	}
+	return delay
+}
+
+func generateWebhookSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(buf), nil
+}
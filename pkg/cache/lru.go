@@ -0,0 +1,122 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// Backend stores cached values. Implementations must be safe for concurrent use.
+type Backend interface {
+	Get(key string) (interface{}, bool)
+	Set(key string, value interface{})
+	Delete(key string)
+	Stats() Stats
+}
+
+type Stats struct {
+	Hits          uint64  `json:"hits"`
+	Misses        uint64  `json:"misses"`
+	Evictions     uint64  `json:"evictions"`
+	Invalidations uint64  `json:"invalidations"`
+	Size          int     `json:"size"`
+	Capacity      int     `json:"capacity"`
+	HitRatio      float64 `json:"hit_ratio"`
+}
+
+type entry struct {
+	key       string
+	value     interface{}
+	expiresAt time.Time
+}
+
+// LRU is an in-process cache that evicts the least recently used entry once
+// capacity is reached. Entries also expire after the configured TTL.
+type LRU struct {
+	mu       sync.Mutex
+	capacity int
+	ttl      time.Duration
+	items    map[string]*list.Element
+	order    *list.List
+	stats    Stats
+}
+
+func NewLRU(capacity int, ttl time.Duration) *LRU {
+	return &LRU{
+		capacity: capacity,
+		ttl:      ttl,
+		items:    make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+func (c *LRU) Get(key string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.items[key]
+	if !ok {
+		c.stats.Misses++
+		return nil, false
+	}
+
+	e := element.Value.(*entry)
+	if time.Now().After(e.expiresAt) {
+		c.removeElement(element)
+		c.stats.Misses++
+		return nil, false
+	}
+
+	c.order.MoveToFront(element)
+	c.stats.Hits++
+	return e.value, true
+}
+
+func (c *LRU) Set(key string, value interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expiresAt := time.Now().Add(c.ttl)
+	if element, ok := c.items[key]; ok {
+		e := element.Value.(*entry)
+		e.value = value
+		e.expiresAt = expiresAt
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.items[key] = c.order.PushFront(&entry{key: key, value: value, expiresAt: expiresAt})
+
+	for c.capacity > 0 && c.order.Len() > c.capacity {
+		c.removeElement(c.order.Back())
+		c.stats.Evictions++
+	}
+}
+
+func (c *LRU) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.items[key]; ok {
+		c.removeElement(element)
+		c.stats.Invalidations++
+	}
+}
+
+func (c *LRU) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := c.stats
+	stats.Size = c.order.Len()
+	stats.Capacity = c.capacity
+	if total := stats.Hits + stats.Misses; total > 0 {
+		stats.HitRatio = float64(stats.Hits) / float64(total)
+	}
+	return stats
+}
+
+func (c *LRU) removeElement(element *list.Element) {
+	c.order.Remove(element)
+	delete(c.items, element.Value.(*entry).key)
+}
@@ -0,0 +1,283 @@
+// Package codec encodes API bodies as JSON, MessagePack or Protobuf. The
+// binary encodings carry the JSON form of a value, so a body has the same
+// members in every encoding. Protobuf bodies are google.protobuf.Struct
+// messages.
+package codec
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/ugorji/go/codec"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+const (
+	MediaTypeJSON     = "application/json"
+	MediaTypeMsgPack  = "application/msgpack"
+	MediaTypeProtobuf = "application/x-protobuf"
+)
+
+// Codec converts bodies of one media type to and from JSON
+type Codec struct {
+	// MediaType is the canonical name, Aliases are accepted as well
+	MediaType string
+	Aliases   []string
+	// contentType is sent in the Content-Type header
+	contentType string
+
+	marshal   func(value interface{}) ([]byte, error)
+	unmarshal func(data []byte) (interface{}, error)
+}
+
+var (
+	JSON = &Codec{
+		MediaType:   MediaTypeJSON,
+		contentType: MediaTypeJSON + "; charset=utf-8",
+	}
+
+	MsgPack = &Codec{
+		MediaType:   MediaTypeMsgPack,
+		Aliases:     []string{"application/x-msgpack", "application/vnd.msgpack"},
+		contentType: MediaTypeMsgPack,
+		marshal:     marshalMsgPack,
+		unmarshal:   unmarshalMsgPack,
+	}
+
+	Protobuf = &Codec{
+		MediaType:   MediaTypeProtobuf,
+		Aliases:     []string{"application/protobuf", "application/vnd.google.protobuf"},
+		contentType: MediaTypeProtobuf + "; messageType=google.protobuf.Struct",
+		marshal:     marshalProtobuf,
+		unmarshal:   unmarshalProtobuf,
+	}
+
+	codecs = []*Codec{JSON, MsgPack, Protobuf}
+)
+
+// ContentType is the Content-Type header of bodies in this encoding
+func (c *Codec) ContentType() string {
+	return c.contentType
+}
+
+// Marshal encodes the JSON form of v
+func (c *Codec) Marshal(v interface{}) ([]byte, error) {
+	encoded, err := json.Marshal(v)
+	if err != nil || c == JSON {
+		return encoded, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	return c.marshal(value)
+}
+
+// ToJSON converts a body in this encoding to JSON
+func (c *Codec) ToJSON(data []byte) ([]byte, error) {
+	if c == JSON {
+		return data, nil
+	}
+	value, err := c.unmarshal(data)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(value)
+}
+
+func (c *Codec) names(mediaType string) bool {
+	if mediaType == c.MediaType {
+		return true
+	}
+	for _, alias := range c.Aliases {
+		if mediaType == alias {
+			return true
+		}
+	}
+	return false
+}
+
+// ForContentType returns the codec of a Content-Type header, JSON for JSON
+// media types such as application/merge-patch+json
+func ForContentType(contentType string) (*Codec, bool) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, false
+	}
+	if isJSON(mediaType) {
+		return JSON, true
+	}
+	for _, c := range codecs {
+		if c.names(mediaType) {
+			return c, true
+		}
+	}
+	return nil, false
+}
+
+// IsBinary reports whether mediaType is MessagePack or Protobuf
+func IsBinary(mediaType string) bool {
+	return MsgPack.names(mediaType) || Protobuf.names(mediaType)
+}
+
+// Negotiate returns the codec the Accept header prefers. Each codec gets the
+// quality of the most specific media range that matches it, and ties go to
+// the codec named explicitly, then to JSON. JSON is also used when the header
+// is missing or accepts none of them.
+func Negotiate(accept string) *Codec {
+	type preference struct {
+		quality     float64
+		specificity int
+	}
+	preferences := make(map[*Codec]preference, len(codecs))
+
+	for _, mediaRange := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(mediaRange))
+		if err != nil {
+			continue
+		}
+		quality := 1.0
+		if q, ok := params["q"]; ok {
+			if quality, err = strconv.ParseFloat(q, 64); err != nil {
+				continue
+			}
+		}
+
+		for _, c := range codecs {
+			specificity := 0
+			switch {
+			case c.names(mediaType), c == JSON && isJSON(mediaType):
+				specificity = 2
+			case mediaType == "application/*":
+				specificity = 1
+			case mediaType == "*/*":
+			default:
+				continue
+			}
+			if current, ok := preferences[c]; !ok || specificity > current.specificity {
+				preferences[c] = preference{quality, specificity}
+			}
+		}
+	}
+
+	best, bestPreference := JSON, preference{quality: 0}
+	for _, c := range codecs {
+		p, ok := preferences[c]
+		if !ok || p.quality <= 0 {
+			continue
+		}
+		if p.quality > bestPreference.quality ||
+			p.quality == bestPreference.quality && p.specificity > bestPreference.specificity {
+			best, bestPreference = c, p
+		}
+	}
+	return best
+}
+
+func isJSON(mediaType string) bool {
+	return mediaType == MediaTypeJSON || strings.HasSuffix(mediaType, "+json")
+}
+
+// msgpackHandle writes maps with sorted keys, so that equal values encode to
+// equal bytes, and reads them as JSON objects
+var msgpackHandle = func() *codec.MsgpackHandle {
+	h := &codec.MsgpackHandle{WriteExt: true}
+	h.Canonical = true
+	h.MapType = reflect.TypeOf(map[string]interface{}(nil))
+	h.RawToString = true
+	return h
+}()
+
+func marshalMsgPack(value interface{}) ([]byte, error) {
+	var buffer bytes.Buffer
+	if err := codec.NewEncoder(&buffer, msgpackHandle).Encode(nativeNumbers(value)); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+func unmarshalMsgPack(data []byte) (interface{}, error) {
+	var value interface{}
+	if err := codec.NewDecoderBytes(data, msgpackHandle).Decode(&value); err != nil {
+		return nil, fmt.Errorf("invalid MessagePack body: %w", err)
+	}
+	return value, nil
+}
+
+// nativeNumbers replaces the json.Numbers of a decoded value with integers
+// where they are whole, so MessagePack keeps the distinction JSON makes
+func nativeNumbers(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = nativeNumbers(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = nativeNumbers(item)
+		}
+	}
+	return value
+}
+
+// Protobuf bodies must be objects, as google.protobuf.Struct is. Numbers are
+// doubles there, so integers a double cannot hold exactly are written as
+// strings, as the proto3 JSON mapping writes 64-bit integers.
+func marshalProtobuf(value interface{}) ([]byte, error) {
+	object, ok := exactNumbers(value).(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("protobuf bodies must be objects, not %T", value)
+	}
+	message, err := structpb.NewStruct(object)
+	if err != nil {
+		return nil, err
+	}
+	return proto.MarshalOptions{Deterministic: true}.Marshal(message)
+}
+
+// maxExactInteger is the largest integer every smaller one of which a double
+// holds exactly, 2^53
+const maxExactInteger = 1 << 53
+
+// exactNumbers replaces the json.Numbers of a decoded value that are
+// integers beyond maxExactInteger with their decimal string
+func exactNumbers(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil && (n > maxExactInteger || n < -maxExactInteger) {
+			return v.String()
+		}
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = exactNumbers(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = exactNumbers(item)
+		}
+	}
+	return value
+}
+
+func unmarshalProtobuf(data []byte) (interface{}, error) {
+	var message structpb.Struct
+	if err := proto.Unmarshal(data, &message); err != nil {
+		return nil, fmt.Errorf("invalid Protobuf body: %w", err)
+	}
+	return message.AsMap(), nil
+}
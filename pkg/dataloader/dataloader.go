@@ -0,0 +1,83 @@
+// Package dataloader batches lookups by key. Callers queue keys with Load
+// and get back a thunk; the first thunk that is called fetches every key
+// queued so far in one batch. Results are kept for the life of the Loader,
+// so a Loader should be scoped to a single request.
+package dataloader
+
+import (
+	"context"
+	"sync"
+)
+
+// BatchFunc fetches the values for keys. Keys without a value are left out
+// of the returned map.
+type BatchFunc func(ctx context.Context, keys []string) (map[string]interface{}, error)
+
+type result struct {
+	value interface{}
+	err   error
+	done  bool
+}
+
+// Loader is safe for concurrent use
+type Loader struct {
+	batch    BatchFunc
+	maxBatch int
+
+	mu      sync.Mutex
+	pending []string
+	results map[string]*result
+}
+
+// New returns a Loader that fetches at most maxBatch keys per call to batch.
+// A maxBatch of zero or less means no limit.
+func New(batch BatchFunc, maxBatch int) *Loader {
+	return &Loader{
+		batch:    batch,
+		maxBatch: maxBatch,
+		results:  make(map[string]*result),
+	}
+}
+
+// Load queues key and returns a thunk resolving to its value. The value is
+// nil when the batch did not return one for the key.
+func (l *Loader) Load(ctx context.Context, key string) func() (interface{}, error) {
+	l.mu.Lock()
+	r, ok := l.results[key]
+	if !ok {
+		r = &result{}
+		l.results[key] = r
+		l.pending = append(l.pending, key)
+	}
+	l.mu.Unlock()
+
+	return func() (interface{}, error) {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		for !r.done {
+			l.dispatch(ctx)
+		}
+		return r.value, r.err
+	}
+}
+
+// dispatch fetches the next batch of pending keys. The caller holds l.mu.
+func (l *Loader) dispatch(ctx context.Context) {
+	keys := l.pending
+	if l.maxBatch > 0 && len(keys) > l.maxBatch {
+		keys = keys[:l.maxBatch]
+	}
+	l.pending = l.pending[len(keys):]
+
+	values, err := l.batch(ctx, keys)
+	for _, key := range keys {
+		r := l.results[key]
+		r.done = true
+		if err != nil {
+			r.err = err
+			continue
+		}
+		r.value = values[key]
+	}
+}
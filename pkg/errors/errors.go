@@ -3,6 +3,7 @@ package errors
 import (
 	"errors"
 	"net/http"
+	"strings"
 )
 
 var (
@@ -13,6 +14,7 @@ var (
 	ErrInvalidCredentials    = errors.New("invalid credentials")
 	ErrUserInactive          = errors.New("user account is inactive")
 	ErrInvalidUserID         = errors.New("invalid user ID")
+	ErrVersionConflict       = errors.New("user has been modified by another request")
 
 	// Auth errors
 	ErrInvalidToken = errors.New("invalid token")
@@ -24,42 +26,248 @@ var (
 	ErrValidationFailed   = errors.New("validation failed")
 	ErrInvalidRequestBody = errors.New("invalid request body")
 
+	// Patch errors
+	ErrInvalidPatch         = errors.New("invalid patch document")
+	ErrPatchTestFailed      = errors.New("patch test operation failed")
+	ErrFieldNotPatchable    = errors.New("patch modifies a field that cannot be changed")
+	ErrUnsupportedMediaType = errors.New("unsupported media type")
+
+	// Privacy errors
+	ErrErasureNotFound          = errors.New("no erasure request found")
+	ErrErasureAlreadyRequested  = errors.New("erasure has already been requested")
+	ErrErasureNotConfirmable    = errors.New("erasure request is not awaiting confirmation")
+	ErrInvalidConfirmationToken = errors.New("invalid confirmation token")
+
+	// Webhook errors
+	ErrWebhookNotFound  = errors.New("webhook not found")
+	ErrDeliveryNotFound = errors.New("webhook delivery not found")
+
+	// Avatar errors
+	ErrAvatarNotFound = errors.New("no avatar has been uploaded")
+	ErrFileTooLarge   = errors.New("file exceeds the maximum upload size")
+	ErrImageTooLarge  = errors.New("image dimensions are too large")
+	ErrInvalidImage   = errors.New("file is not a valid image")
+
+	// Attribute errors
+	ErrAttributeNotFound      = errors.New("attribute not found")
+	ErrAttributeAlreadyExists = errors.New("attribute already exists")
+	ErrAttributeNotWritable   = errors.New("attribute can only be changed by an admin")
+
+	// Import and export errors
+	ErrMalformedRow      = errors.New("malformed row")
+	ErrUnsupportedFormat = errors.New("unsupported format")
+
+	// Idempotency errors
+	ErrInvalidIdempotencyKey        = errors.New("invalid idempotency key")
+	ErrIdempotencyKeyReused         = errors.New("idempotency key was already used for a different request")
+	ErrIdempotencyRequestInProgress = errors.New("a request with this idempotency key is still in progress")
+	ErrIdempotentBodyTooLarge       = errors.New("request body is too large to be sent with an idempotency key")
+
+	// Batch errors
+	ErrNestedBatch            = errors.New("a batch cannot contain another batch")
+	ErrAtomicBatchUnsupported = errors.New("atomic batches need a database that supports transactions")
+
 	// General errors
 	ErrInternalServer = errors.New("internal server error")
 	ErrBadRequest     = errors.New("bad request")
 )
 
+// Code identifies an error for machines. Codes are part of the API contract
+// and never change, unlike messages.
+type Code string
+
+const (
+	// CodeInternal is reported for every error the catalog does not know
+	CodeInternal Code = "internal_server_error"
+
+	CodeValidationFailed Code = "validation_failed"
+)
+
+// Description is what a client is told about an error
+type Description struct {
+	Status int
+	Code   Code
+	// Title is the same for every error with the code, Message may be more
+	// specific. Both are safe to show to clients.
+	Title   string
+	Message string
+}
+
+type definition struct {
+	err    error
+	status int
+	code   Code
+}
+
+// catalog gives every sentinel its HTTP status and stable code
+var catalog = []definition{
+	{ErrUserNotFound, http.StatusNotFound, "user_not_found"},
+	{ErrUserAlreadyExists, http.StatusConflict, "user_already_exists"},
+	{ErrUsernameAlreadyExists, http.StatusConflict, "username_already_exists"},
+	{ErrInvalidCredentials, http.StatusUnauthorized, "invalid_credentials"},
+	{ErrUserInactive, http.StatusForbidden, "user_inactive"},
+	{ErrInvalidUserID, http.StatusBadRequest, "invalid_user_id"},
+	{ErrVersionConflict, http.StatusPreconditionFailed, "version_conflict"},
+
+	{ErrInvalidToken, http.StatusUnauthorized, "invalid_token"},
+	{ErrTokenExpired, http.StatusUnauthorized, "token_expired"},
+	{ErrUnauthorized, http.StatusUnauthorized, "unauthorized"},
+	{ErrForbidden, http.StatusForbidden, "forbidden"},
+
+	{ErrValidationFailed, http.StatusBadRequest, CodeValidationFailed},
+	{ErrInvalidRequestBody, http.StatusBadRequest, "invalid_request_body"},
+
+	{ErrInvalidPatch, http.StatusBadRequest, "invalid_patch"},
+	{ErrPatchTestFailed, http.StatusConflict, "patch_test_failed"},
+	{ErrFieldNotPatchable, http.StatusUnprocessableEntity, "field_not_patchable"},
+	{ErrUnsupportedMediaType, http.StatusUnsupportedMediaType, "unsupported_media_type"},
+
+	{ErrErasureNotFound, http.StatusNotFound, "erasure_not_found"},
+	{ErrErasureAlreadyRequested, http.StatusConflict, "erasure_already_requested"},
+	{ErrErasureNotConfirmable, http.StatusConflict, "erasure_not_confirmable"},
+	{ErrInvalidConfirmationToken, http.StatusForbidden, "invalid_confirmation_token"},
+
+	{ErrWebhookNotFound, http.StatusNotFound, "webhook_not_found"},
+	{ErrDeliveryNotFound, http.StatusNotFound, "delivery_not_found"},
+
+	{ErrAvatarNotFound, http.StatusNotFound, "avatar_not_found"},
+	{ErrFileTooLarge, http.StatusRequestEntityTooLarge, "file_too_large"},
+	{ErrImageTooLarge, http.StatusUnprocessableEntity, "image_too_large"},
+	{ErrInvalidImage, http.StatusBadRequest, "invalid_image"},
+
+	{ErrAttributeNotFound, http.StatusNotFound, "attribute_not_found"},
+	{ErrAttributeAlreadyExists, http.StatusConflict, "attribute_already_exists"},
+	{ErrAttributeNotWritable, http.StatusForbidden, "attribute_not_writable"},
+
+	{ErrMalformedRow, http.StatusBadRequest, "malformed_row"},
+	{ErrUnsupportedFormat, http.StatusBadRequest, "unsupported_format"},
+
+	{ErrInvalidIdempotencyKey, http.StatusBadRequest, "invalid_idempotency_key"},
+	{ErrIdempotencyKeyReused, http.StatusUnprocessableEntity, "idempotency_key_reused"},
+	{ErrIdempotencyRequestInProgress, http.StatusConflict, "idempotency_request_in_progress"},
+	{ErrIdempotentBodyTooLarge, http.StatusRequestEntityTooLarge, "idempotent_body_too_large"},
+
+	{ErrNestedBatch, http.StatusBadRequest, "nested_batch"},
+	{ErrAtomicBatchUnsupported, http.StatusNotImplemented, "atomic_batch_unsupported"},
+
+	{ErrInternalServer, http.StatusInternalServerError, CodeInternal},
+	{ErrBadRequest, http.StatusBadRequest, "bad_request"},
+}
+
+// AppError is an error with its own status, code and client-safe message.
+// Err is the cause, which is only ever logged.
 type AppError struct {
-	Code    int    `json:"code"`
-	Message string `json:"message"`
-	Details string `json:"details,omitempty"`
+	Status  int
+	Code    Code
+	Message string
+	Err     error
 }
 
 func (e *AppError) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
 	return e.Message
 }
 
-func NewAppError(code int, message, details string) *AppError {
+func (e *AppError) Unwrap() error {
+	return e.Err
+}
+
+func NewAppError(status int, code Code, message string, err error) *AppError {
 	return &AppError{
+		Status:  status,
 		Code:    code,
 		Message: message,
-		Details: details,
+		Err:     err,
+	}
+}
+
+// Describe finds the AppError or sentinel err wraps. Anything else is an
+// internal error, and its message is replaced so it cannot leak to clients.
+func Describe(err error) Description {
+	var appErr *AppError
+	if errors.As(err, &appErr) {
+		return Description{
+			Status:  appErr.Status,
+			Code:    appErr.Code,
+			Title:   http.StatusText(appErr.Status),
+			Message: appErr.Message,
+		}
+	}
+
+	for _, def := range catalog {
+		if errors.Is(err, def.err) {
+			return def.describe()
+		}
+	}
+	internal, _ := DescribeCode(CodeInternal)
+	return internal
+}
+
+// DescribeCode returns the catalog entry or HTTP status that code stands for
+func DescribeCode(code Code) (Description, bool) {
+	for _, def := range catalog {
+		if def.code == code {
+			return def.describe(), true
+		}
+	}
+	for status := http.StatusBadRequest; status < 600; status++ {
+		if http.StatusText(status) != "" && StatusCode(status) == code {
+			description := DescribeStatus(status, "")
+			description.Message = description.Title
+			return description, true
+		}
+	}
+	return Description{}, false
+}
+
+// DescribeStatus describes an error that has no sentinel of its own, with
+// the generic code of its status, e.g. not_acceptable for 406
+func DescribeStatus(status int, message string) Description {
+	description := Description{
+		Status:  status,
+		Code:    StatusCode(status),
+		Title:   http.StatusText(status),
+		Message: message,
+	}
+	// Keep the title of codes shared with a sentinel, such as bad_request
+	for _, def := range catalog {
+		if def.code == description.Code && def.status == status {
+			description.Title = def.describe().Title
+		}
 	}
+	return description
+}
+
+// StatusCode is the generic code of an HTTP status, its status text in
+// snake case
+func StatusCode(status int) Code {
+	var code strings.Builder
+	for _, r := range strings.ToLower(http.StatusText(status)) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			code.WriteRune(r)
+		case r == ' ' || r == '-':
+			code.WriteByte('_')
+		}
+	}
+	if code.Len() == 0 {
+		return CodeInternal
+	}
+	return Code(code.String())
 }
 
 func GetHTTPStatusCode(err error) int {
-	switch err {
-	case ErrUserNotFound:
-		return http.StatusNotFound
-	case ErrUserAlreadyExists, ErrUsernameAlreadyExists:
-		return http.StatusConflict
-	case ErrInvalidCredentials, ErrInvalidToken, ErrTokenExpired, ErrUnauthorized:
-		return http.StatusUnauthorized
-	case ErrUserInactive, ErrForbidden:
-		return http.StatusForbidden
-	case ErrInvalidUserID, ErrValidationFailed, ErrInvalidRequestBody, ErrBadRequest:
-		return http.StatusBadRequest
-	default:
-		return http.StatusInternalServerError
+	return Describe(err).Status
+}
+
+func (d definition) describe() Description {
+	message := d.err.Error()
+	return Description{
+		Status:  d.status,
+		Code:    d.code,
+		Title:   strings.ToUpper(message[:1]) + message[1:],
+		Message: message,
 	}
 }
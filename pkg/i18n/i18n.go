@@ -0,0 +1,140 @@
+// Package i18n holds the message catalogs of the API. Catalogs are universal
+// translator files embedded from locales/, one per supported language, and
+// messages fall back to English when a catalog lacks them.
+package i18n
+
+import (
+	"embed"
+	"fmt"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/locales/de"
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/es"
+	"github.com/go-playground/locales/fr"
+	ut "github.com/go-playground/universal-translator"
+	"golang.org/x/text/language"
+)
+
+const (
+	// DefaultLocale is used when a request does not ask for a supported one
+	DefaultLocale = "en"
+
+	// ContextKey holds the locale preference of the authenticated user
+	ContextKey = "locale"
+)
+
+//go:embed locales/*.json
+var catalogs embed.FS
+
+// supported lists the locales with a catalog, the default first
+var supported = []language.Tag{language.English, language.Spanish, language.French, language.German}
+
+var (
+	matcher = language.NewMatcher(supported)
+
+	universal *ut.UniversalTranslator
+	loadOnce  sync.Once
+)
+
+// Locales lists the supported locales, the default first
+func Locales() []string {
+	locales := make([]string, len(supported))
+	for i, tag := range supported {
+		locales[i] = tag.String()
+	}
+	return locales
+}
+
+// IsSupported reports whether tag is a BCP 47 language tag with a catalog,
+// such as de or de-AT
+func IsSupported(tag string) bool {
+	parsed, err := language.Parse(tag)
+	if err != nil {
+		return false
+	}
+	_, _, confidence := matcher.Match(parsed)
+	return confidence >= language.High
+}
+
+// Match picks the supported locale that best fits the preferences, each a
+// language tag or an Accept-Language header. Earlier preferences win ties.
+func Match(preferences ...string) string {
+	var desired []language.Tag
+	for _, preference := range preferences {
+		if preference == "" {
+			continue
+		}
+		tags, _, err := language.ParseAcceptLanguage(preference)
+		if err != nil {
+			continue
+		}
+		desired = append(desired, tags...)
+	}
+
+	_, index, confidence := matcher.Match(desired...)
+	if confidence == language.No {
+		return DefaultLocale
+	}
+	return supported[index].String()
+}
+
+// Locale is the locale of the request in c: the preference of the user if
+// one is signed in and has set one, otherwise the Accept-Language header
+func Locale(c *gin.Context) string {
+	return Match(c.GetString(ContextKey), c.GetHeader("Accept-Language"))
+}
+
+// Translator returns the translator of locale, or of the default locale when
+// locale is not supported
+func Translator(locale string) ut.Translator {
+	translator, _ := translators().FindTranslator(locale, DefaultLocale)
+	return translator
+}
+
+// Lookup translates the message under key, filling {0}, {1}... with params.
+// Messages missing from the catalog of locale are taken from the default
+// catalog, ok is false when neither has key.
+func Lookup(locale, key string, params ...string) (message string, ok bool) {
+	if message, err := Translator(locale).T(key, params...); err == nil {
+		return message, true
+	}
+	if message, err := Translator(DefaultLocale).T(key, params...); err == nil {
+		return message, true
+	}
+	return "", false
+}
+
+// T translates the message under key, or returns key when no catalog has it
+func T(locale, key string, params ...string) string {
+	if message, ok := Lookup(locale, key, params...); ok {
+		return message
+	}
+	return key
+}
+
+// translators loads the embedded catalogs on first use. They are part of
+// the binary, so a catalog that does not load is a programming error.
+func translators() *ut.UniversalTranslator {
+	loadOnce.Do(func() {
+		universal = ut.New(en.New(), en.New(), es.New(), fr.New(), de.New())
+
+		files, err := catalogs.ReadDir("locales")
+		if err != nil {
+			panic(fmt.Sprintf("i18n: reading catalogs: %v", err))
+		}
+		for _, file := range files {
+			catalog, err := catalogs.Open("locales/" + file.Name())
+			if err != nil {
+				panic(fmt.Sprintf("i18n: opening catalog %s: %v", file.Name(), err))
+			}
+			err = universal.ImportByReader(ut.FormatJSON, catalog)
+			catalog.Close()
+			if err != nil {
+				panic(fmt.Sprintf("i18n: loading catalog %s: %v", file.Name(), err))
+			}
+		}
+	})
+	return universal
+}
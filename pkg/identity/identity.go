@@ -0,0 +1,107 @@
+package identity
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// EmailRule describes which parts of the local part a mail domain ignores
+// when delivering, such as "+tag" suffixes or dots in Gmail addresses.
+type EmailRule struct {
+	IgnorePlus bool
+	IgnoreDots bool
+}
+
+// Canonicalizer maps emails and usernames to the keys used to decide whether
+// two accounts are the same person. The keys are only for comparison, the
+// values as typed are kept for display and delivery.
+type Canonicalizer struct {
+	rules map[string]EmailRule
+}
+
+// NewCanonicalizer takes rules keyed by lowercase domain. The domain "*"
+// applies to every domain without a rule of its own.
+func NewCanonicalizer(rules map[string]EmailRule) *Canonicalizer {
+	if rules == nil {
+		rules = map[string]EmailRule{}
+	}
+	return &Canonicalizer{
+		rules: rules,
+	}
+}
+
+// ParseEmailRules parses "gmail.com=plus|dots,outlook.com=plus". An empty
+// spec yields no rules.
+func ParseEmailRules(spec string) (map[string]EmailRule, error) {
+	rules := make(map[string]EmailRule)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		domain, flags, ok := strings.Cut(entry, "=")
+		if !ok || strings.TrimSpace(domain) == "" {
+			return nil, fmt.Errorf("invalid email rule %q, expected domain=flags", entry)
+		}
+
+		var rule EmailRule
+		for _, flag := range strings.Split(flags, "|") {
+			switch strings.TrimSpace(flag) {
+			case "plus":
+				rule.IgnorePlus = true
+			case "dots":
+				rule.IgnoreDots = true
+			default:
+				return nil, fmt.Errorf("invalid email rule flag %q, expected plus or dots", flag)
+			}
+		}
+		rules[fold(strings.TrimSpace(domain))] = rule
+	}
+	return rules, nil
+}
+
+// Email returns the canonical key of an email address: NFKC normalized, case
+// folded, and with the rule of its domain applied to the local part.
+func (c *Canonicalizer) Email(email string) string {
+	email = fold(email)
+
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return email
+	}
+	local, domain := email[:at], strings.TrimSuffix(email[at+1:], ".")
+
+	rule, ok := c.rules[domain]
+	if !ok {
+		rule = c.rules["*"]
+	}
+	if rule.IgnorePlus {
+		local, _, _ = strings.Cut(local, "+")
+	}
+	if rule.IgnoreDots {
+		local = strings.ReplaceAll(local, ".", "")
+	}
+
+	return local + "@" + domain
+}
+
+// Username returns the canonical key of a username: NFKC normalized and case
+// folded, so that "Alice" and "ａｌｉｃｅ" are the same name.
+func (c *Canonicalizer) Username(username string) string {
+	return fold(username)
+}
+
+// Normalize applies NFKC and trims surrounding space without changing case.
+// It is used for the values that are stored and displayed.
+func Normalize(value string) string {
+	return strings.TrimSpace(norm.NFKC.String(value))
+}
+
+func fold(value string) string {
+	// Case folding can produce denormalized text, so normalize again
+	return norm.NFKC.String(cases.Fold().String(Normalize(value)))
+}
@@ -0,0 +1,99 @@
+// Package imaging decodes untrusted uploads into plain pixels and produces
+// resized copies. Re-encoding the pixels drops every piece of metadata the
+// original file carried, EXIF included.
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+
+	"github.com/gabriel-vasile/mimetype"
+)
+
+const (
+	JPEG = "image/jpeg"
+	PNG  = "image/png"
+	GIF  = "image/gif"
+
+	jpegQuality = 85
+)
+
+var (
+	ErrUnsupportedType = errors.New("unsupported image type")
+	ErrInvalidImage    = errors.New("invalid image")
+	ErrTooManyPixels   = errors.New("image dimensions are too large")
+)
+
+// Decode sniffs the content type from the bytes themselves, checks the
+// dimensions before decoding so a small file cannot expand into a huge
+// bitmap, and applies the EXIF orientation of JPEG photos. It returns the
+// upright pixels and the content type they should be encoded as: JPEG stays
+// JPEG, everything else becomes PNG.
+func Decode(data []byte, maxPixels int) (*image.RGBA, string, error) {
+	detected := mimetype.Detect(data)
+
+	var outputType string
+	switch {
+	case detected.Is(JPEG):
+		outputType = JPEG
+	case detected.Is(PNG), detected.Is(GIF):
+		outputType = PNG
+	default:
+		return nil, "", ErrUnsupportedType
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", ErrInvalidImage
+	}
+	if config.Width <= 0 || config.Height <= 0 {
+		return nil, "", ErrInvalidImage
+	}
+	if int64(config.Width)*int64(config.Height) > int64(maxPixels) {
+		return nil, "", ErrTooManyPixels
+	}
+
+	decoded, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", ErrInvalidImage
+	}
+
+	img := toRGBA(decoded)
+	if outputType == JPEG {
+		img = orient(img, jpegOrientation(data))
+	}
+	return img, outputType, nil
+}
+
+// Encode writes img in the given content type, JPEG or PNG
+func Encode(w io.Writer, img image.Image, contentType string) error {
+	switch contentType {
+	case JPEG:
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: jpegQuality})
+	case PNG:
+		return png.Encode(w, img)
+	default:
+		return ErrUnsupportedType
+	}
+}
+
+// Extension returns the file extension for an encoded content type
+func Extension(contentType string) string {
+	if contentType == JPEG {
+		return ".jpg"
+	}
+	return ".png"
+}
+
+func toRGBA(img image.Image) *image.RGBA {
+	bounds := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, bounds.Min, draw.Src)
+	return rgba
+}
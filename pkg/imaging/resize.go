@@ -0,0 +1,118 @@
+package imaging
+
+import (
+	"image"
+	"math"
+)
+
+// contribution is the weight one source pixel has in a destination pixel
+type contribution struct {
+	index  int
+	weight float32
+}
+
+// SquareThumbnail crops the largest centred square out of img and scales it
+// to size by size pixels.
+func SquareThumbnail(img *image.RGBA, size int) *image.RGBA {
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	side := w
+	if h < side {
+		side = h
+	}
+	x0 := img.Rect.Min.X + (w-side)/2
+	y0 := img.Rect.Min.Y + (h-side)/2
+
+	return resample(img, image.Rect(x0, y0, x0+side, y0+side), size, size)
+}
+
+// resample scales the src rectangle of img to dstW by dstH pixels with a box
+// filter, weighting each source pixel by how much of it a destination pixel
+// covers. It runs as a horizontal pass followed by a vertical one. RGBA
+// pixels are alpha-premultiplied, so averaging them directly is correct.
+func resample(img *image.RGBA, src image.Rectangle, dstW, dstH int) *image.RGBA {
+	srcW, srcH := src.Dx(), src.Dy()
+	columns := boxWeights(srcW, dstW)
+	rows := boxWeights(srcH, dstH)
+
+	// Horizontal pass into a dstW x srcH buffer
+	horizontal := make([]float32, dstW*srcH*4)
+	for y := 0; y < srcH; y++ {
+		line := img.Pix[img.PixOffset(src.Min.X, src.Min.Y+y):]
+		for x, weights := range columns {
+			var r, g, b, a float32
+			for _, c := range weights {
+				p := line[c.index*4 : c.index*4+4]
+				r += float32(p[0]) * c.weight
+				g += float32(p[1]) * c.weight
+				b += float32(p[2]) * c.weight
+				a += float32(p[3]) * c.weight
+			}
+			out := horizontal[(y*dstW+x)*4:]
+			out[0], out[1], out[2], out[3] = r, g, b, a
+		}
+	}
+
+	// Vertical pass into the destination
+	dst := image.NewRGBA(image.Rect(0, 0, dstW, dstH))
+	for y, weights := range rows {
+		for x := 0; x < dstW; x++ {
+			var r, g, b, a float32
+			for _, c := range weights {
+				p := horizontal[(c.index*dstW+x)*4:]
+				r += p[0] * c.weight
+				g += p[1] * c.weight
+				b += p[2] * c.weight
+				a += p[3] * c.weight
+			}
+			out := dst.Pix[dst.PixOffset(x, y):]
+			out[0], out[1], out[2], out[3] = clamp(r), clamp(g), clamp(b), clamp(a)
+		}
+	}
+	return dst
+}
+
+// boxWeights works out which source pixels cover each destination pixel
+// along one axis. When enlarging, each destination pixel takes the nearest
+// source pixel.
+func boxWeights(srcLen, dstLen int) [][]contribution {
+	scale := float64(srcLen) / float64(dstLen)
+	weights := make([][]contribution, dstLen)
+
+	for i := range weights {
+		start := float64(i) * scale
+		end := start + scale
+
+		if scale <= 1 {
+			index := int(start + scale/2)
+			if index >= srcLen {
+				index = srcLen - 1
+			}
+			weights[i] = []contribution{{index: index, weight: 1}}
+			continue
+		}
+
+		var total float64
+		for j := int(math.Floor(start)); j < int(math.Ceil(end)) && j < srcLen; j++ {
+			covered := math.Min(end, float64(j+1)) - math.Max(start, float64(j))
+			if covered <= 0 {
+				continue
+			}
+			weights[i] = append(weights[i], contribution{index: j, weight: float32(covered)})
+			total += covered
+		}
+		for k := range weights[i] {
+			weights[i][k].weight /= float32(total)
+		}
+	}
+	return weights
+}
+
+func clamp(v float32) uint8 {
+	if v <= 0 {
+		return 0
+	}
+	if v >= 255 {
+		return 255
+	}
+	return uint8(v + 0.5)
+}
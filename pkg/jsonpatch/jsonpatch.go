@@ -0,0 +1,337 @@
+package jsonpatch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	MergePatchContentType = "application/merge-patch+json"
+	JSONPatchContentType  = "application/json-patch+json"
+)
+
+var (
+	ErrInvalidPatch = errors.New("invalid patch document")
+	ErrTestFailed   = errors.New("patch test operation failed")
+)
+
+type Operation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// MergePatch applies an RFC 7396 JSON Merge Patch to doc. The patch must be a
+// JSON object, because replacing the whole document is never meaningful here.
+func MergePatch(doc map[string]interface{}, patch []byte) (map[string]interface{}, error) {
+	var patchValue interface{}
+	if err := json.Unmarshal(patch, &patchValue); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+
+	patchObject, ok := patchValue.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: merge patch must be a JSON object", ErrInvalidPatch)
+	}
+
+	return mergeObject(deepCopy(doc).(map[string]interface{}), patchObject), nil
+}
+
+func mergeObject(target, patch map[string]interface{}) map[string]interface{} {
+	for key, value := range patch {
+		if value == nil {
+			delete(target, key)
+			continue
+		}
+
+		patchObject, ok := value.(map[string]interface{})
+		if !ok {
+			target[key] = value
+			continue
+		}
+
+		targetObject, ok := target[key].(map[string]interface{})
+		if !ok {
+			targetObject = map[string]interface{}{}
+		}
+		target[key] = mergeObject(targetObject, patchObject)
+	}
+	return target
+}
+
+// Apply applies an RFC 6902 JSON Patch to doc. Operations are applied in
+// order to a copy of doc, so doc is left untouched when any of them fails.
+func Apply(doc map[string]interface{}, patch []byte) (map[string]interface{}, error) {
+	var operations []Operation
+	if err := json.Unmarshal(patch, &operations); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+
+	var result interface{} = deepCopy(doc)
+	for i, operation := range operations {
+		var err error
+		result, err = applyOperation(result, operation)
+		if err != nil {
+			return nil, fmt.Errorf("operation %d (%s %s): %w", i, operation.Op, operation.Path, err)
+		}
+	}
+
+	object, ok := result.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: patched document is not a JSON object", ErrInvalidPatch)
+	}
+	return object, nil
+}
+
+func applyOperation(doc interface{}, operation Operation) (interface{}, error) {
+	path, err := parsePointer(operation.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch operation.Op {
+	case "add", "replace", "test":
+		if len(operation.Value) == 0 {
+			return nil, fmt.Errorf("%w: missing value", ErrInvalidPatch)
+		}
+		var value interface{}
+		if err := json.Unmarshal(operation.Value, &value); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+		}
+
+		switch operation.Op {
+		case "add":
+			return add(doc, path, value)
+		case "replace":
+			if len(path) == 0 {
+				return value, nil
+			}
+			doc, _, err := remove(doc, path)
+			if err != nil {
+				return nil, err
+			}
+			return add(doc, path, value)
+		default:
+			current, err := get(doc, path)
+			if err != nil {
+				return nil, err
+			}
+			if !Equal(current, value) {
+				return nil, ErrTestFailed
+			}
+			return doc, nil
+		}
+
+	case "remove":
+		doc, _, err := remove(doc, path)
+		return doc, err
+
+	case "move", "copy":
+		from, err := parsePointer(operation.From)
+		if err != nil {
+			return nil, err
+		}
+
+		var value interface{}
+		if operation.Op == "move" {
+			if isPrefix(from, path) && len(from) < len(path) {
+				return nil, fmt.Errorf("%w: cannot move a value into one of its children", ErrInvalidPatch)
+			}
+			doc, value, err = remove(doc, from)
+		} else {
+			value, err = get(doc, from)
+			value = deepCopy(value)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, path, value)
+
+	default:
+		return nil, fmt.Errorf("%w: unknown operation %q", ErrInvalidPatch, operation.Op)
+	}
+}
+
+// parsePointer splits an RFC 6901 JSON Pointer into unescaped reference tokens.
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return []string{}, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("%w: invalid JSON pointer %q", ErrInvalidPatch, pointer)
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+func get(doc interface{}, path []string) (interface{}, error) {
+	current := doc
+	for _, token := range path {
+		switch node := current.(type) {
+		case map[string]interface{}:
+			value, ok := node[token]
+			if !ok {
+				return nil, fmt.Errorf("%w: path does not exist", ErrInvalidPatch)
+			}
+			current = value
+		case []interface{}:
+			index, err := arrayIndex(token, len(node)-1)
+			if err != nil {
+				return nil, err
+			}
+			current = node[index]
+		default:
+			return nil, fmt.Errorf("%w: path does not exist", ErrInvalidPatch)
+		}
+	}
+	return current, nil
+}
+
+func add(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	parent, err := get(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	token := path[len(path)-1]
+
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		node[token] = value
+		return doc, nil
+	case []interface{}:
+		index := len(node)
+		if token != "-" {
+			index, err = arrayIndex(token, len(node))
+			if err != nil {
+				return nil, err
+			}
+		}
+		node = append(node, nil)
+		copy(node[index+1:], node[index:])
+		node[index] = value
+		return replaceAt(doc, path[:len(path)-1], node)
+	default:
+		return nil, fmt.Errorf("%w: path does not exist", ErrInvalidPatch)
+	}
+}
+
+func remove(doc interface{}, path []string) (interface{}, interface{}, error) {
+	if len(path) == 0 {
+		return nil, nil, fmt.Errorf("%w: cannot remove the whole document", ErrInvalidPatch)
+	}
+
+	parent, err := get(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, nil, err
+	}
+	token := path[len(path)-1]
+
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		value, ok := node[token]
+		if !ok {
+			return nil, nil, fmt.Errorf("%w: path does not exist", ErrInvalidPatch)
+		}
+		delete(node, token)
+		return doc, value, nil
+	case []interface{}:
+		index, err := arrayIndex(token, len(node)-1)
+		if err != nil {
+			return nil, nil, err
+		}
+		value := node[index]
+		node = append(node[:index:index], node[index+1:]...)
+		doc, err = replaceAt(doc, path[:len(path)-1], node)
+		return doc, value, err
+	default:
+		return nil, nil, fmt.Errorf("%w: path does not exist", ErrInvalidPatch)
+	}
+}
+
+// replaceAt stores an array that may have been reallocated back into its parent.
+func replaceAt(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	parent, err := get(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	token := path[len(path)-1]
+
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		node[token] = value
+	case []interface{}:
+		index, err := arrayIndex(token, len(node)-1)
+		if err != nil {
+			return nil, err
+		}
+		node[index] = value
+	}
+	return doc, nil
+}
+
+func arrayIndex(token string, max int) (int, error) {
+	if token == "" || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("%w: invalid array index %q", ErrInvalidPatch, token)
+	}
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 || index > max {
+		return 0, fmt.Errorf("%w: array index %q out of bounds", ErrInvalidPatch, token)
+	}
+	return index, nil
+}
+
+func isPrefix(prefix, path []string) bool {
+	if len(prefix) > len(path) {
+		return false
+	}
+	for i := range prefix {
+		if prefix[i] != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Equal reports whether a and b have the same JSON form, so that numbers are
+// equal by value whether they were decoded from JSON or from BSON
+func Equal(a, b interface{}) bool {
+	encodedA, errA := json.Marshal(a)
+	encodedB, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(encodedA, encodedB)
+}
+
+func deepCopy(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			copied[key] = deepCopy(item)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(v))
+		for i, item := range v {
+			copied[i] = deepCopy(item)
+		}
+		return copied
+	default:
+		return v
+	}
+}
@@ -0,0 +1,125 @@
+package response
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetValidators sets the ETag and Last-Modified of the representation being
+// returned. A zero lastModified is left out. Clients may keep the response but
+// must revalidate it before use, it is specific to the caller. Tags differ
+// between encodings, so a 304 varies on Accept as the full response does.
+func SetValidators(c *gin.Context, etag string, lastModified time.Time) {
+	addVary(c, "Accept")
+	c.Header("ETag", etag)
+	if !lastModified.IsZero() {
+		c.Header("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+	c.Header("Cache-Control", "private, no-cache")
+}
+
+// NotModified answers a GET with 304 Not Modified when the validators set on
+// the response show that the client's copy is current, and reports whether
+// it did. If-None-Match takes precedence over If-Modified-Since, as in RFC
+// 9110.
+func NotModified(c *gin.Context) bool {
+	if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+		return false
+	}
+
+	header := c.Writer.Header()
+	if ifNoneMatch := c.GetHeader("If-None-Match"); ifNoneMatch != "" {
+		if !etagListMatches(ifNoneMatch, header.Get("ETag")) {
+			return false
+		}
+	} else if ifModifiedSince := c.GetHeader("If-Modified-Since"); ifModifiedSince != "" {
+		since, err := http.ParseTime(ifModifiedSince)
+		if err != nil {
+			return false
+		}
+		modified, err := http.ParseTime(header.Get("Last-Modified"))
+		if err != nil || modified.After(since) {
+			return false
+		}
+	} else {
+		return false
+	}
+
+	// As http.ServeContent, 304s carry no description of a body
+	header.Del("Content-Type")
+	header.Del("Content-Length")
+	c.AbortWithStatus(http.StatusNotModified)
+	return true
+}
+
+// SuccessIfModified responds like Success, with a weak ETag that hashes the
+// body. It suits results without a version of their own, such as lists.
+func SuccessIfModified(c *gin.Context, statusCode int, data interface{}) {
+	buffer := &bufferingWriter{ResponseWriter: c.Writer, status: statusCode}
+	c.Writer = buffer
+	renderer(c).Success(c, statusCode, data)
+	c.Writer = buffer.ResponseWriter
+
+	sum := sha256.Sum256(buffer.body.Bytes())
+	SetValidators(c, `W/"`+hex.EncodeToString(sum[:16])+`"`, time.Time{})
+	if NotModified(c) {
+		return
+	}
+	c.Writer.WriteHeader(buffer.status)
+	c.Writer.Write(buffer.body.Bytes())
+}
+
+// etagListMatches compares the entity tags of an If-None-Match header with
+// etag. The comparison is weak, W/"1" matches "1".
+func etagListMatches(list, etag string) bool {
+	if etag == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(list, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// bufferingWriter holds back the status and body, so that headers can still
+// be set once the body is known
+type bufferingWriter struct {
+	gin.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *bufferingWriter) WriteHeader(code int) {
+	w.status = code
+}
+
+func (w *bufferingWriter) WriteHeaderNow() {}
+
+func (w *bufferingWriter) Write(data []byte) (int, error) {
+	return w.body.Write(data)
+}
+
+func (w *bufferingWriter) WriteString(s string) (int, error) {
+	return w.body.WriteString(s)
+}
+
+func (w *bufferingWriter) Status() int {
+	return w.status
+}
+
+func (w *bufferingWriter) Size() int {
+	return w.body.Len()
+}
+
+func (w *bufferingWriter) Written() bool {
+	return false
+}
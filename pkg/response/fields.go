@@ -0,0 +1,185 @@
+package response
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const fieldsKey = "response_fields"
+
+// Fields is a sparse fieldset, the members of a resource that a client asked
+// for. A member maps to the fieldset of its own members, or to nil when it is
+// selected whole.
+type Fields map[string]Fields
+
+// ParseFields reads a comma separated selection such as "id,username", where
+// attributes.department selects one member of an object. The id is always
+// selected. An empty selection returns nil, which selects everything.
+func ParseFields(selection string) (Fields, error) {
+	if strings.TrimSpace(selection) == "" {
+		return nil, nil
+	}
+
+	fields := Fields{"id": nil}
+	for _, name := range strings.Split(selection, ",") {
+		name = strings.TrimSpace(name)
+		path := strings.Split(name, ".")
+		for _, segment := range path {
+			if !validFieldName(segment) {
+				return nil, fmt.Errorf("invalid field %q", name)
+			}
+		}
+		fields.Add(path...)
+	}
+	return fields, nil
+}
+
+// Add selects the member at path
+func (f Fields) Add(path ...string) {
+	members, selected := f[path[0]]
+	if len(path) == 1 {
+		f[path[0]] = nil
+		return
+	}
+	if selected && members == nil {
+		// The whole member is selected already
+		return
+	}
+	if members == nil {
+		members = Fields{}
+		f[path[0]] = members
+	}
+	members.Add(path[1:]...)
+}
+
+// Has reports whether the member name is selected, whole or in part
+func (f Fields) Has(name string) bool {
+	_, ok := f[name]
+	return ok
+}
+
+// String returns the selection in a canonical form, sorted by name
+func (f Fields) String() string {
+	var names []string
+	f.collect("", &names)
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
+
+func (f Fields) collect(prefix string, names *[]string) {
+	for name, members := range f {
+		if members == nil {
+			*names = append(*names, prefix+name)
+			continue
+		}
+		members.collect(prefix+name+".", names)
+	}
+}
+
+// apply removes the members of object that are not selected. Names the
+// object does not have are ignored, the members of a resource differ between
+// API versions.
+func (f Fields) apply(object map[string]interface{}) {
+	for name, value := range object {
+		members, ok := f[name]
+		if !ok {
+			delete(object, name)
+			continue
+		}
+		if nested, isObject := value.(map[string]interface{}); isObject && members != nil {
+			members.apply(nested)
+		}
+	}
+}
+
+func validFieldName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+type fieldSelection struct {
+	fields Fields
+	paths  [][]string
+}
+
+// SelectFields limits the resources in the data of the response to fields.
+// Resources are found at paths in the data written by the handler, such as
+// "users" for {"users": [...]}. Arrays along a path apply to each of their
+// items, and the empty path is the data itself. Nil fields select everything.
+func SelectFields(c *gin.Context, fields Fields, paths ...string) {
+	if fields == nil {
+		return
+	}
+	selection := &fieldSelection{fields: fields}
+	for _, path := range paths {
+		if path == "" {
+			selection.paths = append(selection.paths, nil)
+			continue
+		}
+		selection.paths = append(selection.paths, strings.Split(path, "."))
+	}
+	c.Set(fieldsKey, selection)
+}
+
+// SelectedFields returns the fieldset of the request, nil when everything is
+// selected
+func SelectedFields(c *gin.Context) Fields {
+	if selection, ok := c.Get(fieldsKey); ok {
+		return selection.(*fieldSelection).fields
+	}
+	return nil
+}
+
+// Sparse applies the fieldset of the request to data. Renderers call it on
+// the data of successful responses, after mapping it to their version.
+func Sparse(c *gin.Context, data interface{}) interface{} {
+	value, ok := c.Get(fieldsKey)
+	if !ok {
+		return data
+	}
+	selection := value.(*fieldSelection)
+
+	// Selecting on the JSON form follows the member names of every version
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return data
+	}
+	var tree interface{}
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.UseNumber()
+	if err := decoder.Decode(&tree); err != nil {
+		return data
+	}
+
+	for _, path := range selection.paths {
+		selectAt(tree, path, selection.fields)
+	}
+	return tree
+}
+
+func selectAt(value interface{}, path []string, fields Fields) {
+	switch v := value.(type) {
+	case []interface{}:
+		for _, item := range v {
+			selectAt(item, path, fields)
+		}
+	case map[string]interface{}:
+		if len(path) == 0 {
+			fields.apply(v)
+			return
+		}
+		selectAt(v[path[0]], path[1:], fields)
+	}
+}
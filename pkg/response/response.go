@@ -1,11 +1,31 @@
 package response
 
 import (
+	"mime"
 	"net/http"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 
+	"github.com/gin-contrib/requestid"
 	"github.com/gin-gonic/gin"
-	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/kaa-dan/clean-architecture-go/pkg/codec"
 	"github.com/kaa-dan/clean-architecture-go/pkg/errors"
+	"github.com/kaa-dan/clean-architecture-go/pkg/i18n"
+	"github.com/kaa-dan/clean-architecture-go/pkg/logger"
+	"github.com/kaa-dan/clean-architecture-go/pkg/validator"
+)
+
+const (
+	rendererKey = "response_renderer"
+
+	// ProblemMediaType is the content type of Problem bodies
+	ProblemMediaType = "application/problem+json"
+
+	// ProblemTypePath is where each error code is described, the type of a
+	// problem is this path followed by its code
+	ProblemTypePath = "/problems/"
 )
 
 type Response struct {
@@ -13,61 +33,211 @@ type Response struct {
 	Message string      `json:"message,omitempty"`
 	Data    interface{} `json:"data,omitempty"`
 	Error   interface{} `json:"error,omitempty"`
+	Code    errors.Code `json:"code,omitempty"`
 }
 
-func Success(c *gin.Context, statusCode int, data interface{}) {
-	c.JSON(statusCode, Response{
+// Problem is an RFC 9457 problem details object. Code repeats the last
+// segment of Type for clients that switch on it, Instance is a URN of the
+// request ID so a report can be traced in the logs.
+type Problem struct {
+	Type      string                `json:"type"`
+	Title     string                `json:"title"`
+	Status    int                   `json:"status"`
+	Detail    string                `json:"detail,omitempty"`
+	Instance  string                `json:"instance,omitempty"`
+	Code      errors.Code           `json:"code"`
+	RequestID string                `json:"request_id,omitempty"`
+	Errors    validator.FieldErrors `json:"errors,omitempty"`
+}
+
+// Renderer writes response bodies in the format of one API version. Requests
+// use Envelope unless a renderer was installed with UseRenderer.
+type Renderer interface {
+	Success(c *gin.Context, statusCode int, data interface{})
+	Problem(c *gin.Context, problem *Problem)
+}
+
+// UseRenderer makes the helpers of this package write with r for the rest of
+// the request
+func UseRenderer(c *gin.Context, r Renderer) {
+	c.Set(rendererKey, r)
+}
+
+// Envelope is the original response format, {success, data, error}. Clients
+// that accept application/problem+json get problems for errors instead.
+type Envelope struct{}
+
+func (Envelope) Success(c *gin.Context, statusCode int, data interface{}) {
+	Write(c, statusCode, Response{
 		Success: true,
-		Data:    data,
+		Data:    Sparse(c, data),
 	})
 }
 
-func Error(c *gin.Context, statusCode int, message string) {
-	c.JSON(statusCode, Response{
+func (Envelope) Problem(c *gin.Context, problem *Problem) {
+	if acceptsProblem(c) {
+		WriteProblem(c, problem)
+		return
+	}
+
+	if len(problem.Errors) > 0 {
+		Write(c, problem.Status, Response{
+			Success: false,
+			Error:   problem.Title,
+			Data:    problem.Errors.Messages(),
+			Code:    problem.Code,
+		})
+		return
+	}
+	Write(c, problem.Status, Response{
 		Success: false,
-		Error:   message,
+		Error:   problem.Detail,
+		Code:    problem.Code,
 	})
 }
 
+// Write encodes body as JSON, MessagePack or Protobuf, whichever the Accept
+// header of the request prefers
+func Write(c *gin.Context, statusCode int, body interface{}) {
+	addVary(c, "Accept")
+
+	format := codec.Negotiate(c.GetHeader("Accept"))
+	if format == codec.JSON {
+		c.JSON(statusCode, body)
+		return
+	}
+	data, err := format.Marshal(body)
+	if err != nil {
+		logger.Errorf("encoding %s response failed: %v", format.MediaType, err)
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+	c.Data(statusCode, format.ContentType(), data)
+}
+
+// WriteProblem writes problem as application/problem+json, which has no
+// binary form
+func WriteProblem(c *gin.Context, problem *Problem) {
+	c.Header("Content-Type", ProblemMediaType)
+	c.JSON(problem.Status, problem)
+}
+
+// NewProblem describes an error of the request in c, in its language
+func NewProblem(c *gin.Context, description errors.Description) *Problem {
+	problem := &Problem{
+		Type:   ProblemTypePath + string(description.Code),
+		Title:  description.Title,
+		Status: description.Status,
+		Detail: description.Message,
+		Code:   description.Code,
+	}
+	if id := requestid.Get(c); id != "" {
+		problem.RequestID = id
+		// Callers may send their own request IDs, which need not be UUIDs
+		if _, err := uuid.Parse(id); err == nil {
+			problem.Instance = "urn:uuid:" + id
+		}
+	}
+	localize(c, problem)
+	return problem
+}
+
+func Success(c *gin.Context, statusCode int, data interface{}) {
+	renderer(c).Success(c, statusCode, data)
+}
+
+// Error responds with a message written for clients, under the generic code
+// of the status
+func Error(c *gin.Context, statusCode int, message string) {
+	renderer(c).Problem(c, NewProblem(c, errors.DescribeStatus(statusCode, message)))
+}
+
+// HandleError responds with the status, code and message of the AppError or
+// sentinel that err wraps. Other errors are logged and reported as internal
+// errors without their message.
 func HandleError(c *gin.Context, err error) {
-	statusCode := errors.GetHTTPStatusCode(err)
-	Error(c, statusCode, err.Error())
+	if validator.IsValidationError(err) {
+		ValidationError(c, err)
+		return
+	}
+
+	description := errors.Describe(err)
+	if description.Status >= http.StatusInternalServerError {
+		logger.Errorf("request %s failed: %v", requestid.Get(c), err)
+	}
+	renderer(c).Problem(c, NewProblem(c, description))
 }
 
 func ValidationError(c *gin.Context, err error) {
-	var validationErrors []string
+	description, _ := errors.DescribeCode(errors.CodeValidationFailed)
+	problem := NewProblem(c, description)
+	locale := i18n.Locale(c)
+	problem.Detail = i18n.T(locale, "validation.summary")
+	problem.Errors = validator.Details(err).Localize(locale)
+	renderer(c).Problem(c, problem)
+}
+
+func renderer(c *gin.Context) Renderer {
+	if r, ok := c.Get(rendererKey); ok {
+		return r.(Renderer)
+	}
+	return Envelope{}
+}
+
+// localize translates the title of problem into the language of the request,
+// and its detail when that is the catalog message of the code. Details
+// written for one occasion stay in English.
+func localize(c *gin.Context, problem *Problem) {
+	locale := i18n.Locale(c)
+	c.Header("Content-Language", locale)
+	addVary(c, "Accept-Language")
 
-	if validationErrs, ok := err.(validator.ValidationErrors); ok {
-		for _, validationErr := range validationErrs {
-			validationErrors = append(validationErrors, getValidationErrorMessage(validationErr))
+	key := "error." + string(problem.Code)
+	message, ok := i18n.Lookup(locale, key)
+	if !ok {
+		// AppErrors may have codes of their own, use the one of the status
+		key = "error." + string(errors.StatusCode(problem.Status))
+		if message, ok = i18n.Lookup(locale, key); !ok {
+			return
 		}
-	} else {
-		validationErrors = append(validationErrors, err.Error())
 	}
 
-	c.JSON(http.StatusBadRequest, Response{
-		Success: false,
-		Error:   "Validation failed",
-		Data:    validationErrors,
-	})
+	switch english, _ := i18n.Lookup(i18n.DefaultLocale, key); problem.Detail {
+	case problem.Title:
+		problem.Detail = capitalize(message)
+	case english:
+		problem.Detail = message
+	}
+	problem.Title = capitalize(message)
 }
 
-func getValidationErrorMessage(err validator.FieldError) string {
-	field := err.Field()
-	tag := err.Tag()
-
-	switch tag {
-	case "required":
-		return field + " is required"
-	case "email":
-		return field + " must be a valid email address"
-	case "min":
-		return field + " must be at least " + err.Param() + " characters long"
-	case "max":
-		return field + " must be at most " + err.Param() + " characters long"
-	case "alphanum":
-		return field + " must contain only alphanumeric characters"
-	default:
-		return field + " is invalid"
+// addVary names header in Vary, unless it is named already
+func addVary(c *gin.Context, header string) {
+	for _, value := range c.Writer.Header().Values("Vary") {
+		for _, name := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(name), header) {
+				return
+			}
+		}
+	}
+	c.Writer.Header().Add("Vary", header)
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	r, size := utf8.DecodeRuneInString(s)
+	return string(unicode.ToUpper(r)) + s[size:]
+}
+
+// acceptsProblem reports whether the Accept header names problem+json
+func acceptsProblem(c *gin.Context) bool {
+	for _, mediaRange := range strings.Split(c.GetHeader("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(mediaRange))
+		if err == nil && mediaType == ProblemMediaType {
+			return true
+		}
 	}
+	return false
 }
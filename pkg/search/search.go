@@ -0,0 +1,263 @@
+package search
+
+import (
+	"html"
+	"strings"
+	"unicode"
+)
+
+const (
+	exactScore  = 1.0
+	prefixScore = 0.75
+	infixScore  = 0.4
+	fuzzyScore  = 0.5
+)
+
+type Field struct {
+	Name   string
+	Value  string
+	Weight float64
+}
+
+type Result struct {
+	Score      float64
+	Highlights map[string]string
+}
+
+type span struct {
+	start, end int
+}
+
+type word struct {
+	runes []rune
+	start int
+}
+
+// Terms splits a query into lowercased search terms.
+func Terms(query string) []string {
+	var terms []string
+	for _, term := range strings.Fields(strings.ToLower(query)) {
+		term = strings.TrimFunc(term, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+		if term != "" {
+			terms = append(terms, term)
+		}
+	}
+	return terms
+}
+
+// Score ranks fields against the query terms. Every term has to match at
+// least one field, otherwise the score is zero.
+func Score(terms []string, fields []Field) Result {
+	result := Result{Highlights: map[string]string{}}
+	if len(terms) == 0 {
+		return result
+	}
+
+	spans := make(map[string][]span)
+	for _, term := range terms {
+		termRunes := []rune(term)
+		matched := false
+
+		for _, field := range fields {
+			score, s, ok := matchField(termRunes, field.Value)
+			if !ok {
+				continue
+			}
+			matched = true
+			result.Score += score * field.Weight
+			spans[field.Name] = append(spans[field.Name], s)
+		}
+
+		if !matched {
+			return Result{Highlights: map[string]string{}}
+		}
+	}
+
+	for _, field := range fields {
+		if s, ok := spans[field.Name]; ok {
+			result.Highlights[field.Name] = highlight(field.Value, s)
+		}
+	}
+
+	return result
+}
+
+func matchField(term []rune, value string) (float64, span, bool) {
+	valueRunes := lower([]rune(value))
+
+	// Whole value prefix, e.g. "alice@exa" against an email address
+	if hasPrefix(valueRunes, term) {
+		if len(valueRunes) == len(term) {
+			return exactScore, span{0, len(term)}, true
+		}
+		return prefixScore, span{0, len(term)}, true
+	}
+
+	best := 0.0
+	var bestSpan span
+	for _, w := range words(valueRunes) {
+		switch {
+		case equal(w.runes, term):
+			return exactScore, span{w.start, w.start + len(w.runes)}, true
+		case hasPrefix(w.runes, term):
+			if prefixScore > best {
+				best, bestSpan = prefixScore, span{w.start, w.start + len(term)}
+			}
+		default:
+			if score, length, ok := fuzzy(w.runes, term); ok && score > best {
+				best, bestSpan = score, span{w.start, w.start + length}
+			}
+		}
+	}
+	if best > 0 {
+		return best, bestSpan, true
+	}
+
+	if len(term) >= 3 {
+		if i := index(valueRunes, term); i >= 0 {
+			return infixScore, span{i, i + len(term)}, true
+		}
+	}
+
+	return 0, span{}, false
+}
+
+// fuzzy compares the term with the whole word and with a word prefix of the
+// same length, so that typos are tolerated while the user is still typing.
+func fuzzy(w, term []rune) (float64, int, bool) {
+	maxEdits := allowedEdits(len(term))
+	if maxEdits == 0 {
+		return 0, 0, false
+	}
+
+	distance, length := levenshtein(w, term), len(w)
+	if len(w) > len(term) {
+		if d := levenshtein(w[:len(term)], term); d < distance {
+			distance, length = d, len(term)
+		}
+	}
+	if distance > maxEdits {
+		return 0, 0, false
+	}
+
+	return fuzzyScore * (1 - float64(distance)/float64(len(term)+1)), length, true
+}
+
+func allowedEdits(length int) int {
+	switch {
+	case length >= 8:
+		return 2
+	case length >= 4:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// levenshtein returns the optimal string alignment distance, which counts an
+// adjacent transposition as a single edit.
+func levenshtein(a, b []rune) int {
+	d := make([][]int, len(a)+1)
+	for i := range d {
+		d[i] = make([]int, len(b)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+
+	return d[len(a)][len(b)]
+}
+
+func words(value []rune) []word {
+	var result []word
+	start := -1
+	for i, r := range value {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			result = append(result, word{runes: value[start:i], start: start})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		result = append(result, word{runes: value[start:], start: start})
+	}
+	return result
+}
+
+// highlight wraps the matched parts of value in <em> tags. The value itself
+// is HTML-escaped, so the result is safe to render as markup.
+func highlight(value string, spans []span) string {
+	runes := []rune(value)
+	marked := make([]bool, len(runes))
+	for _, s := range spans {
+		for i := s.start; i < s.end && i < len(runes); i++ {
+			marked[i] = true
+		}
+	}
+
+	var b strings.Builder
+	for i, r := range runes {
+		if marked[i] && (i == 0 || !marked[i-1]) {
+			b.WriteString("<em>")
+		}
+		b.WriteString(html.EscapeString(string(r)))
+		if marked[i] && (i == len(runes)-1 || !marked[i+1]) {
+			b.WriteString("</em>")
+		}
+	}
+	return b.String()
+}
+
+func lower(runes []rune) []rune {
+	result := make([]rune, len(runes))
+	for i, r := range runes {
+		result[i] = unicode.ToLower(r)
+	}
+	return result
+}
+
+func hasPrefix(s, prefix []rune) bool {
+	return len(s) >= len(prefix) && equal(s[:len(prefix)], prefix)
+}
+
+func equal(a, b []rune) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func index(s, sub []rune) int {
+	for i := 0; i+len(sub) <= len(s); i++ {
+		if equal(s[i:i+len(sub)], sub) {
+			return i
+		}
+	}
+	return -1
+}
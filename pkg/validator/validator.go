@@ -1,22 +1,322 @@
 package validator
 
-import "github.com/go-playground/validator/v10"
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+	detranslations "github.com/go-playground/validator/v10/translations/de"
+	entranslations "github.com/go-playground/validator/v10/translations/en"
+	estranslations "github.com/go-playground/validator/v10/translations/es"
+	frtranslations "github.com/go-playground/validator/v10/translations/fr"
+	"github.com/kaa-dan/clean-architecture-go/pkg/i18n"
+)
+
+var identifierPattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// defaultTranslations registers the library messages of every locale
+var defaultTranslations = map[string]func(*validator.Validate, ut.Translator) error{
+	"en": entranslations.RegisterDefaultTranslations,
+	"es": estranslations.RegisterDefaultTranslations,
+	"fr": frtranslations.RegisterDefaultTranslations,
+	"de": detranslations.RegisterDefaultTranslations,
+}
+
+// FieldError is one problem with one field. Code is the rule that failed,
+// such as required or email. Message is in English, Localize translates it.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+
+	// The catalog message and parameters after the field name, or the failed
+	// rule itself, that Message is translated from
+	key    string
+	params []string
+	source validator.FieldError
+}
+
+// NewFieldError reports a problem with field, message is completed with the
+// field name, e.g. NewFieldError("email", "required", "is required"). The
+// message is not translated, prefer NewLocalizedFieldError.
+func NewFieldError(field, code, message string) FieldError {
+	return FieldError{Field: field, Code: code, Message: field + " " + message}
+}
+
+// NewLocalizedFieldError reports a problem with field described by the
+// catalog message under key, which receives the field name and then params,
+// e.g. NewLocalizedFieldError("email", "required", "validation.required")
+func NewLocalizedFieldError(field, code, key string, params ...string) FieldError {
+	fieldErr := FieldError{Field: field, Code: code, key: key, params: params}
+	fieldErr.Message = fieldErr.translate(i18n.DefaultLocale)
+	return fieldErr
+}
+
+// FieldErrors reports values checked one at a time with ValidateField, such
+// as custom attributes, and anything else validated outside of structs.
+type FieldErrors []FieldError
+
+func (e FieldErrors) Error() string {
+	return strings.Join(e.Messages(), "; ")
+}
+
+// Messages returns the message of each problem
+func (e FieldErrors) Messages() []string {
+	messages := make([]string, len(e))
+	for i, fieldErr := range e {
+		messages[i] = fieldErr.Message
+	}
+	return messages
+}
+
+// Localize returns the problems with their messages in locale. Messages that
+// were not built from the catalog stay as they are.
+func (e FieldErrors) Localize(locale string) FieldErrors {
+	localized := make(FieldErrors, len(e))
+	for i, fieldErr := range e {
+		fieldErr.Message = fieldErr.translate(locale)
+		localized[i] = fieldErr
+	}
+	return localized
+}
+
+func (e FieldError) translate(locale string) string {
+	switch {
+	case e.source != nil:
+		return translate(locale, e.Field, e.source)
+	case e.key != "":
+		return i18n.T(locale, e.key, append([]string{e.Field}, e.params...)...)
+	default:
+		return e.Message
+	}
+}
 
 type Validator struct {
 	validator *validator.Validate
 }
 
+var (
+	// shared is used by every Validator, so that the translations of the
+	// rules are registered once
+	shared     *validator.Validate
+	sharedOnce sync.Once
+)
+
 func New() *Validator {
+	sharedOnce.Do(func() {
+		shared = newValidate()
+	})
+	return &Validator{
+		validator: shared,
+	}
+}
+
+func newValidate() *validator.Validate {
 	v := validator.New()
 
-	// Register custom validators if needed
-	// v.RegisterValidation("custom_tag", customValidationFunc)
+	// Name fields the way clients send them
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+		switch name {
+		case "-":
+			return ""
+		case "":
+			return field.Name
+		}
+		return name
+	})
 
-	return &Validator{
-		validator: v,
+	// identifier allows lowercase letters, digits, and underscores, starting
+	// with a letter
+	v.RegisterValidation("identifier", func(fl validator.FieldLevel) bool {
+		return IsIdentifier(fl.Field().String())
+	})
+
+	// locale is a language tag with a message catalog, e.g. de or de-AT
+	v.RegisterValidation("locale", func(fl validator.FieldLevel) bool {
+		return i18n.IsSupported(fl.Field().String())
+	})
+
+	// The library translations cover the rules our catalogs do not
+	for locale, register := range defaultTranslations {
+		if err := register(v, i18n.Translator(locale)); err != nil {
+			panic(fmt.Sprintf("validator: registering %s translations: %v", locale, err))
+		}
 	}
+
+	return v
 }
 
 func (v *Validator) Validate(i interface{}) error {
 	return v.validator.Struct(i)
 }
+
+// ValidateExcept validates every field of a struct except the named ones.
+func (v *Validator) ValidateExcept(i interface{}, fields ...string) error {
+	return v.validator.StructExcept(i, fields...)
+}
+
+// ValidateVar validates a single value against a tag, e.g. "oneof=user admin".
+func (v *Validator) ValidateVar(field interface{}, tag string) error {
+	return v.validator.Var(field, tag)
+}
+
+// ValidateField validates a value that is not a struct field against a
+// tag and names it in the messages of the FieldErrors it returns.
+func (v *Validator) ValidateField(name string, field interface{}, tag string) error {
+	if field == nil {
+		return v.fieldErrors(name, v.validator.Var(field, tag))
+	}
+
+	// Wrap the value in a struct so that every translation knows its name
+	wrapper := reflect.New(reflect.StructOf([]reflect.StructField{{
+		Name: "Value",
+		Type: reflect.TypeOf(field),
+		Tag:  reflect.StructTag("json:" + strconv.Quote(name) + " validate:" + strconv.Quote(tag)),
+	}}))
+	wrapper.Elem().Field(0).Set(reflect.ValueOf(field))
+	return v.fieldErrors(name, v.validator.Struct(wrapper.Interface()))
+}
+
+func (v *Validator) fieldErrors(name string, err error) error {
+	validationErrs, ok := err.(validator.ValidationErrors)
+	if !ok {
+		return err
+	}
+
+	fieldErrs := make(FieldErrors, 0, len(validationErrs))
+	for _, validationErr := range validationErrs {
+		fieldErrs = append(fieldErrs, fieldError(name, validationErr))
+	}
+	return fieldErrs
+}
+
+// CheckTag reports whether tag is a usable rule for values like sample. The
+// underlying library panics on unknown tags and malformed parameters, so
+// tags that come from users must be checked before they are stored.
+func (v *Validator) CheckTag(tag string, sample interface{}) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("invalid rules %q: %v", tag, r)
+		}
+	}()
+
+	if err := v.validator.Var(sample, tag); err != nil && !IsValidationError(err) {
+		return fmt.Errorf("invalid rules %q: %w", tag, err)
+	}
+	return nil
+}
+
+// IsIdentifier reports whether s is safe to use as a name in field paths
+func IsIdentifier(s string) bool {
+	return identifierPattern.MatchString(s)
+}
+
+// IsValidationError reports whether err is or wraps a validation failure
+func IsValidationError(err error) bool {
+	var validationErrs validator.ValidationErrors
+	var fieldErrs FieldErrors
+	return errors.As(err, &validationErrs) || errors.As(err, &fieldErrs)
+}
+
+// Details converts validation errors into one FieldError per problem.
+func Details(err error) FieldErrors {
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		fieldErrs := make(FieldErrors, 0, len(validationErrs))
+		for _, validationErr := range validationErrs {
+			fieldErrs = append(fieldErrs, fieldError(fieldPath(validationErr), validationErr))
+		}
+		return fieldErrs
+	}
+
+	var fieldErrs FieldErrors
+	if errors.As(err, &fieldErrs) {
+		return fieldErrs
+	}
+	return FieldErrors{{Code: "invalid", Message: err.Error()}}
+}
+
+// ErrorMessages converts validation errors into human readable messages.
+func ErrorMessages(err error) []string {
+	return Details(err).Messages()
+}
+
+// fieldPath is the namespace of the field without the struct name, e.g.
+// attributes.department rather than SignUpRequest.attributes.department
+func fieldPath(err validator.FieldError) string {
+	namespace := err.Namespace()
+	if i := strings.IndexByte(namespace, '.'); i >= 0 {
+		return namespace[i+1:]
+	}
+	return err.Field()
+}
+
+func fieldError(field string, err validator.FieldError) FieldError {
+	return FieldError{Field: field, Code: err.Tag(), Message: translate(i18n.DefaultLocale, field, err), source: err}
+}
+
+// translate describes a failed rule in locale, from our catalog when it has
+// the rule, then from the library translations, and finally as invalid
+func translate(locale, field string, err validator.FieldError) string {
+	tag, param := err.Tag(), err.Param()
+	switch tag {
+	case "gte":
+		tag = "min"
+	case "lte":
+		tag = "max"
+	case "oneof":
+		param = strings.ReplaceAll(param, " ", ", ")
+	case "locale":
+		param = strings.Join(i18n.Locales(), ", ")
+	}
+
+	prefix := "validation." + tag
+	keys := []string{prefix + "." + kindName(err.Kind()), prefix}
+	if param == "1" {
+		// Counts of one are singular in every catalog
+		keys = append([]string{keys[0] + ".one"}, keys...)
+	}
+	for _, key := range keys {
+		if message, ok := i18n.Lookup(locale, key, field, param); ok {
+			return message
+		}
+	}
+
+	// The library names the field without its path
+	for _, candidate := range []string{locale, i18n.DefaultLocale} {
+		if message := err.Translate(i18n.Translator(candidate)); message != err.Error() {
+			return message
+		}
+	}
+	return i18n.T(locale, "validation.invalid", field)
+}
+
+// kindName groups kinds the way rules such as min and max count them
+func kindName(kind reflect.Kind) string {
+	switch {
+	case isNumber(kind):
+		return "number"
+	case kind == reflect.Slice || kind == reflect.Array || kind == reflect.Map:
+		return "items"
+	default:
+		return "string"
+	}
+}
+
+func isNumber(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	default:
+		return false
+	}
+}
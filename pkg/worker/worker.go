@@ -0,0 +1,27 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/kaa-dan/clean-architecture-go/pkg/logger"
+)
+
+// Run calls fn once immediately and then every interval until ctx is
+// cancelled. Errors are logged and do not stop the loop.
+func Run(ctx context.Context, name string, interval time.Duration, fn func(ctx context.Context) error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := fn(ctx); err != nil && ctx.Err() == nil {
+			logger.Errorf("%s failed: %v", name, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}